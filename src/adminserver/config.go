@@ -0,0 +1,88 @@
+package adminserver
+
+import (
+	"fmt"
+	"github.com/SongZihuan/huan-springboard/src/config"
+	"github.com/SongZihuan/huan-springboard/src/utils"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type rollbackView struct {
+	Reason  string    `json:"reason"`
+	SavedAt time.Time `json:"saved-at"`
+	Files   []string  `json:"files"`
+}
+
+type configDiffView struct {
+	Changed  bool          `json:"changed"`
+	Diff     string        `json:"diff"`
+	Valid    bool          `json:"valid"`              // 磁盘上的配置文件是否通过检查
+	Issues   []string      `json:"issues"`             // 磁盘上的配置文件检查发现的错误和警告
+	Rollback *rollbackView `json:"rollback,omitempty"` // 正在使用最后一次成功应用的配置
+}
+
+// handleConfigDiff 比较正在运行的配置与磁盘上的配置文件（合并、设置默认值并隐藏密钥后），磁盘上的配置检查不通过时仍然比较并返回检查结果；
+// format=text 时输出 diff，检查发现的问题以 # 开头放在 diff 之前
+func handleConfigDiff(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	} else if format != "json" && format != "text" {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("bad format '%s'", format))
+		return
+	}
+
+	running, err := config.MarshalConfig()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	disk, issues, err := config.MarshalDiskConfig()
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "on-disk config cannot be parsed: "+err.Error())
+		return
+	}
+
+	diff := utils.UnifiedDiff("running", "disk", string(running), string(disk))
+
+	valid := true
+	issueList := make([]string, 0, len(issues))
+	for _, i := range issues {
+		if i.IsError {
+			valid = false
+		}
+		issueList = append(issueList, i.String())
+	}
+
+	if format == "text" {
+		var buf strings.Builder
+		for _, i := range issueList {
+			buf.WriteString("# " + i + "\n")
+		}
+		buf.WriteString(diff)
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write([]byte(buf.String()))
+		return
+	}
+
+	res := configDiffView{
+		Changed: diff != "",
+		Diff:    diff,
+		Valid:   valid,
+		Issues:  issueList,
+	}
+
+	if rb := config.GetRollback(); rb != nil {
+		res.Rollback = &rollbackView{
+			Reason:  rb.Reason,
+			SavedAt: rb.SavedAt,
+			Files:   rb.Files,
+		}
+	}
+
+	writeJSON(w, http.StatusOK, res)
+}
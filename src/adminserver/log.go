@@ -0,0 +1,46 @@
+package adminserver
+
+import (
+	"github.com/SongZihuan/huan-springboard/src/logger"
+	"net/http"
+)
+
+type logState struct {
+	Level logger.LoggerLevel `json:"level"`
+	Tag   bool               `json:"tag"`
+}
+
+type logUpdate struct {
+	Level *logger.LoggerLevel `json:"level"` // 为空表示不修改
+	Tag   *bool               `json:"tag"`   // 为空表示不修改
+}
+
+func currentLogState() *logState {
+	return &logState{
+		Level: logger.GetLevel(),
+		Tag:   logger.GetLogTag(),
+	}
+}
+
+func handleGetLog(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, currentLogState())
+}
+
+// handleSetLog 运行时修改日志级别和标签，重新加载配置后会恢复为配置文件中的值
+func handleSetLog(w http.ResponseWriter, r *http.Request) {
+	var req logUpdate
+
+	err := readJSON(r, &req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "bad request: "+err.Error())
+		return
+	}
+
+	err = logger.Update("admin api", req.Level, req.Tag)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, currentLogState())
+}
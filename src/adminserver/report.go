@@ -0,0 +1,107 @@
+package adminserver
+
+import (
+	"fmt"
+	"github.com/SongZihuan/huan-springboard/src/config"
+	"github.com/SongZihuan/huan-springboard/src/database"
+	"github.com/SongZihuan/huan-springboard/src/report"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// readReportOptions 读取查询参数，与 report 子命令的选项同名，format 默认为 json
+func readReportOptions(query url.Values) (*report.Options, error) {
+	opts := &report.Options{
+		IP:     query.Get("ip"),
+		To:     query.Get("to"),
+		Since:  query.Get("since"),
+		Until:  query.Get("until"),
+		Accept: query.Get("accept"),
+		Limit:  report.DefaultLimit,
+		Top:    report.DefaultTop,
+		Format: query.Get("format"),
+	}
+
+	if opts.Format == "" {
+		opts.Format = report.FormatJSON
+	}
+
+	for name, value := range map[string]*int{"limit": &opts.Limit, "top": &opts.Top} {
+		if !query.Has(name) {
+			continue
+		}
+
+		n, err := strconv.Atoi(query.Get(name))
+		if err != nil {
+			return nil, fmt.Errorf("bad %s '%s'", name, query.Get(name))
+		}
+		*value = n
+	}
+
+	err := opts.Check()
+	if err != nil {
+		return nil, err
+	}
+
+	return opts, nil
+}
+
+func setReportContentType(w http.ResponseWriter, format string) {
+	switch format {
+	case report.FormatCSV:
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	case report.FormatTable:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	default:
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	}
+}
+
+// handleListRecords 查询 ssh 连接记录（按时间倒序）
+func handleListRecords(w http.ResponseWriter, r *http.Request) {
+	opts, err := readReportOptions(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filter, err := opts.Filter()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	records, err := database.FindConnectRecords(filter, opts.Limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	setReportContentType(w, opts.Format)
+	_ = report.WriteRecords(w, opts.Format, records)
+}
+
+// handleRecordsSummary 统计 ssh 连接记录：接受率、被拒绝最多的 IP、连接最多的转发目标以及每小时的连接数
+func handleRecordsSummary(w http.ResponseWriter, r *http.Request) {
+	opts, err := readReportOptions(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filter, err := opts.Filter()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res, err := database.ReportConnectRecords(filter, opts.Top, config.TimeZone())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	setReportContentType(w, opts.Format)
+	_ = report.WriteReport(w, opts.Format, res)
+}
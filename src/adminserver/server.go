@@ -0,0 +1,105 @@
+package adminserver
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/SongZihuan/huan-springboard/src/config"
+	"github.com/SongZihuan/huan-springboard/src/logger"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var server *http.Server
+
+func InitAdmin() error {
+	if server != nil {
+		return nil
+	}
+
+	if !config.IsReady() {
+		panic("config is not ready")
+	}
+
+	if config.GetConfig().Admin.Enable.IsDisable(true) {
+		return nil
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/log", handleGetLog)
+	mux.HandleFunc("PUT /api/v1/log", handleSetLog)
+	mux.HandleFunc("POST /api/v1/simulate", handleSimulate)
+	mux.HandleFunc("GET /api/v1/records", handleListRecords)
+	mux.HandleFunc("GET /api/v1/records/summary", handleRecordsSummary)
+	mux.HandleFunc("GET /api/v1/config/diff", handleConfigDiff)
+
+	ln, err := net.Listen("tcp", config.GetConfig().Admin.Address)
+	if err != nil {
+		return fmt.Errorf("listen on %s error: %s", config.GetConfig().Admin.Address, err.Error())
+	}
+
+	server = &http.Server{
+		Handler:           auth(mux),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func(srv *http.Server) {
+		err := srv.Serve(ln)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Errorf("admin server stop with error: %s", err.Error())
+		}
+	}(server)
+
+	logger.Infof("admin server listen on %s", ln.Addr().String())
+	return nil
+}
+
+func CloseAdmin() {
+	if server == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_ = server.Shutdown(ctx)
+	server = nil
+}
+
+// auth 校验 Authorization: Bearer <token>，令牌每次从当前配置读取，重新加载配置后立即生效
+func auth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		expect := config.GetConfig().Admin.Token
+
+		if !ok || expect == "" || subtle.ConstantTimeCompare([]byte(token), []byte(expect)) != 1 {
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+func readJSON(r *http.Request, v any) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(nil, r.Body, 1<<20))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
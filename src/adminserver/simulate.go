@@ -0,0 +1,32 @@
+package adminserver
+
+import (
+	"github.com/SongZihuan/huan-springboard/src/rulesim"
+	"github.com/SongZihuan/huan-springboard/src/sshserver"
+	"github.com/SongZihuan/huan-springboard/src/tcpserver"
+	"net/http"
+)
+
+// handleSimulate 模拟来访 IP 的检查流程，返回是否放行、命中的规则以及每一步的检查过程
+func handleSimulate(w http.ResponseWriter, r *http.Request) {
+	var req rulesim.Request
+
+	err := readJSON(r, &req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "bad request: "+err.Error())
+		return
+	}
+
+	var res *rulesim.Result
+	if req.Component == rulesim.ComponentSSH {
+		res, err = sshserver.Simulate(&req)
+	} else {
+		res, err = tcpserver.Simulate(&req)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, res)
+}
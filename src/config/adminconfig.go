@@ -0,0 +1,43 @@
+package config
+
+import (
+	"github.com/SongZihuan/huan-springboard/src/utils"
+	"net"
+)
+
+type AdminConfig struct {
+	Enable  utils.StringBool `yaml:"enable"`
+	Address string           `yaml:"address"` // 监听地址，修改后需要重启
+	Token   string           `yaml:"token"`   // 访问令牌，请求头 Authorization: Bearer <token>
+}
+
+func (a *AdminConfig) setDefault() {
+	a.Enable.SetDefaultDisable()
+
+	if a.Address == "" {
+		a.Address = "127.0.0.1:7070"
+	}
+
+	return
+}
+
+func (a *AdminConfig) check(report *checkReport) (err ConfigError) {
+	if a.Enable.IsDisable(true) {
+		return nil
+	}
+
+	host, _, splitErr := net.SplitHostPort(a.Address)
+	if splitErr != nil {
+		return report.error("admin address is invalid")
+	}
+
+	if a.Token == "" {
+		return report.error("admin token is empty")
+	}
+
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		_ = report.warning("address is not a loopback address, make sure it is protected by a firewall")
+	}
+
+	return nil
+}
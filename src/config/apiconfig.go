@@ -9,9 +9,9 @@ func (a *ApiConfig) setDefault() {
 	return
 }
 
-func (a *ApiConfig) check() (err ConfigError) {
+func (a *ApiConfig) check(report *checkReport) (err ConfigError) {
 	if a.AppCode == "" {
-		return NewConfigError("app-code is empty")
+		return report.error("app-code is empty")
 	}
 
 	return nil
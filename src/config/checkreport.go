@@ -0,0 +1,175 @@
+package config
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ConfigIssue 配置检查发现的错误或警告
+type ConfigIssue struct {
+	Path    string // YAML 路径，例如 tcp.forward[0]，顶层配置项为空
+	Msg     string
+	IsError bool
+}
+
+func (i *ConfigIssue) String() string {
+	level := "warning"
+	if i.IsError {
+		level = "error"
+	}
+
+	if i.Path == "" {
+		return fmt.Sprintf("%s: %s", level, i.Msg)
+	}
+	return fmt.Sprintf("%s: %s: %s", level, i.Path, i.Msg)
+}
+
+// checkReport 一次检查的 YAML 路径和问题，每次检查单独创建并传入各个 check；
+// 检查模式（collect）下收集全部问题，遇到错误时继续检查其余配置项，否则直接输出到控制台
+type checkReport struct {
+	collect bool
+	path    []string
+	issues  []*ConfigIssue
+}
+
+// enterPath 进入下一级 YAML 路径，返回退出该路径的函数
+func (r *checkReport) enterPath(name string) (leave func()) {
+	r.path = append(r.path, name)
+	return func() {
+		r.path = r.path[:len(r.path)-1]
+	}
+}
+
+func indexPath(name string, index int) string {
+	return fmt.Sprintf("%s[%d]", name, index)
+}
+
+func (r *checkReport) currentPath() string {
+	return strings.Join(r.path, ".")
+}
+
+// checkPath 在下一级 YAML 路径下执行检查
+func (r *checkReport) checkPath(name string, check func(report *checkReport) ConfigError) ConfigError {
+	leave := r.enterPath(name)
+	defer leave()
+	return check(r)
+}
+
+// keepChecking 遇到错误后是否继续检查其余配置项（仅检查模式）
+func (r *checkReport) keepChecking() bool {
+	return r.collect
+}
+
+// error 检查发现的错误，消息前附加当前的 YAML 路径，检查模式下只记录不输出
+func (r *checkReport) error(msg string) ConfigError {
+	return r.issue(msg, true)
+}
+
+// warning 检查发现的警告，同 error
+func (r *checkReport) warning(msg string) ConfigError {
+	return r.issue(msg, false)
+}
+
+func (r *checkReport) issue(msg string, isError bool) ConfigError {
+	path := r.currentPath()
+
+	if r.collect {
+		r.issues = append(r.issues, &ConfigIssue{Path: path, Msg: msg, IsError: isError})
+	}
+
+	if path != "" {
+		msg = fmt.Sprintf("%s: %s", path, msg)
+	}
+
+	if r.collect {
+		return &configError{msg: msg, isError: isError}
+	} else if isError {
+		return NewConfigError(msg)
+	}
+	return NewConfigWarning(msg)
+}
+
+var decodeErrorLine = regexp.MustCompile(`^line (\d+): (.*)$`)
+
+// addDecodeIssues 将解析时的类型错误（yaml.TypeError，每一项形如 line 8: ...）按所在行转换为带有 YAML 路径的问题，
+// 其余选项已经正常解析；不是类型错误时返回 false
+func (y *YamlConfig) addDecodeIssues(file string, doc *yaml.Node, err error) bool {
+	typeErr, ok := err.(*yaml.TypeError)
+	if !ok {
+		return false
+	}
+
+	paths := make(map[int]string)
+	nodePaths(doc, "", paths)
+
+	for _, e := range typeErr.Errors {
+		issue := &ConfigIssue{Msg: fmt.Sprintf("%s: %s", file, e), IsError: true}
+
+		if m := decodeErrorLine.FindStringSubmatch(e); m != nil {
+			line, _ := strconv.Atoi(m[1])
+			issue.Path = paths[line]
+			issue.Msg = fmt.Sprintf("%s line %d: %s", file, line, m[2])
+		}
+
+		y.decodeIssues = append(y.decodeIssues, issue)
+	}
+
+	return true
+}
+
+// nodePaths 记录每一行最内层的值所在的 YAML 路径，例如 tcp.forward[0].src；流式（[...]、{...}）的列表和映射整体作为一个值
+func nodePaths(node *yaml.Node, path string, paths map[int]string) {
+	paths[node.Line] = path
+
+	if node.Style&yaml.FlowStyle != 0 {
+		return
+	}
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, n := range node.Content {
+			nodePaths(n, path, paths)
+		}
+	case yaml.SequenceNode:
+		for i, n := range node.Content {
+			nodePaths(n, indexPath(path, i), paths)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			if path != "" {
+				key = path + "." + key
+			}
+			nodePaths(node.Content[i+1], key, paths)
+		}
+	}
+}
+
+// CheckConfigFile 解析并检查配置文件（设置默认值、检查配置并解析地址），返回全部错误和警告；
+// 不会影响当前运行的配置，也不会连接 Redis、SQLite 或监听端口
+func CheckConfigFile(filepath string) (issues []*ConfigIssue, err error) {
+	y := new(YamlConfig)
+
+	err = y.Init()
+	if err != nil {
+		return nil, err
+	}
+
+	parserErr := y.parser(filepath)
+	if parserErr != nil {
+		return nil, parserErr
+	}
+
+	y.setDefault()
+	return collectIssues(y.check), nil
+}
+
+// collectIssues 在检查模式下执行检查，返回全部错误和警告
+func collectIssues(check func(report *checkReport) ConfigError) []*ConfigIssue {
+	report := &checkReport{collect: true}
+	_ = check(report)
+	return report.issues
+}
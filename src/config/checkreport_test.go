@@ -0,0 +1,103 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const invalidConfig = `api:
+  app-code: x
+redis:
+  address: 127.0.0.1:6379
+sqlite:
+  path: test.db
+tcp:
+  forward:
+    - src: 99999
+      ipv4-dest: 127.0.0.1:1
+`
+
+// TestCheckConfigFileConcurrent 同时检查多个配置（以及检查以外创建错误）时，每次检查的路径和问题互不影响
+func TestCheckConfigFileConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte(invalidConfig), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const want = "error: tcp.forward[0]: src port must be between 1 and 65535"
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for n := 0; n < 20; n++ {
+				issues, err := CheckConfigFile(path)
+				if err != nil {
+					t.Error(err)
+					return
+				} else if len(issues) != 1 || issues[0].String() != want {
+					t.Errorf("issues = %v, want [%s]", issues, want)
+					return
+				}
+			}
+		}()
+	}
+
+	// 检查以外（例如重新加载时）创建的错误不会加入检查结果，也不会带有检查中的 YAML 路径
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		for n := 0; n < 20; n++ {
+			if err := NewConfigError("runtime error"); err.Error() != "runtime error" {
+				t.Errorf("runtime error = %q, want %q", err.Error(), "runtime error")
+				return
+			}
+		}
+	}()
+
+	wg.Wait()
+}
+
+// TestCheckConfigFileDecodeIssues 类型或格式不正确的选项按 YAML 路径报告，并继续检查其余配置项
+func TestCheckConfigFileDecodeIssues(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := strings.Replace(invalidConfig, "    - src: 99999\n", "    - src: abc\n      dest-refresh-seconds: soon\n    - src: [1]\n      ipv4-dest: 127.0.0.1:2\n    - src: 99999\n", 1)
+	err := os.WriteFile(path, []byte(content), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issues, err := CheckConfigFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"tcp.forward[0].src":                  "config.yaml line 9: bad port 'abc'",
+		"tcp.forward[0].dest-refresh-seconds": "config.yaml line 10: cannot unmarshal !!str `soon` into int64",
+		"tcp.forward[1].src":                  "config.yaml line 11: port must be a number or a range like 30000-30100",
+		"tcp.forward[2]":                      "src port must be between 1 and 65535",
+	}
+
+	got := make(map[string]string, len(issues))
+	for _, i := range issues {
+		if !i.IsError {
+			continue
+		} else if _, ok := got[i.Path]; !ok {
+			got[i.Path] = i.Msg
+		}
+	}
+
+	for path, msg := range want {
+		if got[path] != msg {
+			t.Errorf("issue at %s = %q, want %q", path, got[path], msg)
+		}
+	}
+}
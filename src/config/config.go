@@ -19,6 +19,7 @@ type ConfigStruct struct {
 	configDir      string
 	configFileName string
 	watcher        *fsnotify.Watcher
+	rollback       *Rollback // 启动时使用了最后一次成功应用的配置，重新加载成功后清除
 
 	Yaml *YamlConfig
 }
@@ -94,6 +95,7 @@ func (c *ConfigStruct) Reload() (err ConfigError) {
 		configDir:      c.configDir,
 		configFileName: c.configFileName,
 		watcher:        c.watcher,
+		rollback:       c.rollback,
 		Yaml:           c.Yaml,
 		// 新建类型
 	}
@@ -108,6 +110,7 @@ func (c *ConfigStruct) Reload() (err ConfigError) {
 				configDir:      bak.configDir,
 				configFileName: bak.configFileName,
 				watcher:        bak.watcher,
+				rollback:       bak.rollback,
 				Yaml:           bak.Yaml,
 				// 新建类型 Lock不需要复制
 			}
@@ -138,6 +141,7 @@ func (c *ConfigStruct) Reload() (err ConfigError) {
 
 	locationOnce = new(sync.Once)
 	c.configReady = true
+	c.rollback = nil
 	return nil
 }
 
@@ -168,7 +172,7 @@ func (c *ConfigStruct) SetDefault() {
 }
 
 func (c *ConfigStruct) check() (err ConfigError) {
-	err = c.Yaml.check()
+	err = c.Yaml.check(new(checkReport))
 	if err != nil && err.IsError() {
 		return err
 	}
@@ -264,3 +268,27 @@ func (c *ConfigStruct) GetConfigFileName() string {
 	// 不需要检查Ready
 	return c.configFileName
 }
+
+// GetConfigFiles 加载的全部配置文件（主配置文件以及 include 的文件）
+func (c *ConfigStruct) GetConfigFiles() []string {
+	c.ConfigLock.Lock()
+	defer c.ConfigLock.Unlock()
+
+	if c.Yaml == nil {
+		return []string{c.configPath}
+	}
+
+	return c.Yaml.Files()
+}
+
+// GetIncludePatterns include 的通配符（包括目录）
+func (c *ConfigStruct) GetIncludePatterns() []string {
+	c.ConfigLock.Lock()
+	defer c.ConfigLock.Unlock()
+
+	if c.Yaml == nil {
+		return nil
+	}
+
+	return c.Yaml.IncludePatterns()
+}
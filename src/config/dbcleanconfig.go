@@ -10,9 +10,11 @@ type DBCleanConfig struct {
 
 	IfaceRecordSaveRetentionPeriod string `yaml:"iface-record-save-retention-period"`
 	SSHRecordSaveRetentionPeriod   string `yaml:"ssh-record-save-retention-period"`
+	NotifyDeadSaveRetentionPeriod  string `yaml:"notify-dead-save-retention-period"`
 
 	IfaceRecordSaveTime time.Duration `yaml:"-"`
 	SSHRecordSaveTime   time.Duration `yaml:"-"`
+	NotifyDeadSaveTime  time.Duration `yaml:"-"`
 }
 
 func (d *DBCleanConfig) setDefault() {
@@ -28,31 +30,46 @@ func (d *DBCleanConfig) setDefault() {
 		d.SSHRecordSaveRetentionPeriod = "3M"
 	}
 
+	if d.NotifyDeadSaveRetentionPeriod == "" {
+		d.NotifyDeadSaveRetentionPeriod = "1M"
+	}
+
 	return
 }
 
-func (d *DBCleanConfig) check() (err ConfigError) {
+func (d *DBCleanConfig) check(report *checkReport) (err ConfigError) {
 	d.IfaceRecordSaveTime = utils.ReadTimeDuration(d.IfaceRecordSaveRetentionPeriod)
 	d.SSHRecordSaveTime = utils.ReadTimeDuration(d.SSHRecordSaveRetentionPeriod)
+	d.NotifyDeadSaveTime = utils.ReadTimeDuration(d.NotifyDeadSaveRetentionPeriod)
 
 	if d.IfaceRecordSaveTime == 0 {
-		return NewConfigError("bad iface-record-save-retention-period")
+		return report.error("bad iface-record-save-retention-period")
 	}
 
 	if d.SSHRecordSaveTime == 0 {
-		return NewConfigError("bad ssh-record-save-retention-period")
+		return report.error("bad ssh-record-save-retention-period")
+	}
+
+	if d.NotifyDeadSaveTime == 0 {
+		return report.error("bad notify-dead-save-retention-period")
 	}
 
 	if d.IfaceRecordSaveTime == -1 {
-		_ = NewConfigWarning("iface-record-save-retention-period is set to be saved permanently")
+		_ = report.warning("iface-record-save-retention-period is set to be saved permanently")
 	} else if d.IfaceRecordSaveTime < time.Minute*5 {
-		return NewConfigError("bad iface-record-save-retention-period, must more than 5 minute")
+		return report.error("bad iface-record-save-retention-period, must more than 5 minute")
 	}
 
 	if d.SSHRecordSaveTime == -1 {
-		_ = NewConfigWarning("ssh-record-save-retention-period is set to be saved permanently")
+		_ = report.warning("ssh-record-save-retention-period is set to be saved permanently")
 	} else if d.SSHRecordSaveTime < time.Minute*5 {
-		return NewConfigError("bad ssh-record-save-retention-period, must more than 5 minute")
+		return report.error("bad ssh-record-save-retention-period, must more than 5 minute")
+	}
+
+	if d.NotifyDeadSaveTime == -1 {
+		_ = report.warning("notify-dead-save-retention-period is set to be saved permanently")
+	} else if d.NotifyDeadSaveTime < time.Minute*5 {
+		return report.error("bad notify-dead-save-retention-period, must more than 5 minute")
 	}
 
 	return nil
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"github.com/SongZihuan/huan-springboard/src/ipcheck"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const forwardConfig = `api:
+  app-code: x
+redis:
+  address: 127.0.0.1:6379
+sqlite:
+  path: test.db
+tcp:
+  forward:
+    - src: 8080
+      ipv4-dest: localhost:80
+      dest-refresh-seconds: 60
+ssh:
+  forward:
+    - src: 2222
+      ipv4-dest: localhost:22
+      dest-refresh-seconds: 60
+`
+
+// checkTestConfig 像重新加载配置一样解析并检查一次配置文件，返回检查发现的问题
+func checkTestConfig(t *testing.T, content string) (*YamlConfig, []*ConfigIssue) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte(content), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	y := new(YamlConfig)
+	err = y.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parserErr := y.parser(path)
+	if parserErr != nil {
+		t.Fatal(parserErr)
+	}
+
+	y.setDefault()
+	return y, collectIssues(y.check)
+}
+
+// loadTestConfig 解析并检查一次配置文件，检查出现错误时测试失败
+func loadTestConfig(t *testing.T, content string) *YamlConfig {
+	y, issues := checkTestConfig(t, content)
+	for _, i := range issues {
+		if i.IsError {
+			t.Fatal(i.String())
+		}
+	}
+
+	return y
+}
+
+// TestForwardEqualAfterReload 重新加载未修改的主机名目标时保留转发（解析结果的过期时间不同）
+func TestForwardEqualAfterReload(t *testing.T) {
+	ipcheck.AssumeDualStack()
+
+	old := loadTestConfig(t, forwardConfig)
+	time.Sleep(10 * time.Millisecond)
+	reload := loadTestConfig(t, forwardConfig)
+
+	oldTcp, newTcp := old.TCP.Forward[0], reload.TCP.Forward[0]
+	if oldTcp.IPv4DestTarget == nil || oldTcp.IPv4DestTarget.Host() != "localhost" {
+		t.Fatalf("ipv4 target = %v, want localhost", oldTcp.IPv4DestTarget)
+	} else if reflect.DeepEqual(oldTcp, newTcp) {
+		t.Fatal("targets are expected to carry different runtime state")
+	}
+
+	if !oldTcp.Equal(newTcp) {
+		t.Error("unchanged tcp forward is not kept after reload")
+	} else if !old.SSH.Forward[0].Equal(reload.SSH.Forward[0]) {
+		t.Error("unchanged ssh forward is not kept after reload")
+	}
+
+	changes := []struct {
+		old string
+		new string
+	}{
+		{"localhost:80", "localhost:81"},
+		{"localhost:80", "127.0.0.1:80"},
+		{"dest-refresh-seconds: 60\nssh", "dest-refresh-seconds: 30\nssh"},
+		{"ipv4-dest: localhost:80", "ipv4-dest: localhost:80\n      allow-cross: disable"},
+		{"ipv4-dest: localhost:80", "ipv4-dest: localhost:80\n      upstream:\n        nodelay: disable"},
+	}
+
+	for _, c := range changes {
+		changed := loadTestConfig(t, strings.Replace(forwardConfig, c.old, c.new, 1))
+		if oldTcp.Equal(changed.TCP.Forward[0]) {
+			t.Errorf("tcp forward changed from %q to %q is kept", c.old, c.new)
+		}
+	}
+
+	changed := loadTestConfig(t, strings.Replace(forwardConfig, "localhost:22", "localhost:2022", 1))
+	if old.SSH.Forward[0].Equal(changed.SSH.Forward[0]) {
+		t.Error("changed ssh forward is kept")
+	}
+}
+
+// TestForwardCheckIssues 转发选项组合的检查结果
+func TestForwardCheckIssues(t *testing.T) {
+	ipcheck.AssumeDualStack()
+
+	fallbackProxy := "cross-fallback with ipv4-dest-proxy or ipv6-dest-proxy"
+	transparentCross := "transparent can not be used with cross forwarding"
+
+	tests := []struct {
+		old     string
+		new     string
+		path    string
+		msg     string // 为空表示没有问题
+		isError bool
+	}{
+		{"ipv4-dest: localhost:80", "ipv4-dest: localhost:80", "", "", false},
+		{"ipv4-dest: localhost:80", "ipv4-dest: localhost:80\n      cross-fallback: enable", "tcp.forward[0]", fallbackProxy, false},
+		{"ipv4-dest: localhost:80", "ipv4-dest: localhost:80\n      cross-fallback: enable\n      ipv4-dest-proxy: disable\n      ipv6-dest-proxy: disable", "", "", false},
+		{"ipv4-dest: localhost:22", "ipv4-dest: localhost:22\n      cross-fallback: enable", "", "", false},
+		{"ipv4-dest: localhost:80", "ipv4-dest: localhost:80\n      transparent: enable", "tcp.forward[0]", transparentCross, true},
+		{"ipv4-dest: localhost:80", "ipv4-dest: localhost:80\n      transparent: enable\n      allow-cross: disable", "", "", false},
+		{"ipv4-dest: localhost:80", "ipv4-dest: 127.0.0.1:80\n      ipv6-dest: \"[::1]:80\"\n      transparent: enable", "", "", false},
+		{"ipv4-dest: localhost:80", "ipv4-dest: 127.0.0.1:80\n      ipv6-dest: \"[::1]:80\"\n      transparent: enable\n      cross-fallback: enable", "tcp.forward[0]", transparentCross, true},
+		{"ipv4-dest: localhost:22", "ipv4-dest: localhost:22\n      transparent: enable", "ssh.forward[0]", transparentCross, true},
+	}
+
+	for _, test := range tests {
+		content := strings.Replace(forwardConfig, test.old, test.new, 1)
+		_, issues := checkTestConfig(t, content)
+
+		var found *ConfigIssue
+		for _, i := range issues {
+			if strings.HasPrefix(i.Path, "tcp.forward") || strings.HasPrefix(i.Path, "ssh.forward") {
+				found = i
+				break
+			}
+		}
+
+		if test.msg == "" {
+			if found != nil {
+				t.Errorf("%q: unexpected issue %s", test.new, found.String())
+			}
+		} else if found == nil {
+			t.Errorf("%q: expected issue %q", test.new, test.msg)
+		} else if found.Path != test.path || !strings.Contains(found.Msg, test.msg) || found.IsError != test.isError {
+			t.Errorf("%q: got %s, want %s: %s (error %v)", test.new, found.String(), test.path, test.msg, test.isError)
+		}
+	}
+}
@@ -5,6 +5,8 @@ import (
 	resource "github.com/SongZihuan/huan-springboard"
 	"github.com/SongZihuan/huan-springboard/src/utils"
 	"os"
+	"strings"
+	"time"
 )
 
 const EnvModeName = "HUAN_SPRINGBOARD_MODE"
@@ -73,13 +75,19 @@ func (g *GlobalConfig) setDefault() {
 	return
 }
 
-func (g *GlobalConfig) Check() ConfigError {
+func (g *GlobalConfig) Check(report *checkReport) ConfigError {
 	if g.Mode != DebugMode && g.Mode != ReleaseMode && g.Mode != TestMode {
-		return NewConfigError("bad mode")
+		return report.error("bad mode")
 	}
 
 	if _, ok := levelMap[g.LogLevel]; !ok {
-		return NewConfigError("log level error")
+		return report.error("log level error")
+	}
+
+	if tz := strings.ToLower(g.Timezone); tz != "utc" && tz != "local" {
+		if _, err := time.LoadLocation(g.Timezone); err != nil {
+			_ = report.warning(fmt.Sprintf("bad time-zone '%s', UTC will be used: %s", g.Timezone, err.Error()))
+		}
 	}
 
 	g.SystemName = fmt.Sprintf("%s-%s", resource.Name, g.Name)
@@ -0,0 +1,267 @@
+package config
+
+import (
+	"fmt"
+	"github.com/SongZihuan/huan-springboard/src/utils"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 目录形式的 include 加载目录下的这些文件（drop-in 目录，例如 conf.d）
+var includeDirPatterns = []string{"*.yaml", "*.yml"}
+
+// mergedLists 跨文件追加合并的列表；其他选项由后加载的文件覆盖先加载的文件
+type mergedLists struct {
+	tcpRules      []*TcpRuleConfig
+	tcpForward    []*TcpForwardConfig
+	sshRules      []*SshRuleConfig
+	sshCountRules []*SshCountRuleConfig
+	sshForward    []*SshForwardConfig
+}
+
+func (y *YamlConfig) lists() mergedLists {
+	return mergedLists{
+		tcpRules:      y.TCP.RuleList.RuleList,
+		tcpForward:    y.TCP.Forward,
+		sshRules:      y.SSH.RuleList.RuleList,
+		sshCountRules: y.SSH.RuleList.CountRules,
+		sshForward:    y.SSH.Forward,
+	}
+}
+
+func (y *YamlConfig) setLists(l mergedLists) {
+	y.TCP.RuleList.RuleList = l.tcpRules
+	y.TCP.Forward = l.tcpForward
+	y.SSH.RuleList.RuleList = l.sshRules
+	y.SSH.RuleList.CountRules = l.sshCountRules
+	y.SSH.Forward = l.sshForward
+}
+
+// appendLists 追加 part 中的列表，并记录每一项来自的文件
+func (y *YamlConfig) appendLists(part *YamlConfig, origin string) {
+	l := y.lists()
+	p := part.lists()
+
+	y.setLists(mergedLists{
+		tcpRules:      append(l.tcpRules, p.tcpRules...),
+		tcpForward:    append(l.tcpForward, p.tcpForward...),
+		sshRules:      append(l.sshRules, p.sshRules...),
+		sshCountRules: append(l.sshCountRules, p.sshCountRules...),
+		sshForward:    append(l.sshForward, p.sshForward...),
+	})
+
+	for path, n := range map[string]int{
+		"tcp.rules":       len(p.tcpRules),
+		"tcp.forward":     len(p.tcpForward),
+		"ssh.rules":       len(p.sshRules),
+		"ssh.count-rules": len(p.sshCountRules),
+		"ssh.forward":     len(p.sshForward),
+	} {
+		for i := 0; i < n; i++ {
+			y.origins[path] = append(y.origins[path], origin)
+		}
+	}
+}
+
+// parseFile 加载一个配置文件，然后按顺序加载其 include 的文件（深度优先）
+func (y *YamlConfig) parseFile(path string, visited map[string]bool) ParserError {
+	path, err := utils.CleanFilePathAbs(path)
+	if err != nil {
+		return NewParserError(err, err.Error())
+	}
+
+	if visited[path] {
+		return NewParserError(path, fmt.Sprintf("%s is included more than once", path))
+	}
+	visited[path] = true
+
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return NewParserError(err, err.Error())
+	}
+
+	part, parserErr := y.parseBytes(path, file)
+	if parserErr != nil {
+		return parserErr
+	}
+
+	for _, include := range part.Include {
+		files, err := y.resolveInclude(filepath.Dir(path), include)
+		if err != nil {
+			return NewParserError(err, fmt.Sprintf("%s: include '%s': %s", path, include, err.Error()))
+		}
+
+		for _, f := range files {
+			parserErr = y.parseFile(f, visited)
+			if parserErr != nil {
+				return parserErr
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseBytes 合并一个文件的内容：先覆盖普通选项（列表会被替换，随后恢复），再单独解析一次得到本文件的列表和 include
+func (y *YamlConfig) parseBytes(path string, file []byte) (*YamlConfig, ParserError) {
+	var doc yaml.Node
+	err := yaml.Unmarshal(file, &doc)
+	if err != nil {
+		return nil, NewParserError(err, fmt.Sprintf("%s: %s", path, err.Error()))
+	}
+
+	// 替换环境变量和文件引用
+	err = expandNode(&doc, filepath.Dir(path))
+	if err != nil {
+		return nil, NewParserError(err, fmt.Sprintf("%s: %s", path, err.Error()))
+	}
+
+	var part YamlConfig
+	if doc.Kind != 0 { // 空文件
+		lists := y.lists()
+		err = doc.Decode(y)
+		if err != nil && !y.addDecodeIssues(y.relPath(path), &doc, err) {
+			return nil, NewParserError(err, fmt.Sprintf("%s: %s", path, err.Error()))
+		}
+		y.setLists(lists)
+
+		err = doc.Decode(&part)
+		if _, ok := err.(*yaml.TypeError); err != nil && !ok { // 类型错误已经记录
+			return nil, NewParserError(err, fmt.Sprintf("%s: %s", path, err.Error()))
+		}
+	}
+
+	y.files = append(y.files, path)
+	y.contents = append(y.contents, file)
+	y.appendLists(&part, y.relPath(path))
+
+	return &part, nil
+}
+
+// resolveInclude 解析 include 的一项：文件、通配符（按文件名排序）或者目录（目录下的 *.yaml 和 *.yml，按文件名排序），相对路径基于 include 所在文件的目录
+func (y *YamlConfig) resolveInclude(dir string, include string) ([]string, error) {
+	include = strings.TrimSpace(include)
+	if include == "" {
+		return nil, fmt.Errorf("empty path")
+	}
+
+	if !filepath.IsAbs(include) {
+		include = filepath.Join(dir, include)
+	}
+
+	patterns := []string{include}
+	if strings.ContainsAny(include, "*?[") {
+		// 通配符，没有匹配的文件时不报错
+	} else if info, err := os.Stat(include); err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		return []string{include}, nil
+	} else {
+		patterns = make([]string, 0, len(includeDirPatterns))
+		for _, p := range includeDirPatterns {
+			patterns = append(patterns, filepath.Join(include, p))
+		}
+	}
+
+	res := make([]string, 0, 10)
+	for _, p := range patterns {
+		matches, err := filepath.Glob(p)
+		if err != nil {
+			return nil, fmt.Errorf("bad pattern: %s", err.Error())
+		}
+
+		y.patterns = append(y.patterns, p)
+
+		for _, m := range matches {
+			if info, err := os.Stat(m); err == nil && !info.IsDir() {
+				res = append(res, m)
+			}
+		}
+	}
+
+	sort.Strings(res)
+	return res, nil
+}
+
+// relPath 相对于主配置文件目录的路径，用于标记来源
+func (y *YamlConfig) relPath(path string) string {
+	if len(y.files) == 0 {
+		return filepath.Base(path)
+	}
+
+	rel, err := filepath.Rel(filepath.Dir(y.files[0]), path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+
+	return rel
+}
+
+// marshal 输出合并后的配置：不输出 include，隐藏密钥，存在多个文件时在列表的每一项前注释其来源
+func (y *YamlConfig) marshal() ([]byte, error) {
+	var node yaml.Node
+
+	err := node.Encode(y)
+	if err != nil {
+		return nil, err
+	}
+
+	removeMappingKey(&node, "include")
+	redactNode(&node)
+
+	if len(y.files) > 1 {
+		from := make([]string, 0, len(y.files))
+		for _, f := range y.files {
+			from = append(from, y.relPath(f))
+		}
+		node.HeadComment = "merged from: " + strings.Join(from, ", ")
+
+		for path, origins := range y.origins {
+			seq := findMappingPath(&node, strings.Split(path, "."))
+			if seq == nil || seq.Kind != yaml.SequenceNode || len(seq.Content) != len(origins) {
+				continue
+			}
+
+			for i, item := range seq.Content {
+				item.HeadComment = "from: " + origins[i]
+			}
+		}
+	}
+
+	return yaml.Marshal(&node)
+}
+
+func findMappingPath(node *yaml.Node, path []string) *yaml.Node {
+	for _, key := range path {
+		if node == nil || node.Kind != yaml.MappingNode {
+			return nil
+		}
+
+		var next *yaml.Node = nil
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				next = node.Content[i+1]
+				break
+			}
+		}
+		node = next
+	}
+
+	return node
+}
+
+func removeMappingKey(node *yaml.Node, key string) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"fmt"
+	"github.com/SongZihuan/huan-springboard/src/flagparser"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+const lastGoodFileName = "last-good.yaml"
+
+type snapshotFile struct {
+	Path    string `yaml:"path"`
+	Content string `yaml:"content"`
+}
+
+// lastGoodSnapshot 最后一次成功应用的配置：按加载顺序保存全部文件的原始内容，
+// 环境变量和文件引用不展开（使用快照时重新替换），因此快照不会额外泄露密钥
+type lastGoodSnapshot struct {
+	SavedAt  time.Time      `yaml:"saved-at"`
+	Patterns []string       `yaml:"patterns"` // include 的通配符，用于监听新增的文件
+	Files    []snapshotFile `yaml:"files"`
+}
+
+// Rollback 启动时配置文件检查失败，改为使用最后一次成功应用的配置
+type Rollback struct {
+	Reason  string    // 配置文件检查失败的原因
+	SavedAt time.Time // 快照的保存时间
+	Files   []string  // 快照中的文件
+}
+
+func lastGoodPath() string {
+	dir := flagparser.StateDir()
+	if dir == "" {
+		return ""
+	}
+
+	return filepath.Join(dir, lastGoodFileName)
+}
+
+func loadLastGood(path string) (*lastGoodSnapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var snap lastGoodSnapshot
+	err = yaml.Unmarshal(data, &snap)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err.Error())
+	} else if len(snap.Files) == 0 {
+		return nil, fmt.Errorf("%s: no config file in snapshot", path)
+	}
+
+	return &snap, nil
+}
+
+// parserSnapshot 按顺序合并快照中的文件；快照已包含 include 的全部文件，因此不再处理 include
+func (y *YamlConfig) parserSnapshot(snap *lastGoodSnapshot) ParserError {
+	y.files = make([]string, 0, len(snap.Files))
+	y.contents = make([][]byte, 0, len(snap.Files))
+	y.patterns = slices.Clone(snap.Patterns)
+	y.origins = make(map[string][]string, 5)
+
+	for _, f := range snap.Files {
+		_, err := y.parseBytes(f.Path, []byte(f.Content))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fallback 配置文件检查失败时改为加载最后一次成功应用的配置
+func (c *ConfigStruct) fallback(reason string) ConfigError {
+	path := lastGoodPath()
+	if path == "" {
+		return NewConfigError("--state-dir is not set")
+	}
+
+	snap, err := loadLastGood(path)
+	if err != nil {
+		return NewConfigError("load last-known-good config error: " + err.Error())
+	}
+
+	c.ConfigLock.Lock()
+	defer c.ConfigLock.Unlock()
+
+	err = c.reload()
+	if err != nil {
+		return NewConfigError("reload error: " + err.Error())
+	}
+
+	parserErr := c.Yaml.parserSnapshot(snap)
+	if parserErr != nil {
+		return NewConfigError("last-known-good config parser error: " + parserErr.Error())
+	}
+	c.yamlHasParser = true
+
+	c.SetDefault()
+
+	cfgErr := c.check()
+	if cfgErr != nil && cfgErr.IsError() {
+		return cfgErr
+	}
+
+	locationOnce = new(sync.Once)
+	c.configReady = true
+	c.rollback = &Rollback{
+		Reason:  reason,
+		SavedAt: snap.SavedAt,
+		Files:   c.Yaml.Files(),
+	}
+	return nil
+}
+
+// saveLastGood 保存当前配置的快照，先写入临时文件再重命名，避免快照损坏；
+// 未设置 --state-dir 或者当前配置本身来自快照时不保存
+func (c *ConfigStruct) saveLastGood() error {
+	c.ConfigLock.Lock()
+	defer c.ConfigLock.Unlock()
+
+	path := lastGoodPath()
+	if path == "" || c.rollback != nil {
+		return nil
+	}
+
+	if !c.isReady() {
+		return fmt.Errorf("config is not ready")
+	}
+
+	snap := lastGoodSnapshot{
+		SavedAt:  time.Now(),
+		Patterns: c.Yaml.patterns,
+		Files:    make([]snapshotFile, 0, len(c.Yaml.files)),
+	}
+
+	for i, f := range c.Yaml.files {
+		snap.Files = append(snap.Files, snapshotFile{
+			Path:    f,
+			Content: string(c.Yaml.contents[i]),
+		})
+	}
+
+	data, err := yaml.Marshal(&snap)
+	if err != nil {
+		return err
+	}
+
+	// 配置文件可能包含密钥，只允许当前用户读写
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	_ = os.Remove(tmp)
+
+	err = os.WriteFile(tmp, data, 0600)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+func (c *ConfigStruct) GetRollback() *Rollback {
+	c.ConfigLock.Lock()
+	defer c.ConfigLock.Unlock()
+
+	return c.rollback
+}
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+const unixPrefix = "unix:"
+
+// ListenBind 转发的一个监听地址
+type ListenBind struct {
+	Address string        // 配置中的地址，留空表示全部地址
+	IPv4    *net.TCPAddr  // ipv4 监听地址，nil 表示不监听 ipv4
+	IPv6    *net.TCPAddr  // ipv6 监听地址，nil 表示不监听 ipv6
+	Unix    *net.UnixAddr // unix socket 监听地址，不为 nil 时不监听 ipv4 和 ipv6
+	Ports   PortRange     // 监听的端口，IPv4 和 IPv6 的端口为第一个端口
+}
+
+// Key 监听地址和端口，例如 :22（全部地址）、192.0.2.1:22、[2001:db8::1]:22、:30000-30100、unix:/run/app.sock
+func (b *ListenBind) Key() string {
+	if b.Unix != nil {
+		return unixPrefix + b.Unix.Name
+	} else if b.IPv4 != nil && b.IPv6 != nil {
+		return ":" + b.Ports.String()
+	} else if b.IPv4 != nil {
+		return b.IPv4.IP.String() + ":" + b.Ports.String()
+	} else if b.IPv6 != nil {
+		return "[" + b.IPv6.IP.String() + "]:" + b.Ports.String()
+	}
+	return ""
+}
+
+// Overlaps 两个监听地址是否冲突：端口范围有重叠且地址相同，或者其中一个为同一协议族的全部地址
+func (b *ListenBind) Overlaps(o *ListenBind) bool {
+	if b.Unix != nil || o.Unix != nil {
+		return b.Unix != nil && o.Unix != nil && b.Unix.Name == o.Unix.Name
+	} else if !b.Ports.Overlaps(o.Ports) {
+		return false
+	}
+	return tcpAddrOverlaps(b.IPv4, o.IPv4) || tcpAddrOverlaps(b.IPv6, o.IPv6)
+}
+
+func tcpAddrOverlaps(a *net.TCPAddr, b *net.TCPAddr) bool {
+	if a == nil || b == nil {
+		return false
+	}
+
+	return a.IP == nil || b.IP == nil || a.IP.IsUnspecified() || b.IP.IsUnspecified() || a.IP.Equal(b.IP)
+}
+
+// resolveListen 解析转发的监听地址：每一项为 IP 地址（ipv4 地址只监听 ipv4，ipv6 地址只监听 ipv6，0.0.0.0 和 :: 分别表示 ipv4 和 ipv6 的全部地址），
+// 留空表示两种协议族的全部地址，unix: 开头表示监听 unix socket（例如 unix:/run/app.sock）
+func resolveListen(report *checkReport, listen []string, ports PortRange) ([]*ListenBind, ConfigError) {
+	if len(listen) == 0 {
+		listen = []string{""}
+	}
+
+	res := make([]*ListenBind, 0, len(listen))
+	for _, l := range listen {
+		address := strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(l), "["), "]")
+		bind := &ListenBind{Address: address, Ports: ports}
+
+		if path, ok := strings.CutPrefix(address, unixPrefix); ok {
+			if path == "" {
+				return nil, report.error(fmt.Sprintf("listen address '%s' has no socket path", l))
+			}
+			bind.Unix = &net.UnixAddr{Name: path, Net: "unix"}
+		} else if address == "" {
+			bind.IPv4 = &net.TCPAddr{Port: int(ports.First)}
+			bind.IPv6 = &net.TCPAddr{Port: int(ports.First)}
+		} else if ip := net.ParseIP(address); ip == nil {
+			return nil, report.error(fmt.Sprintf("listen address '%s' is not an ip address", l))
+		} else if ip4 := ip.To4(); ip4 != nil && !strings.Contains(address, ":") {
+			bind.IPv4 = &net.TCPAddr{IP: ip4, Port: int(ports.First)}
+		} else {
+			bind.IPv6 = &net.TCPAddr{IP: ip, Port: int(ports.First)}
+		}
+
+		for _, b := range res {
+			if b.Overlaps(bind) {
+				return nil, report.error(fmt.Sprintf("listen address '%s' overlaps '%s'", bind.Key(), b.Key()))
+			}
+		}
+
+		res = append(res, bind)
+	}
+
+	return res, nil
+}
+
+// isUnixAddress 地址是否为 unix socket（unix: 开头）
+func isUnixAddress(address string) bool {
+	return strings.HasPrefix(strings.TrimSpace(address), unixPrefix)
+}
+
+// onlyUnixListen 是否只监听 unix socket（此时可以不设置端口）
+func onlyUnixListen(listen []string) bool {
+	if len(listen) == 0 {
+		return false
+	}
+
+	for _, l := range listen {
+		if !isUnixAddress(l) {
+			return false
+		}
+	}
+	return true
+}
+
+// resolveUnixDest 解析 unix socket 目标地址，address 不是 unix: 开头时返回 nil
+func resolveUnixDest(address string) (*net.UnixAddr, error) {
+	path, ok := strings.CutPrefix(strings.TrimSpace(address), unixPrefix)
+	if !ok {
+		return nil, nil
+	} else if path == "" {
+		return nil, fmt.Errorf("no socket path")
+	}
+
+	if info, err := os.Stat(path); err == nil && info.Mode()&os.ModeSocket == 0 {
+		return nil, fmt.Errorf("'%s' is not a socket", path)
+	}
+
+	return &net.UnixAddr{Name: path, Net: "unix"}, nil
+}
@@ -0,0 +1,175 @@
+package config
+
+import (
+	"fmt"
+	"github.com/SongZihuan/huan-springboard/src/utils"
+	"strings"
+)
+
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+type LogConfig struct {
+	Format  string           `yaml:"format"`  // 控制台日志格式：text/json
+	Console utils.StringBool `yaml:"console"` // 是否输出到控制台（stdout/stderr）
+	File    LogFileConfig    `yaml:"file"`    // 文件输出
+	Access  LogAccessConfig  `yaml:"access"`  // 访问日志
+
+	Syslog   LogSyslogConfig   `yaml:"syslog"`   // syslog 输出（RFC 5424）
+	Journald LogJournaldConfig `yaml:"journald"` // journald 输出（原生协议）
+	Severity LogSeverityConfig `yaml:"severity"` // 日志级别与 syslog 严重性的对应关系，syslog 和 journald 共用
+}
+
+// LogAccessConfig 访问日志，每个连接结束时记录一行
+type LogAccessConfig struct {
+	Enable  utils.StringBool `yaml:"enable"`
+	Format  string           `yaml:"format"`  // text（类似 combined 的单行文本）/json
+	Console utils.StringBool `yaml:"console"` // 是否输出到控制台（stdout），未设置文件时默认输出
+	File    LogFileConfig    `yaml:"file"`    // 文件输出，轮转配置与 log.file 相同
+}
+
+type LogFileConfig struct {
+	Path        string           `yaml:"path"`         // 日志文件路径，为空表示不输出到文件
+	Format      string           `yaml:"format"`       // 文件日志格式：text/json，留空与 log.format 相同
+	MaxSizeMB   int64            `yaml:"max-size-mb"`  // 单个文件的最大大小（单位：MB），超过后轮转，负数表示不按大小轮转
+	RotateHours int64            `yaml:"rotate-hours"` // 按时间轮转的间隔（单位：小时），负数表示不按时间轮转
+	MaxBackups  int64            `yaml:"max-backups"`  // 保留的历史文件数，负数表示不限制
+	MaxAgeDays  int64            `yaml:"max-age-days"` // 历史文件保留天数，负数表示不限制
+	Compress    utils.StringBool `yaml:"compress"`     // 是否使用 gzip 压缩历史文件
+}
+
+func (l *LogConfig) setDefault() {
+	l.Format = strings.ToLower(strings.TrimSpace(l.Format))
+	if l.Format == "" {
+		l.Format = LogFormatText
+	}
+
+	l.Console.SetDefaultEnable()
+	l.File.setDefault(l.Format)
+	l.Access.setDefault()
+	l.Syslog.setDefault()
+	l.Journald.setDefault()
+	l.Severity.setDefault()
+
+	return
+}
+
+func (l *LogConfig) check(report *checkReport) (err ConfigError) {
+	if l.Format != LogFormatText && l.Format != LogFormatJSON {
+		err = report.error(fmt.Sprintf("bad format '%s', must be text/json", l.Format))
+		if !report.keepChecking() {
+			return err
+		}
+	}
+
+	err = report.checkPath("file", l.File.check)
+	if err != nil && err.IsError() && !report.keepChecking() {
+		return err
+	}
+
+	err = report.checkPath("syslog", l.Syslog.check)
+	if err != nil && err.IsError() && !report.keepChecking() {
+		return err
+	}
+
+	err = report.checkPath("journald", l.Journald.check)
+	if err != nil && err.IsError() && !report.keepChecking() {
+		return err
+	}
+
+	err = report.checkPath("severity", l.Severity.check)
+	if err != nil && err.IsError() && !report.keepChecking() {
+		return err
+	}
+
+	if l.Console.IsDisable(false) && l.File.Path == "" && l.Syslog.Enable.IsDisable(true) && l.Journald.Enable.IsDisable(true) {
+		_ = report.warning("console, file, syslog and journald are all disabled, all logs will be dropped")
+	}
+
+	err = report.checkPath("access", l.Access.check)
+	if err != nil && err.IsError() && !report.keepChecking() {
+		return err
+	}
+
+	return nil
+}
+
+func (l *LogAccessConfig) setDefault() {
+	l.Enable.SetDefaultDisable()
+
+	l.Format = strings.ToLower(strings.TrimSpace(l.Format))
+	if l.Format == "" {
+		l.Format = LogFormatText
+	}
+
+	if l.File.Path == "" {
+		l.Console.SetDefaultEnable()
+	} else {
+		l.Console.SetDefaultDisable()
+	}
+
+	l.File.setDefault(l.Format)
+
+	return
+}
+
+func (l *LogAccessConfig) check(report *checkReport) (err ConfigError) {
+	if l.Enable.IsDisable(true) {
+		return nil
+	}
+
+	if l.Format != LogFormatText && l.Format != LogFormatJSON {
+		err = report.error(fmt.Sprintf("bad format '%s', must be text/json", l.Format))
+		if !report.keepChecking() {
+			return err
+		}
+	}
+
+	err = report.checkPath("file", l.File.check)
+	if err != nil && err.IsError() && !report.keepChecking() {
+		return err
+	}
+
+	if l.Console.IsDisable(false) && l.File.Path == "" {
+		_ = report.warning("console is disabled and file path is empty, access logs will be dropped")
+	}
+
+	return nil
+}
+
+func (l *LogFileConfig) setDefault(format string) {
+	l.Format = strings.ToLower(strings.TrimSpace(l.Format))
+	if l.Format == "" {
+		l.Format = format
+	}
+
+	if l.MaxSizeMB == 0 {
+		l.MaxSizeMB = 100
+	}
+
+	if l.RotateHours == 0 {
+		l.RotateHours = 24
+	}
+
+	if l.MaxBackups == 0 {
+		l.MaxBackups = 7
+	}
+
+	if l.MaxAgeDays == 0 {
+		l.MaxAgeDays = 30
+	}
+
+	l.Compress.SetDefaultEnable()
+
+	return
+}
+
+func (l *LogFileConfig) check(report *checkReport) (err ConfigError) {
+	if l.Format != LogFormatText && l.Format != LogFormatJSON {
+		return report.error(fmt.Sprintf("bad format '%s', must be text/json", l.Format))
+	}
+
+	return nil
+}
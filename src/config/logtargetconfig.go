@@ -0,0 +1,217 @@
+package config
+
+import (
+	"fmt"
+	"github.com/SongZihuan/huan-springboard/src/utils"
+	"strconv"
+	"strings"
+)
+
+const (
+	SyslogNetworkUnix = "unix" // 本地 unix socket（先尝试数据报，再尝试流）
+	SyslogNetworkUDP  = "udp"
+	SyslogNetworkTCP  = "tcp"
+)
+
+// syslog 严重性（RFC 5424）
+var syslogSeverityMap = map[string]int{
+	"emerg":   0,
+	"alert":   1,
+	"crit":    2,
+	"err":     3,
+	"error":   3,
+	"warning": 4,
+	"warn":    4,
+	"notice":  5,
+	"info":    6,
+	"debug":   7,
+}
+
+// syslog 设施（RFC 5424）
+var syslogFacilityMap = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+type LogSyslogConfig struct {
+	Enable   utils.StringBool `yaml:"enable"`
+	Network  string           `yaml:"network"`  // unix/udp/tcp
+	Address  string           `yaml:"address"`  // unix 为 socket 路径（默认 /dev/log），udp/tcp 为 host:port
+	Facility string           `yaml:"facility"` // 设施，默认 daemon
+	AppName  string           `yaml:"app-name"` // APP-NAME 字段，默认为程序名称
+
+	ResolveFacility int `yaml:"-"`
+}
+
+type LogJournaldConfig struct {
+	Enable     utils.StringBool `yaml:"enable"`
+	Socket     string           `yaml:"socket"`     // journald 原生协议 socket，默认 /run/systemd/journal/socket
+	Identifier string           `yaml:"identifier"` // SYSLOG_IDENTIFIER 字段，默认为程序名称
+}
+
+// LogSeverityConfig 各日志级别对应的 syslog 严重性，可以使用名称（emerg/alert/crit/err/warning/notice/info/debug）或数字 0-7
+type LogSeverityConfig struct {
+	Debug string `yaml:"debug"`
+	Info  string `yaml:"info"`
+	Warn  string `yaml:"warn"`
+	Error string `yaml:"error"`
+	Panic string `yaml:"panic"`
+
+	Resolve map[string]int `yaml:"-"` // 日志级别 -> 严重性
+}
+
+func (s *LogSyslogConfig) setDefault() {
+	s.Enable.SetDefaultDisable()
+
+	s.Network = strings.ToLower(strings.TrimSpace(s.Network))
+	if s.Network == "" {
+		s.Network = SyslogNetworkUnix
+	}
+
+	if s.Address == "" && s.Network == SyslogNetworkUnix {
+		s.Address = "/dev/log"
+	}
+
+	s.Facility = strings.ToLower(strings.TrimSpace(s.Facility))
+	if s.Facility == "" {
+		s.Facility = "daemon"
+	}
+
+	if s.AppName == "" {
+		s.AppName = utils.GetArgs0Name()
+	}
+
+	return
+}
+
+func (s *LogSyslogConfig) check(report *checkReport) (err ConfigError) {
+	if s.Enable.IsDisable(true) {
+		return nil
+	}
+
+	switch s.Network {
+	case SyslogNetworkUnix, SyslogNetworkUDP, SyslogNetworkTCP:
+		// pass
+	default:
+		return report.error(fmt.Sprintf("bad network '%s', must be unix/udp/tcp", s.Network))
+	}
+
+	if s.Address == "" {
+		return report.error("address must be set")
+	}
+
+	facility, ok := syslogFacilityMap[s.Facility]
+	if !ok {
+		return report.error(fmt.Sprintf("bad facility '%s'", s.Facility))
+	}
+	s.ResolveFacility = facility
+
+	if strings.ContainsAny(s.AppName, " \t\r\n") || len(s.AppName) > 48 {
+		return report.error("app-name must not contain spaces and must be at most 48 characters")
+	}
+
+	return nil
+}
+
+func (j *LogJournaldConfig) setDefault() {
+	j.Enable.SetDefaultDisable()
+
+	if j.Socket == "" {
+		j.Socket = "/run/systemd/journal/socket"
+	}
+
+	if j.Identifier == "" {
+		j.Identifier = utils.GetArgs0Name()
+	}
+
+	return
+}
+
+func (j *LogJournaldConfig) check(report *checkReport) (err ConfigError) {
+	return nil
+}
+
+func (l *LogSeverityConfig) setDefault() {
+	if l.Debug == "" {
+		l.Debug = "debug"
+	}
+
+	if l.Info == "" {
+		l.Info = "info"
+	}
+
+	if l.Warn == "" {
+		l.Warn = "warning"
+	}
+
+	if l.Error == "" {
+		l.Error = "err"
+	}
+
+	if l.Panic == "" {
+		l.Panic = "crit"
+	}
+
+	return
+}
+
+func (l *LogSeverityConfig) check(report *checkReport) (err ConfigError) {
+	l.Resolve = make(map[string]int, 5)
+
+	for _, i := range []struct {
+		level string
+		value string
+	}{
+		{"debug", l.Debug},
+		{"info", l.Info},
+		{"warn", l.Warn},
+		{"error", l.Error},
+		{"panic", l.Panic},
+	} {
+		severity, ok := parseSyslogSeverity(i.value)
+		if !ok {
+			return report.error(fmt.Sprintf("bad severity '%s' for %s", i.value, i.level))
+		}
+		l.Resolve[i.level] = severity
+	}
+
+	return nil
+}
+
+// Severity 返回日志级别对应的 syslog 严重性，未知级别返回 info
+func (l *LogSeverityConfig) Severity(level string) int {
+	severity, ok := l.Resolve[level]
+	if !ok {
+		return syslogSeverityMap["info"]
+	}
+	return severity
+}
+
+func parseSyslogSeverity(s string) (int, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+
+	if n, err := strconv.Atoi(s); err == nil {
+		return n, n >= 0 && n <= 7
+	}
+
+	severity, ok := syslogSeverityMap[s]
+	return severity, ok
+}
@@ -3,7 +3,6 @@ package config
 import (
 	"fmt"
 	"github.com/SongZihuan/huan-springboard/src/flagparser"
-	"gopkg.in/yaml.v3"
 	"os"
 )
 
@@ -17,7 +16,16 @@ func InitConfig(configPath string) ConfigError {
 
 	cfgErr := config.Init()
 	if cfgErr != nil && cfgErr.IsError() {
-		return cfgErr
+		if !flagparser.FallbackLastGood() {
+			return cfgErr
+		}
+
+		fmt.Println("fallback to the last-known-good config") // 错误已经输出
+
+		fallbackErr := config.fallback(cfgErr.Error())
+		if fallbackErr != nil && fallbackErr.IsError() {
+			return NewConfigError(fmt.Sprintf("%s (fallback error: %s)", cfgErr.Error(), fallbackErr.Error()))
+		}
 	}
 
 	if !config.IsReady() {
@@ -56,12 +64,27 @@ func OutputConfig() error {
 		return nil
 	}
 
-	out, err := yaml.Marshal(config.Yaml)
+	out, err := config.Yaml.marshal()
 	if err != nil {
 		return err
 	}
 
-	err = os.WriteFile(outputPath, out, 0644)
+	// 密钥已隐藏，但输出的配置仍可能包含其他敏感信息，只允许当前用户读写；
+	// 文件已存在时 WriteFile 不会修改权限，因此单独修改
+	f, err := os.OpenFile(outputPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	err = f.Chmod(0600)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(out)
 	if err != nil {
 		return err
 	}
@@ -69,6 +92,47 @@ func OutputConfig() error {
 	return nil
 }
 
+// SaveLastGood 保存当前配置为最后一次成功应用的配置（需要设置 --state-dir）
+func SaveLastGood() error {
+	return config.saveLastGood()
+}
+
+// GetRollback 启动时是否改为使用了最后一次成功应用的配置，没有时返回 nil
+func GetRollback() *Rollback {
+	return config.GetRollback()
+}
+
+// MarshalConfig 输出正在运行的配置（合并后的配置，密钥已隐藏）
+func MarshalConfig() ([]byte, error) {
+	return config.GetConfig().marshal()
+}
+
+// MarshalDiskConfig 重新读取磁盘上的配置文件并输出（与 MarshalConfig 格式相同），不影响正在运行的配置；
+// 配置检查不通过时仍然输出配置，同时返回全部错误和警告，只有读取或解析失败时返回 err
+func MarshalDiskConfig() (out []byte, issues []*ConfigIssue, err error) {
+	y := new(YamlConfig)
+
+	err = y.Init()
+	if err != nil {
+		return nil, nil, fmt.Errorf("init error: %s", err.Error())
+	}
+
+	parserErr := y.parser(config.GetConfigPathFile())
+	if parserErr != nil {
+		return nil, nil, fmt.Errorf("parser error: %s", parserErr.Error())
+	}
+
+	y.setDefault()
+	issues = collectIssues(y.check)
+
+	out, err = y.marshal()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return out, issues, nil
+}
+
 func IsReady() bool {
 	return config.IsReady()
 }
@@ -93,4 +157,12 @@ func GetConfigFileName() string {
 	return config.GetConfigFileName()
 }
 
+func GetConfigFiles() []string {
+	return config.GetConfigFiles()
+}
+
+func GetIncludePatterns() []string {
+	return config.GetIncludePatterns()
+}
+
 var config *ConfigStruct
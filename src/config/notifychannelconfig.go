@@ -0,0 +1,105 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	NotifyTypeWebhook  = "webhook"
+	NotifyTypeTelegram = "telegram"
+	NotifyTypeDingTalk = "dingtalk"
+	NotifyTypeFeishu   = "feishu"
+	NotifyTypeSlack    = "slack"
+	NotifyTypeWxRobot  = "wxrobot"
+	NotifyTypeSMTP     = "smtp" // 内置渠道，由 smtp 配置生成，不可在 channels 中声明
+)
+
+const DefaultTelegramAPI = "https://api.telegram.org"
+
+type NotifyChannelConfig struct {
+	Name string `yaml:"name"` // 渠道名称（唯一）
+	Type string `yaml:"type"` // 渠道类型：webhook/telegram/dingtalk/feishu/slack/wxrobot
+
+	URL            string            `yaml:"url"`             // 推送地址，telegram 为 API 地址（默认：https://api.telegram.org）
+	Method         string            `yaml:"method"`          // 请求方法（仅 webhook，默认：POST）
+	Headers        map[string]string `yaml:"headers"`         // 请求头（仅 webhook）
+	ContentType    string            `yaml:"content-type"`    // 请求体类型（仅 webhook，默认：application/json）
+	Body           string            `yaml:"body"`            // 请求体模板（仅 webhook，Go text/template，为空使用默认JSON）
+	Token          string            `yaml:"token"`           // telegram 机器人 token
+	ChatID         string            `yaml:"chat-id"`         // telegram 会话 ID
+	Secret         string            `yaml:"secret"`          // dingtalk/feishu 加签密钥，可为空
+	TimeoutSeconds int64             `yaml:"timeout-seconds"` // 请求超时（单位：秒）
+
+	NotifyPolicyConfig `yaml:",inline"`
+}
+
+func (n *NotifyChannelConfig) setDefault() {
+	n.Type = strings.ToLower(strings.TrimSpace(n.Type))
+
+	if n.Type == NotifyTypeWebhook {
+		if n.Method == "" {
+			n.Method = http.MethodPost
+		}
+
+		if n.ContentType == "" {
+			n.ContentType = "application/json"
+		}
+	}
+
+	if n.Type == NotifyTypeTelegram && n.URL == "" {
+		n.URL = DefaultTelegramAPI
+	}
+
+	if n.TimeoutSeconds <= 0 {
+		n.TimeoutSeconds = 10
+	}
+
+	n.NotifyPolicyConfig.setDefault()
+
+	return
+}
+
+func (n *NotifyChannelConfig) check(report *checkReport) (err ConfigError) {
+	if n.Name == "" {
+		return report.error("notify channel name is empty")
+	}
+
+	switch n.Type {
+	case NotifyTypeWebhook, NotifyTypeDingTalk, NotifyTypeFeishu, NotifyTypeSlack, NotifyTypeWxRobot, NotifyTypeTelegram:
+		// pass
+	case NotifyTypeSMTP:
+		return report.error(fmt.Sprintf("notify channel %s: smtp channel is built-in, please use the smtp config", n.Name))
+	default:
+		return report.error(fmt.Sprintf("notify channel %s: unknown type '%s'", n.Name, n.Type))
+	}
+
+	if n.URL == "" {
+		return report.error(fmt.Sprintf("notify channel %s: url is empty", n.Name))
+	}
+
+	u, urlErr := url.Parse(n.URL)
+	if urlErr != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return report.error(fmt.Sprintf("notify channel %s: bad url", n.Name))
+	}
+
+	if n.Type == NotifyTypeTelegram && (n.Token == "" || n.ChatID == "") {
+		return report.error(fmt.Sprintf("notify channel %s: telegram token and chat-id must be set", n.Name))
+	}
+
+	if n.Type == NotifyTypeWebhook {
+		n.Method = strings.ToUpper(n.Method)
+		if n.Method != http.MethodPost && n.Method != http.MethodPut && n.Method != http.MethodPatch {
+			return report.error(fmt.Sprintf("notify channel %s: method must be POST, PUT or PATCH", n.Name))
+		}
+	}
+
+	err = n.NotifyPolicyConfig.check(report)
+	if err != nil && err.IsError() {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+type NotifyConfig struct {
+	Locale    string                  `yaml:"locale"`    // 内置模板的语言：zh/en
+	Templates []*NotifyTemplateConfig `yaml:"templates"` // 自定义消息模板
+	WxRobot   NotifyPolicyConfig      `yaml:"wxrobot"`   // 内置企业微信渠道（api.webhook）的推送策略
+	SMTP      NotifyPolicyConfig      `yaml:"smtp"`      // 内置邮件渠道（smtp）的推送策略
+	Channels  []*NotifyChannelConfig  `yaml:"channels"`  // 自定义推送渠道
+	Routes    []*NotifyRouteConfig    `yaml:"routes"`    // 路由规则，按顺序匹配，均不命中时发送到全部渠道
+	Queue     NotifyQueueConfig       `yaml:"queue"`     // 持久化发送队列（失败重试）
+}
+
+func (n *NotifyConfig) setDefault() {
+	n.WxRobot.setDefault()
+	n.SMTP.setDefault()
+
+	for _, c := range n.Channels {
+		c.setDefault()
+	}
+
+	for _, r := range n.Routes {
+		r.setDefault()
+	}
+
+	n.Queue.setDefault()
+
+	n.Locale = strings.ToLower(strings.TrimSpace(n.Locale))
+	if n.Locale == "" {
+		n.Locale = NotifyLocaleZh
+	}
+
+	for _, t := range n.Templates {
+		t.setDefault()
+	}
+
+	return
+}
+
+func (n *NotifyConfig) check(report *checkReport) (err ConfigError) {
+	err = report.checkPath("wxrobot", n.WxRobot.check)
+	if err != nil && err.IsError() && !report.keepChecking() {
+		return err
+	}
+
+	err = report.checkPath("smtp", n.SMTP.check)
+	if err != nil && err.IsError() && !report.keepChecking() {
+		return err
+	}
+
+	names := make(map[string]bool, len(n.Channels)+2)
+	names[NotifyTypeWxRobot] = true
+	names[NotifyTypeSMTP] = true
+
+	for i, c := range n.Channels {
+		leave := report.enterPath(indexPath("channels", i))
+		err = c.check(report)
+		leave()
+
+		if err != nil && err.IsError() && !report.keepChecking() {
+			return err
+		}
+
+		if c.Name == NotifyTypeWxRobot || c.Name == NotifyTypeSMTP {
+			return report.error(fmt.Sprintf("notify channel name '%s' is reserved", c.Name))
+		}
+
+		if names[c.Name] {
+			return report.error(fmt.Sprintf("notify channel name '%s' is duplicate", c.Name))
+		}
+
+		names[c.Name] = true
+	}
+
+	for i, r := range n.Routes {
+		leave := report.enterPath(indexPath("routes", i))
+		err = r.check(report, names)
+		leave()
+
+		if err != nil && err.IsError() && !report.keepChecking() {
+			return err
+		}
+	}
+
+	for i, t := range n.Templates {
+		leave := report.enterPath(indexPath("templates", i))
+		err = t.check(report, names)
+		leave()
+
+		if err != nil && err.IsError() && !report.keepChecking() {
+			return err
+		}
+	}
+
+	if n.Locale != NotifyLocaleZh && n.Locale != NotifyLocaleEn {
+		return report.error(fmt.Sprintf("bad locale '%s'", n.Locale))
+	}
+
+	err = report.checkPath("queue", n.Queue.check)
+	if err != nil && err.IsError() && !report.keepChecking() {
+		return err
+	}
+
+	return nil
+}
+
+// ChannelPolicy 返回渠道的推送策略，渠道不存在时返回 nil
+func (n *NotifyConfig) ChannelPolicy(name string) *NotifyPolicyConfig {
+	switch name {
+	case NotifyTypeWxRobot:
+		return &n.WxRobot
+	case NotifyTypeSMTP:
+		return &n.SMTP
+	}
+
+	for _, c := range n.Channels {
+		if c.Name == name {
+			return &c.NotifyPolicyConfig
+		}
+	}
+
+	return nil
+}
+
+// MatchRoute 返回第一个匹配的路由规则，均不匹配时返回 nil
+func (n *NotifyConfig) MatchRoute(event string, severity string) *NotifyRouteConfig {
+	for _, r := range n.Routes {
+		if r.Match(event, severity) {
+			return r
+		}
+	}
+
+	return nil
+}
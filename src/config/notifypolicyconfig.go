@@ -0,0 +1,182 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	NotifySeverityInfo     = "info"
+	NotifySeverityNotice   = "notice"
+	NotifySeverityWarning  = "warning"
+	NotifySeverityCritical = "critical"
+)
+
+var notifySeverityMap = map[string]int{
+	NotifySeverityInfo:     1,
+	NotifySeverityNotice:   2,
+	NotifySeverityWarning:  3,
+	NotifySeverityCritical: 4,
+}
+
+// NotifySeverityLevel 返回等级的数值，数值越大越严重，未知等级返回 0
+func NotifySeverityLevel(severity string) int {
+	return notifySeverityMap[severity]
+}
+
+type NotifyPolicyConfig struct {
+	MinSeverity      string   `yaml:"min-severity"`       // 渠道接收的最低等级
+	QuietHours       []string `yaml:"quiet-hours"`        // 静默时段（按 time-zone 计算），例如 23:00-07:00
+	QuietMinSeverity string   `yaml:"quiet-min-severity"` // 静默时段内仍然发送的最低等级
+
+	RateLimit        int64    `yaml:"rate-limit"`         // 限流：窗口内最多发送的消息数，0 表示不限流
+	RateLimitSeconds int64    `yaml:"rate-limit-seconds"` // 限流窗口（单位：秒）
+	DigestSeconds    int64    `yaml:"digest-seconds"`     // 汇总窗口：窗口内的消息合并为一条摘要发送，0 表示不汇总
+	DigestEvents     []string `yaml:"digest-events"`      // 需要汇总的事件，被限流的消息也会进入汇总
+
+	QuietRanges []*QuietRange `yaml:"-"`
+}
+
+type QuietRange struct {
+	Start time.Duration // 距离零点的时长
+	End   time.Duration
+}
+
+func (n *NotifyPolicyConfig) setDefault() {
+	n.MinSeverity = strings.ToLower(n.MinSeverity)
+	n.QuietMinSeverity = strings.ToLower(n.QuietMinSeverity)
+
+	if n.MinSeverity == "" {
+		n.MinSeverity = NotifySeverityInfo
+	}
+
+	if n.QuietMinSeverity == "" {
+		n.QuietMinSeverity = NotifySeverityCritical
+	}
+
+	if n.RateLimitSeconds <= 0 {
+		n.RateLimitSeconds = 60
+	}
+
+	if n.DigestEvents == nil {
+		n.DigestEvents = []string{NotifyEventSshBanned, NotifyEventSshSuccess}
+	}
+
+	for i, e := range n.DigestEvents {
+		n.DigestEvents[i] = strings.ToLower(strings.TrimSpace(e))
+	}
+
+	return
+}
+
+func (n *NotifyPolicyConfig) check(report *checkReport) (err ConfigError) {
+	if NotifySeverityLevel(n.MinSeverity) == 0 {
+		return report.error(fmt.Sprintf("bad min-severity: %s", n.MinSeverity))
+	}
+
+	if NotifySeverityLevel(n.QuietMinSeverity) == 0 {
+		return report.error(fmt.Sprintf("bad quiet-min-severity: %s", n.QuietMinSeverity))
+	}
+
+	if n.RateLimit < 0 || n.DigestSeconds < 0 {
+		return report.error("rate-limit and digest-seconds must not be negative")
+	}
+
+	for _, e := range n.DigestEvents {
+		if !notifyEventMap[e] {
+			return report.error(fmt.Sprintf("bad digest-events: unknown event '%s'", e))
+		}
+	}
+
+	n.QuietRanges = make([]*QuietRange, 0, len(n.QuietHours))
+	for _, q := range n.QuietHours {
+		r, parseErr := parseQuietRange(q)
+		if parseErr != nil {
+			return report.error(fmt.Sprintf("bad quiet-hours '%s': %s", q, parseErr.Error()))
+		}
+
+		n.QuietRanges = append(n.QuietRanges, r)
+	}
+
+	return nil
+}
+
+// Allow 判断该等级的消息在 t 时刻是否允许发送
+func (n *NotifyPolicyConfig) Allow(severity string, t time.Time) bool {
+	level := NotifySeverityLevel(severity)
+
+	if level < NotifySeverityLevel(n.MinSeverity) {
+		return false
+	}
+
+	if level >= NotifySeverityLevel(n.QuietMinSeverity) {
+		return true
+	}
+
+	return !n.InQuietHours(t)
+}
+
+func (n *NotifyPolicyConfig) IsDigestEvent(event string) bool {
+	if n.DigestSeconds <= 0 {
+		return false
+	}
+
+	for _, e := range n.DigestEvents {
+		if e == event {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (n *NotifyPolicyConfig) InQuietHours(t time.Time) bool {
+	t = t.In(TimeZone())
+	since := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+
+	for _, r := range n.QuietRanges {
+		if r.Contains(since) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *QuietRange) Contains(since time.Duration) bool {
+	if r.Start <= r.End {
+		return since >= r.Start && since < r.End
+	}
+
+	// 跨越零点，例如 23:00-07:00
+	return since >= r.Start || since < r.End
+}
+
+func parseQuietRange(s string) (*QuietRange, error) {
+	start, end, ok := strings.Cut(strings.TrimSpace(s), "-")
+	if !ok {
+		return nil, fmt.Errorf("must be HH:MM-HH:MM")
+	}
+
+	startTime, err := time.Parse("15:04", strings.TrimSpace(start))
+	if err != nil {
+		return nil, err
+	}
+
+	endTime, err := time.Parse("15:04", strings.TrimSpace(end))
+	if err != nil {
+		return nil, err
+	}
+
+	res := &QuietRange{
+		Start: time.Duration(startTime.Hour())*time.Hour + time.Duration(startTime.Minute())*time.Minute,
+		End:   time.Duration(endTime.Hour())*time.Hour + time.Duration(endTime.Minute())*time.Minute,
+	}
+
+	if res.Start == res.End {
+		return nil, fmt.Errorf("start and end must be different")
+	}
+
+	return res, nil
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"github.com/SongZihuan/huan-springboard/src/utils"
+	"time"
+)
+
+type NotifyQueueConfig struct {
+	Enable              utils.StringBool `yaml:"enable"`                // 启用持久化发送队列（存储于 SQLite）
+	MaxAttempts         int64            `yaml:"max-attempts"`          // 最大尝试次数，超过后进入死信
+	RetryBaseSeconds    int64            `yaml:"retry-base-seconds"`    // 首次重试的等待时间，之后按指数增长
+	RetryMaxSeconds     int64            `yaml:"retry-max-seconds"`     // 重试等待时间的上限
+	DrainTimeoutSeconds int64            `yaml:"drain-timeout-seconds"` // 停止服务时清空队列的最长等待时间
+}
+
+func (n *NotifyQueueConfig) setDefault() {
+	n.Enable.SetDefaultEnable()
+
+	if n.MaxAttempts <= 0 {
+		n.MaxAttempts = 8
+	}
+
+	if n.RetryBaseSeconds <= 0 {
+		n.RetryBaseSeconds = 5
+	}
+
+	if n.RetryMaxSeconds <= 0 {
+		n.RetryMaxSeconds = 3600
+	}
+
+	if n.DrainTimeoutSeconds <= 0 {
+		n.DrainTimeoutSeconds = 10
+	}
+
+	return
+}
+
+func (n *NotifyQueueConfig) check(report *checkReport) (err ConfigError) {
+	if n.RetryMaxSeconds < n.RetryBaseSeconds {
+		return report.error("retry-max-seconds must not be less than retry-base-seconds")
+	}
+
+	return nil
+}
+
+// RetryDelay 返回第 attempts 次失败后的等待时间
+func (n *NotifyQueueConfig) RetryDelay(attempts int64) time.Duration {
+	delay := time.Duration(n.RetryBaseSeconds) * time.Second
+	limit := time.Duration(n.RetryMaxSeconds) * time.Second
+
+	for i := int64(1); i < attempts && delay < limit; i++ {
+		delay *= 2
+	}
+
+	return min(delay, limit)
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	NotifyEventStart          = "start"
+	NotifyEventWaitStop       = "wait-stop"
+	NotifyEventStop           = "stop"
+	NotifyEventTcpNotAccept   = "tcp-not-accept"
+	NotifyEventTcpStopAccept  = "tcp-stop-accept"
+	NotifyEventTcpReAccept    = "tcp-re-accept"
+	NotifyEventSshBanned      = "ssh-banned"
+	NotifyEventSshSuccess     = "ssh-success"
+	NotifyEventConfigRollback = "config-rollback"
+
+	NotifyEventDigest = "digest" // 汇总消息，由渠道生成，不参与路由
+)
+
+var notifyEventMap = map[string]bool{
+	NotifyEventStart:          true,
+	NotifyEventWaitStop:       true,
+	NotifyEventStop:           true,
+	NotifyEventTcpNotAccept:   true,
+	NotifyEventTcpStopAccept:  true,
+	NotifyEventTcpReAccept:    true,
+	NotifyEventSshBanned:      true,
+	NotifyEventSshSuccess:     true,
+	NotifyEventConfigRollback: true,
+}
+
+type NotifyRouteConfig struct {
+	Events          []string `yaml:"events"`           // 匹配的事件类型，留空表示全部事件
+	MinSeverity     string   `yaml:"min-severity"`     // 匹配的最低等级，留空表示全部等级
+	Channels        []string `yaml:"channels"`         // 目标渠道名称，留空表示丢弃消息
+	DedupSeconds    int64    `yaml:"dedup-seconds"`    // 去重窗口：窗口内相同内容（例如同一IP）只发送一次，0 表示不去重
+	SuppressSeconds int64    `yaml:"suppress-seconds"` // 抑制窗口：窗口内同类事件只发送一次，0 表示不抑制
+}
+
+func (n *NotifyRouteConfig) setDefault() {
+	n.MinSeverity = strings.ToLower(n.MinSeverity)
+
+	for i, e := range n.Events {
+		n.Events[i] = strings.ToLower(strings.TrimSpace(e))
+	}
+
+	return
+}
+
+func (n *NotifyRouteConfig) check(report *checkReport, channels map[string]bool) (err ConfigError) {
+	for _, e := range n.Events {
+		if !notifyEventMap[e] {
+			return report.error(fmt.Sprintf("unknown event '%s'", e))
+		}
+	}
+
+	if n.MinSeverity != "" && NotifySeverityLevel(n.MinSeverity) == 0 {
+		return report.error(fmt.Sprintf("bad min-severity '%s'", n.MinSeverity))
+	}
+
+	for _, c := range n.Channels {
+		if !channels[c] {
+			return report.error(fmt.Sprintf("unknown channel '%s'", c))
+		}
+	}
+
+	if n.DedupSeconds < 0 || n.SuppressSeconds < 0 {
+		return report.error("dedup-seconds and suppress-seconds must not be negative")
+	}
+
+	return nil
+}
+
+func (n *NotifyRouteConfig) Match(event string, severity string) bool {
+	if n.MinSeverity != "" && NotifySeverityLevel(severity) < NotifySeverityLevel(n.MinSeverity) {
+		return false
+	}
+
+	if len(n.Events) == 0 {
+		return true
+	}
+
+	for _, e := range n.Events {
+		if e == event {
+			return true
+		}
+	}
+
+	return false
+}
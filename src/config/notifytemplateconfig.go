@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	NotifyLocaleZh = "zh"
+	NotifyLocaleEn = "en"
+)
+
+type NotifyTemplateConfig struct {
+	Event    string `yaml:"event"`    // 匹配的事件类型（可为 digest），留空表示全部事件
+	Channel  string `yaml:"channel"`  // 匹配的渠道名称，留空表示全部渠道
+	Title    string `yaml:"title"`    // 标题模板
+	Content  string `yaml:"content"`  // 正文模板（各格式的外框仍使用内置模板）
+	Text     string `yaml:"text"`     // 完整纯文本模板（即时通讯类渠道、邮件纯文本部分）
+	Markdown string `yaml:"markdown"` // 完整 Markdown 模板（企业微信）
+	HTML     string `yaml:"html"`     // 完整 HTML 模板（邮件 HTML 部分，Go html/template）
+}
+
+func (n *NotifyTemplateConfig) setDefault() {
+	n.Event = strings.ToLower(strings.TrimSpace(n.Event))
+	n.Channel = strings.TrimSpace(n.Channel)
+	return
+}
+
+func (n *NotifyTemplateConfig) check(report *checkReport, channels map[string]bool) (err ConfigError) {
+	if n.Event != "" && n.Event != NotifyEventDigest && !notifyEventMap[n.Event] {
+		return report.error(fmt.Sprintf("unknown event '%s'", n.Event))
+	}
+
+	if n.Channel != "" && !channels[n.Channel] {
+		return report.error(fmt.Sprintf("unknown channel '%s'", n.Channel))
+	}
+
+	if n.Title == "" && n.Content == "" && n.Text == "" && n.Markdown == "" && n.HTML == "" {
+		_ = report.warning("no template is set")
+	}
+
+	return nil
+}
+
+// Score 返回模板与渠道、事件的匹配程度，不匹配返回 -1，越具体分数越高
+func (n *NotifyTemplateConfig) Score(channel string, event string) int {
+	score := 0
+
+	if n.Channel != "" {
+		if n.Channel != channel {
+			return -1
+		}
+		score += 2
+	}
+
+	if n.Event != "" {
+		if n.Event != event {
+			return -1
+		}
+		score += 1
+	}
+
+	return score
+}
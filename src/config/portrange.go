@@ -0,0 +1,165 @@
+package config
+
+import (
+	"fmt"
+	"github.com/SongZihuan/huan-springboard/src/resolver"
+	"gopkg.in/yaml.v3"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PortRange 端口或端口范围，配置中写作 22 或者 30000-30100
+type PortRange struct {
+	First int64
+	Last  int64
+}
+
+func ParsePortRange(str string) (PortRange, error) {
+	str = strings.TrimSpace(str)
+
+	first, last, isRange := strings.Cut(str, "-")
+	if !isRange {
+		last = first
+	}
+
+	var res PortRange
+	var err error
+
+	res.First, err = strconv.ParseInt(strings.TrimSpace(first), 10, 64)
+	if err != nil {
+		return PortRange{}, fmt.Errorf("bad port '%s'", str)
+	}
+
+	res.Last, err = strconv.ParseInt(strings.TrimSpace(last), 10, 64)
+	if err != nil {
+		return PortRange{}, fmt.Errorf("bad port '%s'", str)
+	}
+
+	return res, nil
+}
+
+// UnmarshalYAML 格式错误时返回 yaml.TypeError，继续解析其余选项（错误在检查时按 YAML 路径报告）
+func (p *PortRange) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.ScalarNode {
+		return &yaml.TypeError{Errors: []string{fmt.Sprintf("line %d: port must be a number or a range like 30000-30100", value.Line)}}
+	}
+
+	res, err := ParsePortRange(value.Value)
+	if err != nil {
+		return &yaml.TypeError{Errors: []string{fmt.Sprintf("line %d: %s", value.Line, err.Error())}}
+	}
+
+	*p = res
+	return nil
+}
+
+func (p PortRange) MarshalYAML() (interface{}, error) {
+	return p.Value(), nil
+}
+
+// maxPortRangeCount 端口范围最多包含的端口数：每个端口、每个协议族单独监听（一个 socket 和一个 goroutine）
+const maxPortRangeCount = 1024
+
+// check 端口范围为 1-65535，第一个端口不大于最后一个端口，且不超过 maxPortRangeCount 个端口
+func (p PortRange) check() error {
+	if p.First <= 0 || p.Last > 65535 { // 一般不建议使用端口号0
+		return fmt.Errorf("port must be between 1 and 65535")
+	} else if p.First > p.Last {
+		return fmt.Errorf("bad port range %s", p.String())
+	} else if p.Count() > maxPortRangeCount {
+		return fmt.Errorf("port range %s has %d ports, at most %d are allowed", p.String(), p.Count(), maxPortRangeCount)
+	}
+	return nil
+}
+
+func (p PortRange) IsZero() bool {
+	return p.First == 0 && p.Last == 0
+}
+
+func (p PortRange) IsRange() bool {
+	return p.First != p.Last
+}
+
+// Count 端口数
+func (p PortRange) Count() int64 {
+	return p.Last - p.First + 1
+}
+
+func (p PortRange) Contains(port int64) bool {
+	return port >= p.First && port <= p.Last
+}
+
+func (p PortRange) Overlaps(o PortRange) bool {
+	return p.First <= o.Last && o.First <= p.Last
+}
+
+func (p PortRange) String() string {
+	if p.IsRange() {
+		return fmt.Sprintf("%d-%d", p.First, p.Last)
+	}
+	return strconv.FormatInt(p.First, 10)
+}
+
+// Value 单个端口时为数字，端口范围时为字符串，用于输出配置和日志字段
+func (p PortRange) Value() any {
+	if p.IsRange() {
+		return p.String()
+	}
+	return p.First
+}
+
+// checkDestRange 检查目标地址的端口：可以是单个端口，或者与 src 长度相同的端口范围（unix socket 不检查）
+func checkDestRange(address string, src PortRange) error {
+	if address == "" || isUnixAddress(address) {
+		return nil
+	}
+
+	_, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ports, err := ParsePortRange(port)
+	if err != nil {
+		return err
+	} else if err = ports.check(); err != nil {
+		return err
+	} else if ports.IsRange() && ports.Count() != src.Count() {
+		return fmt.Errorf("dest port range %s must have the same length as src %s", ports.String(), src.String())
+	}
+
+	return nil
+}
+
+// resolveDest 解析目标地址，端口为范围时使用第一个端口，isRange 表示端口是否为范围；主机名每隔 refresh 重新解析一次（refresh 为 0 时不重新解析）
+func resolveDest(network string, address string, refresh time.Duration) (target *resolver.Target, isRange bool, err error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, false, err
+	}
+
+	ports, err := ParsePortRange(port)
+	if err != nil {
+		return nil, false, err
+	}
+
+	target, err = resolver.NewTarget(network, host, int(ports.First), refresh)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return target, ports.IsRange(), nil
+}
+
+// destForPort 监听端口对应的目标地址：目标端口为范围时按监听端口相对于第一个端口的偏移量计算
+func destForPort(dest *resolver.Target, isRange bool, src PortRange, port int64) *resolver.Addr {
+	if dest == nil {
+		return nil
+	} else if !isRange {
+		return dest.Addr(dest.Port())
+	}
+
+	return dest.Addr(dest.Port() + int(port-src.First))
+}
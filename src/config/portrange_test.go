@@ -0,0 +1,136 @@
+package config
+
+import (
+	"github.com/SongZihuan/huan-springboard/src/resolver"
+	"gopkg.in/yaml.v3"
+	"testing"
+)
+
+func TestParsePortRange(t *testing.T) {
+	tests := []struct {
+		str      string
+		want     PortRange
+		wantErr  bool
+		checkErr bool
+	}{
+		{"22", PortRange{22, 22}, false, false},
+		{" 22 ", PortRange{22, 22}, false, false},
+		{"30000-30100", PortRange{30000, 30100}, false, false},
+		{"30000 - 30100", PortRange{30000, 30100}, false, false},
+		{"1-65535", PortRange{1, 65535}, false, true},
+		{"30000-31023", PortRange{30000, 31023}, false, false},
+		{"30000-31024", PortRange{30000, 31024}, false, true},
+		{"0", PortRange{0, 0}, false, true},
+		{"65536", PortRange{65536, 65536}, false, true},
+		{"30100-30000", PortRange{30100, 30000}, false, true},
+		{"", PortRange{}, true, false},
+		{"ssh", PortRange{}, true, false},
+		{"30000-", PortRange{}, true, false},
+		{"-22", PortRange{}, true, false},
+		{"1-2-3", PortRange{}, true, false},
+	}
+
+	for _, tt := range tests {
+		got, err := ParsePortRange(tt.str)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParsePortRange(%q) error = %v, wantErr %v", tt.str, err, tt.wantErr)
+			continue
+		} else if got != tt.want {
+			t.Errorf("ParsePortRange(%q) = %v, want %v", tt.str, got, tt.want)
+		} else if err == nil && (got.check() != nil) != tt.checkErr {
+			t.Errorf("ParsePortRange(%q).check() = %v, want error %v", tt.str, got.check(), tt.checkErr)
+		}
+	}
+}
+
+func TestPortRangeYAML(t *testing.T) {
+	var res struct {
+		Single PortRange `yaml:"single"`
+		Range  PortRange `yaml:"range"`
+	}
+
+	err := yaml.Unmarshal([]byte("single: 22\nrange: 30000-30100\n"), &res)
+	if err != nil {
+		t.Fatal(err)
+	} else if res.Single != (PortRange{22, 22}) || res.Range != (PortRange{30000, 30100}) {
+		t.Fatalf("unmarshal = %+v", res)
+	}
+
+	out, err := yaml.Marshal(&res)
+	if err != nil {
+		t.Fatal(err)
+	} else if string(out) != "single: 22\nrange: 30000-30100\n" {
+		t.Errorf("marshal = %q", string(out))
+	}
+
+	if err = yaml.Unmarshal([]byte("single: [22]\n"), &res); err == nil {
+		t.Error("expected error for sequence port")
+	}
+}
+
+func TestCheckDestRange(t *testing.T) {
+	src := PortRange{30000, 30100}
+
+	tests := []struct {
+		address string
+		wantErr bool
+	}{
+		{"", false},
+		{"unix:/run/app.sock", false},
+		{"localhost:8080", false},
+		{"localhost:40000-40100", false},
+		{"[::1]:40000-40100", false},
+		{"localhost:40000-40099", true},
+		{"localhost:0", true},
+		{"localhost:40100-40000", true},
+		{"localhost", true},
+	}
+
+	for _, tt := range tests {
+		err := checkDestRange(tt.address, src)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkDestRange(%q) error = %v, wantErr %v", tt.address, err, tt.wantErr)
+		}
+	}
+}
+
+func TestDestForPort(t *testing.T) {
+	src := PortRange{30000, 30100}
+
+	if destForPort(nil, false, src, 30000) != nil {
+		t.Error("nil target must have no dest")
+	}
+
+	single, isRange, err := resolveDest("tcp4", "127.0.0.1:8080", 0)
+	if err != nil {
+		t.Fatal(err)
+	} else if isRange {
+		t.Fatal("127.0.0.1:8080 is not a range")
+	}
+
+	ranged, isRange, err := resolveDest("tcp4", "127.0.0.1:40000-40100", 0)
+	if err != nil {
+		t.Fatal(err)
+	} else if !isRange {
+		t.Fatal("127.0.0.1:40000-40100 is a range")
+	}
+
+	tests := []struct {
+		target  *resolver.Target
+		isRange bool
+		port    int64
+		want    string
+	}{
+		{single, false, 30000, "127.0.0.1:8080"},
+		{single, false, 30050, "127.0.0.1:8080"},
+		{ranged, true, 30000, "127.0.0.1:40000"},
+		{ranged, true, 30050, "127.0.0.1:40050"},
+		{ranged, true, 30100, "127.0.0.1:40100"},
+	}
+
+	for _, tt := range tests {
+		if got := destForPort(tt.target, tt.isRange, src, tt.port).String(); got != tt.want {
+			t.Errorf("destForPort(%s, %d) = %s, want %s", tt.target.String(), tt.port, got, tt.want)
+		}
+	}
+}
@@ -15,10 +15,10 @@ func (r *RedisConfig) setDefault() {
 	return
 }
 
-func (r *RedisConfig) check() (cfgErr ConfigError) {
+func (r *RedisConfig) check(report *checkReport) (cfgErr ConfigError) {
 	_, _, err := net.SplitHostPort(r.Address)
 	if err != nil {
-		return NewConfigError("redis address is invalid")
+		return report.error("redis address is invalid")
 	}
 
 	return nil
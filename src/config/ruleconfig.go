@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"github.com/SongZihuan/huan-springboard/src/utils"
 	"net"
 )
@@ -28,33 +29,33 @@ func (r *RuleConfig) setDefault() {
 	return
 }
 
-func (r *RuleConfig) check() (err ConfigError) {
+func (r *RuleConfig) check(report *checkReport) (err ConfigError) {
 	if r.IPv4 != "" {
 		if !utils.IsValidIPv4(r.IPv4) {
-			return NewConfigError("bad IPv4")
+			return report.error(fmt.Sprintf("bad ipv4 '%s'", r.IPv4))
 		}
 	}
 
 	if r.IPv6 != "" {
 		if !utils.IsValidIPv6(r.IPv6) {
-			return NewConfigError("bad IPv6")
+			return report.error(fmt.Sprintf("bad ipv6 '%s'", r.IPv6))
 		}
 	}
 
 	if r.IPv4Cidr != "" {
 		if !utils.IsValidIPv4CIDR(r.IPv4Cidr) {
-			return NewConfigError("bad IPv4")
+			return report.error(fmt.Sprintf("bad ipv4cidr '%s'", r.IPv4Cidr))
 		}
 	}
 
 	if r.IPv6Cidr != "" {
 		if !utils.IsValidIPv6CIDR(r.IPv6Cidr) {
-			return NewConfigError("bad IPv6")
+			return report.error(fmt.Sprintf("bad ipv6cidr '%s'", r.IPv6Cidr))
 		}
 	}
 
 	if r.IPv4 == "" && r.IPv6 == "" && r.IPv4Cidr == "" && r.IPv6Cidr == "" {
-		return NewConfigError("bad IP or CIDR")
+		return report.error("bad IP or CIDR")
 	}
 
 	return nil
@@ -0,0 +1,192 @@
+package config
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const redacted = "<redacted>"
+
+// secretKeys 输出配置时隐藏这些选项的值
+var secretKeys = map[string]bool{
+	"password":      true, // smtp、redis
+	"app-code":      true, // api
+	"webhook":       true, // api（企业微信机器人地址包含 key）
+	"token":         true, // admin、telegram
+	"secret":        true, // dingtalk、feishu
+	"access-token":  true, // smtp oauth2
+	"client-secret": true, // smtp oauth2
+	"refresh-token": true, // smtp oauth2
+}
+
+// secretHeader 请求头名称包含这些字符串时隐藏其值（webhook 渠道的 headers）
+var secretHeader = regexp.MustCompile(`(?i)auth|token|key|secret|cookie`)
+
+var envName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// expandNode 替换映射的值和列表项（不包括键）中的引用：
+// ${NAME}、${env:NAME} 环境变量；${NAME:-default} 环境变量未设置或为空时使用默认值；
+// ${file:path} 文件内容（去除末尾的换行，相对路径基于配置文件所在目录）；$${ 表示 ${ 本身
+func expandNode(node *yaml.Node, dir string) error {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, n := range node.Content {
+			err := expandNode(n, dir)
+			if err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			err := expandNode(node.Content[i], dir)
+			if err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		if !strings.Contains(node.Value, "${") {
+			return nil
+		}
+
+		value, err := expandString(node.Value, dir)
+		if err != nil {
+			return fmt.Errorf("line %d: %s", node.Line, err.Error())
+		}
+
+		node.Value = value
+		if node.Style == 0 {
+			node.Tag = "" // 未加引号的值重新推断类型，例如 port: ${PORT}
+		}
+	}
+
+	return nil
+}
+
+func expandString(str string, dir string) (string, error) {
+	var res strings.Builder
+
+	for {
+		start := strings.Index(str, "${")
+		if start == -1 {
+			res.WriteString(str)
+			return res.String(), nil
+		}
+
+		if start > 0 && str[start-1] == '$' { // $${ 转义
+			res.WriteString(str[:start-1])
+			res.WriteString("${")
+			str = str[start+2:]
+			continue
+		}
+
+		end := strings.Index(str[start:], "}")
+		if end == -1 {
+			return "", fmt.Errorf("unclosed '${' in '%s'", str)
+		}
+
+		value, err := resolveRef(str[start+2:start+end], dir)
+		if err != nil {
+			return "", err
+		}
+
+		res.WriteString(str[:start])
+		res.WriteString(value)
+		str = str[start+end+1:]
+	}
+}
+
+func resolveRef(ref string, dir string) (string, error) {
+	if path, ok := strings.CutPrefix(ref, "file:"); ok {
+		if path == "" {
+			return "", fmt.Errorf("empty file path in '${%s}'", ref)
+		}
+
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("read secret file: %s", err.Error())
+		}
+
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+
+	name := strings.TrimPrefix(ref, "env:")
+	name, def, hasDefault := strings.Cut(name, ":-")
+
+	if !envName.MatchString(name) {
+		return "", fmt.Errorf("bad environment variable name in '${%s}'", ref)
+	}
+
+	value, ok := os.LookupEnv(name)
+	if hasDefault && value == "" {
+		return def, nil
+	} else if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+
+	return value, nil
+}
+
+// redactNode 隐藏输出配置中的密钥
+func redactNode(node *yaml.Node) {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, n := range node.Content {
+			redactNode(n)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+
+			if secretKeys[key.Value] {
+				redactScalar(value)
+			} else if key.Value == "url" {
+				redactURL(value)
+			} else if key.Value == "headers" && value.Kind == yaml.MappingNode {
+				for j := 0; j+1 < len(value.Content); j += 2 {
+					if secretHeader.MatchString(value.Content[j].Value) {
+						redactScalar(value.Content[j+1])
+					}
+				}
+			} else {
+				redactNode(value)
+			}
+		}
+	}
+}
+
+// redactURL 推送地址（notify 渠道的 url）的路径和参数中可能包含密钥，例如钉钉的 access_token、飞书的 /hook/<token>、
+// Slack 的 /services/T/B/<secret>，因此只保留协议和主机；只有协议和主机的地址（例如 telegram 的 API 地址）不隐藏
+func redactURL(node *yaml.Node) {
+	if node.Kind != yaml.ScalarNode || node.Value == "" {
+		return
+	}
+
+	u, err := url.Parse(node.Value)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		redactScalar(node)
+		return
+	} else if (u.Path == "" || u.Path == "/") && u.RawQuery == "" && u.Fragment == "" && u.User == nil {
+		return
+	}
+
+	node.Value = u.Scheme + "://" + u.Host + "/" + redacted
+	node.Tag = "!!str"
+	node.Style = yaml.DoubleQuotedStyle
+}
+
+func redactScalar(node *yaml.Node) {
+	if node.Kind == yaml.ScalarNode && node.Value != "" {
+		node.Value = redacted
+		node.Tag = "!!str"
+		node.Style = yaml.DoubleQuotedStyle
+	}
+}
@@ -1,16 +1,114 @@
 package config
 
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+const (
+	SMTPTLSImplicit = "implicit" // 连接建立后立即进行 TLS 握手（通常为 465 端口）
+	SMTPTLSStartTLS = "starttls" // 明文连接后使用 STARTTLS 升级（通常为 587/25 端口）
+	SMTPTLSNone     = "none"     // 不使用 TLS
+)
+
+const (
+	SMTPAuthAuto    = "auto" // 按服务器支持的方式自动选择（配置了 oauth2 时使用 xoauth2）
+	SMTPAuthPlain   = "plain"
+	SMTPAuthLogin   = "login"
+	SMTPAuthCramMD5 = "cram-md5"
+	SMTPAuthXOAuth2 = "xoauth2"
+	SMTPAuthNone    = "none"
+)
+
 type SMTPConfig struct {
 	Address   string   `yaml:"address"`
 	User      string   `yaml:"user"`
 	Password  string   `yaml:"password"`
 	Recipient []string `yaml:"recipient"`
+
+	TLS                string           `yaml:"tls"`                  // implicit/starttls/none，留空时 465 端口为 implicit，其余为 starttls
+	Auth               string           `yaml:"auth"`                 // auto/plain/login/cram-md5/xoauth2/none
+	OAuth2             SMTPOAuth2Config `yaml:"oauth2"`               // XOAUTH2 认证
+	TimeoutSeconds     int64            `yaml:"timeout-seconds"`      // 连接和命令超时（单位：秒）
+	KeepAliveSeconds   int64            `yaml:"keepalive-seconds"`    // 空闲连接保持时长（单位：秒），0 表示不复用连接
+	MaxIdleConnections int64            `yaml:"max-idle-connections"` // 最多保留的空闲连接数
+}
+
+type SMTPOAuth2Config struct {
+	AccessToken  string   `yaml:"access-token"`  // 固定的访问令牌
+	TokenFile    string   `yaml:"token-file"`    // 访问令牌文件（由外部程序刷新，每次认证时重新读取）
+	TokenURL     string   `yaml:"token-url"`     // 令牌端点，设置后使用 refresh-token 自动刷新访问令牌
+	ClientID     string   `yaml:"client-id"`     // 客户端 ID
+	ClientSecret string   `yaml:"client-secret"` // 客户端密钥
+	RefreshToken string   `yaml:"refresh-token"` // 刷新令牌
+	Scopes       []string `yaml:"scopes"`        // 申请的权限范围，可为空
 }
 
 func (s *SMTPConfig) setDefault() {
+	s.TLS = strings.ToLower(strings.TrimSpace(s.TLS))
+	s.Auth = strings.ToLower(strings.TrimSpace(s.Auth))
+
+	if s.TLS == "" {
+		_, port, err := net.SplitHostPort(s.Address)
+		if err == nil && port == "465" {
+			s.TLS = SMTPTLSImplicit
+		} else {
+			s.TLS = SMTPTLSStartTLS
+		}
+	}
+
+	if s.Auth == "" {
+		s.Auth = SMTPAuthAuto
+	}
+
+	if s.TimeoutSeconds <= 0 {
+		s.TimeoutSeconds = 30
+	}
+
+	if s.KeepAliveSeconds < 0 {
+		s.KeepAliveSeconds = 0
+	} else if s.KeepAliveSeconds == 0 {
+		s.KeepAliveSeconds = 60
+	}
+
+	if s.MaxIdleConnections <= 0 {
+		s.MaxIdleConnections = 2
+	}
+
 	return
 }
 
-func (s *SMTPConfig) check() (err ConfigError) {
+func (s *SMTPConfig) check(report *checkReport) (err ConfigError) {
+	switch s.TLS {
+	case SMTPTLSImplicit, SMTPTLSStartTLS:
+		// pass
+	case SMTPTLSNone:
+		if s.Address != "" && s.Auth != SMTPAuthNone {
+			_ = report.warning("tls is none, the credentials will be sent in plain text")
+		}
+	default:
+		return report.error(fmt.Sprintf("bad tls '%s', must be implicit/starttls/none", s.TLS))
+	}
+
+	switch s.Auth {
+	case SMTPAuthAuto, SMTPAuthPlain, SMTPAuthLogin, SMTPAuthCramMD5, SMTPAuthNone:
+		// pass
+	case SMTPAuthXOAuth2:
+		if !s.OAuth2.IsSet() {
+			return report.error("auth is xoauth2 but oauth2 is not set")
+		}
+	default:
+		return report.error(fmt.Sprintf("bad auth '%s'", s.Auth))
+	}
+
+	if s.OAuth2.TokenURL != "" && (s.OAuth2.ClientID == "" || s.OAuth2.RefreshToken == "") {
+		return report.error("oauth2 token-url requires client-id and refresh-token")
+	}
+
 	return nil
 }
+
+func (o *SMTPOAuth2Config) IsSet() bool {
+	return o.AccessToken != "" || o.TokenFile != "" || o.TokenURL != ""
+}
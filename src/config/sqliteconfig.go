@@ -14,13 +14,13 @@ func (s *SQLiteConfig) setDefault() {
 	return
 }
 
-func (s *SQLiteConfig) check() (err ConfigError) {
+func (s *SQLiteConfig) check(report *checkReport) (err ConfigError) {
 	if s.Path == "" {
-		return NewConfigError("sqlite path is empty")
+		return report.error("sqlite path is empty")
 	}
 
-	err = s.Clean.check()
-	if err != nil && err.IsError() {
+	err = report.checkPath("clean", s.Clean.check)
+	if err != nil && err.IsError() && !report.keepChecking() {
 		return err
 	}
 
@@ -10,17 +10,17 @@ func (s *SshCountRuleConfig) setDefault() {
 	return
 }
 
-func (s *SshCountRuleConfig) check() (err ConfigError) {
+func (s *SshCountRuleConfig) check(report *checkReport) (err ConfigError) {
 	if s.TryCount < 0 {
 		s.TryCount = 0
 	}
 
 	if s.Seconds <= 0 {
-		return NewConfigError("seconds must be greater than 0")
+		return report.error("seconds must be greater than 0")
 	}
 
 	if s.BannedSeconds <= 0 {
-		return NewConfigError("banned-seconds must be greater than 0")
+		return report.error("banned-seconds must be greater than 0")
 	}
 	return nil
 }
@@ -3,16 +3,22 @@ package config
 import (
 	"fmt"
 	"github.com/SongZihuan/huan-springboard/src/ipcheck"
+	"github.com/SongZihuan/huan-springboard/src/resolver"
+	"github.com/SongZihuan/huan-springboard/src/sockopt"
 	"github.com/SongZihuan/huan-springboard/src/utils"
 	"net"
+	"reflect"
+	"time"
 )
 
 type SshForwardConfig struct {
-	SrcPort         int64            `yaml:"src"`
+	Src             PortRange        `yaml:"src"`    // 端口或端口范围，例如 30000-30100
+	Listen          []string         `yaml:"listen"` // 监听地址（IP），留空表示全部地址
 	DestAddress     string           `yaml:"dest"`
 	IPv4DestAddress string           `yaml:"ipv4-dest"`
 	IPv6DestAddress string           `yaml:"ipv6-dest"`
-	AllowCross      utils.StringBool `yaml:"allow-cross"` // 允许 ipv4 -> ipv6 或 ipv6 -> ipv4
+	AllowCross      utils.StringBool `yaml:"allow-cross"`    // 允许 ipv4 -> ipv6 或 ipv6 -> ipv4
+	CrossFallback   utils.StringBool `yaml:"cross-fallback"` // 同时有 ipv4、ipv6 目标时，同协议族的目标连接失败后连接另一协议族的目标（Happy Eyeballs）
 
 	IPv4SrcServerProxy utils.StringBool `yaml:"ipv4-src-proxy"`
 	IPv6SrcServerProxy utils.StringBool `yaml:"ipv6-src-proxy"`
@@ -23,30 +29,81 @@ type SshForwardConfig struct {
 	IPv6DestRequestProxy        utils.StringBool `yaml:"ipv6-dest-proxy"`
 	IPv6DestRequestProxyVersion int              `yaml:"ipv6-dest-proxy-version"`
 
+	UnixSrcServerProxy          utils.StringBool `yaml:"unix-src-proxy"`
+	UnixDestRequestProxy        utils.StringBool `yaml:"unix-dest-proxy"`
+	UnixDestRequestProxyVersion int              `yaml:"unix-dest-proxy-version"`
+
+	Transparent utils.StringBool `yaml:"transparent"` // 透明代理（IP_TRANSPARENT，仅 Linux）：以来访 IP 为源地址连接目标，替代 ipv4-dest-proxy 和 ipv6-dest-proxy
+
 	CountRules []*SshCountRuleConfig `yaml:"count-rules"` // 全局连接规则
 
-	ResolveIPv4SrcAddress  *net.TCPAddr `yaml:"-"`
-	ResolveIPv4DestAddress *net.TCPAddr `yaml:"-"`
+	Binds []*ListenBind `yaml:"-"` // 解析后的监听地址，每一项单独监听
+
+	DestRefreshSeconds int64 `yaml:"dest-refresh-seconds"` // 目标主机名重新解析的间隔（秒），-1 表示不重新解析
 
-	ResolveIPv6SrcAddress  *net.TCPAddr `yaml:"-"`
-	ResolveIPv6DestAddress *net.TCPAddr `yaml:"-"`
+	Upstream UpstreamConfig `yaml:"upstream"` // 连接目标的选项
+
+	IPv4DestTarget         *resolver.Target `yaml:"-"` // ipv4 目标地址（主机名的全部 A 记录）
+	IPv6DestTarget         *resolver.Target `yaml:"-"` // ipv6 目标地址（主机名的全部 AAAA 记录）
+	ResolveUnixDestAddress *net.UnixAddr    `yaml:"-"` // dest 为 unix: 开头时转发到 unix socket
+
+	IPv4DestPortRange bool `yaml:"-"` // 目标端口为范围，IPv4DestTarget 的端口为第一个端口
+	IPv6DestPortRange bool `yaml:"-"` // 目标端口为范围，IPv6DestTarget 的端口为第一个端口
 
 	Cross bool `yaml:"-"` // 开启交叉
 }
 
+// IPv4Dest 监听端口 port 对应的 ipv4 目标地址
+func (s *SshForwardConfig) IPv4Dest(port int64) *resolver.Addr {
+	return destForPort(s.IPv4DestTarget, s.IPv4DestPortRange, s.Src, port)
+}
+
+// IPv6Dest 监听端口 port 对应的 ipv6 目标地址
+func (s *SshForwardConfig) IPv6Dest(port int64) *resolver.Addr {
+	return destForPort(s.IPv6DestTarget, s.IPv6DestPortRange, s.Src, port)
+}
+
+// Equal 两个转发的配置是否相同，用于重新加载时保留未修改的转发；目标地址只比较配置，不比较解析结果等运行状态
+func (s *SshForwardConfig) Equal(other *SshForwardConfig) bool {
+	if s == nil || other == nil {
+		return s == other
+	}
+
+	if !s.IPv4DestTarget.Equal(other.IPv4DestTarget) || !s.IPv6DestTarget.Equal(other.IPv6DestTarget) {
+		return false
+	}
+
+	a, b := *s, *other
+	a.IPv4DestTarget, a.IPv6DestTarget = nil, nil
+	b.IPv4DestTarget, b.IPv6DestTarget = nil, nil
+	return reflect.DeepEqual(a, b)
+}
+
 func (s *SshForwardConfig) setDefault() {
-	if s.SrcPort == 0 {
-		s.SrcPort = 22
+	if s.Src.IsZero() {
+		s.Src = PortRange{First: 22, Last: 22}
 	}
 
 	s.AllowCross.SetDefaultEnable()
+	s.CrossFallback.SetDefaultDisable()
+
+	if s.DestRefreshSeconds == 0 {
+		s.DestRefreshSeconds = 60
+	}
+
+	s.Upstream.setDefault()
 
 	s.IPv4SrcServerProxy.SetDefaultEnable()
 	s.IPv6SrcServerProxy.SetDefaultEnable()
 
+	s.Transparent.SetDefaultDisable()
+
 	s.IPv4DestRequestProxy.SetDefaultDisable()
 	s.IPv6DestRequestProxy.SetDefaultDisable()
 
+	s.UnixSrcServerProxy.SetDefaultDisable()
+	s.UnixDestRequestProxy.SetDefaultDisable()
+
 	if s.IPv4DestRequestProxyVersion <= 0 && s.IPv4DestRequestProxyVersion != -1 { // -1 表示使用最新版; 0 表示默认（使用版本1）
 		s.IPv4DestRequestProxyVersion = 1
 	}
@@ -55,6 +112,10 @@ func (s *SshForwardConfig) setDefault() {
 		s.IPv6DestRequestProxyVersion = 1
 	}
 
+	if s.UnixDestRequestProxyVersion <= 0 && s.UnixDestRequestProxyVersion != -1 { // -1 表示使用最新版; 0 表示默认（使用版本1）
+		s.UnixDestRequestProxyVersion = 1
+	}
+
 	for _, r := range s.CountRules {
 		r.setDefault()
 	}
@@ -62,93 +123,143 @@ func (s *SshForwardConfig) setDefault() {
 	return
 }
 
-func (s *SshForwardConfig) check() (cfgErr ConfigError) {
-	if s.SrcPort <= 0 || s.SrcPort > 65535 { // 一般不建议使用端口号0
-		return NewConfigError("src point must be between 1 and 65535")
+func (s *SshForwardConfig) check(report *checkReport) (cfgErr ConfigError) {
+	if s.Src.IsZero() && onlyUnixListen(s.Listen) {
+		// 只监听 unix socket 时可以不设置端口
+	} else if err := s.Src.check(); err != nil {
+		return report.error(fmt.Sprintf("src %s", err.Error()))
+	}
+
+	for _, dest := range []string{s.DestAddress, s.IPv4DestAddress, s.IPv6DestAddress} {
+		if err := checkDestRange(dest, s.Src); err != nil {
+			return report.error(fmt.Sprintf("dest address '%s' not valid: %s", dest, err.Error()))
+		}
+	}
+
+	if s.IPv4DestRequestProxy.IsEnable(false) || s.IPv6DestRequestProxy.IsEnable(false) || s.UnixDestRequestProxy.IsEnable(false) {
+		_ = report.warning("ssh does not recommend using proxy protocol, use transparent if the backend needs the client address")
+	}
+
+	if s.Transparent.IsEnable(false) {
+		if !sockopt.Supported {
+			return report.error("transparent is only supported on linux")
+		} else if s.IPv4DestRequestProxy.IsEnable(false) || s.IPv6DestRequestProxy.IsEnable(false) {
+			return report.error("transparent can not be used with ipv4-dest-proxy or ipv6-dest-proxy")
+		}
+	}
+
+	if s.CrossFallback.IsEnable(false) && (s.IPv4DestRequestProxy.IsEnable(false) || s.IPv6DestRequestProxy.IsEnable(false)) {
+		_ = report.warning("cross-fallback with ipv4-dest-proxy or ipv6-dest-proxy: connections that fall back to the other address family send a proxy header without the client address")
 	}
 
-	if s.IPv4DestRequestProxy.IsEnable(false) || s.IPv6DestRequestProxy.IsEnable(false) {
-		_ = NewConfigWarning("ssh does not recommend using proxy protocol")
+	if s.DestRefreshSeconds < -1 {
+		return report.error("dest-refresh-seconds must be greater than 0 or equal to -1")
 	}
 
+	cfgErr = report.checkPath("upstream", s.Upstream.check)
+	if cfgErr != nil && cfgErr.IsError() && !report.keepChecking() {
+		return cfgErr
+	}
+
+	refresh := time.Duration(s.DestRefreshSeconds) * time.Second
+	if s.DestRefreshSeconds == -1 {
+		refresh = 0
+	}
+
+	unixDest, err := resolveUnixDest(s.DestAddress)
+	if err != nil {
+		return report.error(fmt.Sprintf("unix dest address not valid: %s", err.Error()))
+	}
+	s.ResolveUnixDestAddress = unixDest
+
 	if ipcheck.SupportIPv4() {
 		if s.IPv4DestAddress != "" {
-			ip4, err := net.ResolveTCPAddr("tcp4", s.IPv4DestAddress)
+			target4, isRange, err := resolveDest("tcp4", s.IPv4DestAddress, refresh)
 			if err != nil {
-				return NewConfigError(fmt.Sprintf("ipv4 dest address not valid: %s", err.Error()))
+				return report.error(fmt.Sprintf("ipv4 dest address not valid: %s", err.Error()))
 			}
 
-			s.ResolveIPv4DestAddress = ip4
+			s.IPv4DestTarget = target4
+			s.IPv4DestPortRange = isRange
 		} else if s.DestAddress != "" {
-			ip4, err := net.ResolveTCPAddr("tcp4", s.DestAddress)
+			target4, isRange, err := resolveDest("tcp4", s.DestAddress, refresh)
 			if err == nil {
-				s.ResolveIPv4DestAddress = ip4
+				s.IPv4DestTarget = target4
+				s.IPv4DestPortRange = isRange
 			}
 		} else if s.AllowCross.IsEnable() && s.IPv6DestAddress != "" {
 			// 如果 IPv6DestAddress 可以解析为 ipv4 那么就可以直接转发
-			ip4, err := net.ResolveTCPAddr("tcp4", s.IPv6DestAddress)
+			target4, isRange, err := resolveDest("tcp4", s.IPv6DestAddress, refresh)
 			if err == nil {
-				s.ResolveIPv4DestAddress = ip4
+				s.IPv4DestTarget = target4
+				s.IPv4DestPortRange = isRange
 			}
 		}
 	}
 
 	if ipcheck.SupportIPv6() {
 		if s.IPv6DestAddress != "" {
-			ip6, err := net.ResolveTCPAddr("tcp6", s.IPv6DestAddress)
+			target6, isRange, err := resolveDest("tcp6", s.IPv6DestAddress, refresh)
 			if err != nil {
-				return NewConfigError(fmt.Sprintf("ipv6 dest address not valid: %s", err.Error()))
+				return report.error(fmt.Sprintf("ipv6 dest address not valid: %s", err.Error()))
 			}
 
-			s.ResolveIPv6DestAddress = ip6
+			s.IPv6DestTarget = target6
+			s.IPv6DestPortRange = isRange
 		} else if s.DestAddress != "" {
-			ip6, err := net.ResolveTCPAddr("tcp6", s.DestAddress)
+			target6, isRange, err := resolveDest("tcp6", s.DestAddress, refresh)
 			if err == nil {
-				s.ResolveIPv6DestAddress = ip6
+				s.IPv6DestTarget = target6
+				s.IPv6DestPortRange = isRange
 			}
 		} else if s.AllowCross.IsEnable() && s.IPv4DestAddress != "" {
 			// 如果 IPv4DestAddress 可以解析为 ipv6 那么就可以直接转发
-			ip6, err := net.ResolveTCPAddr("tcp6", s.IPv4DestAddress)
+			target6, isRange, err := resolveDest("tcp6", s.IPv4DestAddress, refresh)
 			if err == nil {
-				s.ResolveIPv6DestAddress = ip6
+				s.IPv6DestTarget = target6
+				s.IPv6DestPortRange = isRange
 			}
 		}
 	}
 
-	{
-		ip4, err := net.ResolveTCPAddr("tcp4", fmt.Sprintf(":%d", s.SrcPort))
-		if err != nil {
-			return NewConfigError(fmt.Sprintf("ipv4 src address not valid: %s", err.Error()))
-		}
-
-		s.ResolveIPv4SrcAddress = ip4
+	binds, cfgErr := resolveListen(report, s.Listen, s.Src)
+	if cfgErr != nil {
+		return cfgErr
+	}
+	s.Binds = binds
 
-		ip6, err := net.ResolveTCPAddr("tcp6", fmt.Sprintf(":%d", s.SrcPort))
-		if err != nil {
-			return NewConfigError(fmt.Sprintf("ipv6 src address not valid: %s", err.Error()))
+	for _, b := range binds {
+		if b.Unix != nil && (s.IPv4DestPortRange || s.IPv6DestPortRange) {
+			return report.error(fmt.Sprintf("listen address '%s' can not be used with a dest port range", b.Key()))
 		}
-
-		s.ResolveIPv6SrcAddress = ip6
 	}
 
-	if s.ResolveIPv4DestAddress == nil && s.ResolveIPv6DestAddress == nil {
-		return NewConfigError("dest address not valid")
+	if s.IPv4DestTarget == nil && s.IPv6DestTarget == nil && s.ResolveUnixDestAddress == nil {
+		return report.error("dest address not valid")
 	}
 
-	s.Cross = s.AllowCross.IsEnable(true) && ipcheck.SupportIPv4() && ipcheck.SupportIPv6() && (s.ResolveIPv4DestAddress == nil || s.ResolveIPv6DestAddress == nil)
+	s.Cross = s.AllowCross.IsEnable(true) && ipcheck.SupportIPv4() && ipcheck.SupportIPv6() && (s.IPv4DestTarget == nil || s.IPv6DestTarget == nil)
+
+	// 透明代理只能以来访 IP 连接同协议族的目标
+	if s.Transparent.IsEnable(false) && ((s.Cross && s.ResolveUnixDestAddress == nil) || s.CrossFallback.IsEnable(false)) {
+		return report.error("transparent can not be used with cross forwarding (allow-cross without an ipv4 or ipv6 dest) or cross-fallback")
+	}
 
 	tr := int64(-1)
 	ms := int64(-1)
-	for _, r := range s.CountRules {
-		err := r.check()
-		if err != nil && err.IsError() {
+	for i, r := range s.CountRules {
+		leave := report.enterPath(indexPath("count-rules", i))
+		err := r.check(report)
+		leave()
+
+		if err != nil && err.IsError() && !report.keepChecking() {
 			return err
 		}
 
 		if (tr != -1 && ms != -1) && r.TryCount > tr {
-			return NewConfigError("The count-rules are not sorted correctly, the try-count with the largest number is placed first")
+			return report.error("The count-rules are not sorted correctly, the try-count with the largest number is placed first")
 		} else if (tr != -1 && ms != -1) && r.Seconds > ms {
-			return NewConfigError("The count-rules are not sorted correctly, the seconds with the largest number is placed first")
+			return report.error("The count-rules are not sorted correctly, the seconds with the largest number is placed first")
 		} else {
 			tr = r.TryCount
 			ms = r.Seconds
@@ -10,8 +10,8 @@ func (s *SshRuleConfig) setDefault() {
 	return
 }
 
-func (s *SshRuleConfig) check() (err ConfigError) {
-	err = s.RuleConfig.check()
+func (s *SshRuleConfig) check(report *checkReport) (err ConfigError) {
+	err = s.RuleConfig.check(report)
 	if err != nil && err.IsError() {
 		return err
 	}
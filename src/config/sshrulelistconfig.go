@@ -27,30 +27,36 @@ func (s *SshRuleListConfig) setDefault() {
 	return
 }
 
-func (s *SshRuleListConfig) check() (err ConfigError) {
+func (s *SshRuleListConfig) check(report *checkReport) (err ConfigError) {
 	if !s.DefaultBanned.IsEnable(false) {
-		_ = NewConfigWarning("ssh recommends setting the default policy to banned")
+		_ = report.warning("ssh recommends setting the default policy to banned")
 	}
 
-	for _, r := range s.RuleList {
-		err := r.check()
-		if err != nil && err.IsError() {
+	for i, r := range s.RuleList {
+		leave := report.enterPath(indexPath("rules", i))
+		err := r.check(report)
+		leave()
+
+		if err != nil && err.IsError() && !report.keepChecking() {
 			return err
 		}
 	}
 
 	tr := int64(-1)
 	ms := int64(-1)
-	for _, r := range s.CountRules {
-		err := r.check()
-		if err != nil && err.IsError() {
+	for i, r := range s.CountRules {
+		leave := report.enterPath(indexPath("count-rules", i))
+		err := r.check(report)
+		leave()
+
+		if err != nil && err.IsError() && !report.keepChecking() {
 			return err
 		}
 
 		if (tr != -1 && ms != -1) && r.TryCount > tr {
-			return NewConfigError("The count-rules are not sorted correctly, the try-count with the largest number is placed first")
+			return report.error("The count-rules are not sorted correctly, the try-count with the largest number is placed first")
 		} else if (tr != -1 && ms != -1) && r.Seconds > ms {
-			return NewConfigError("The count-rules are not sorted correctly, the seconds with the largest number is placed first")
+			return report.error("The count-rules are not sorted correctly, the seconds with the largest number is placed first")
 		} else {
 			tr = r.TryCount
 			ms = r.Seconds
@@ -15,15 +15,18 @@ func (t *TcpConfig) setDefault() {
 	return
 }
 
-func (t *TcpConfig) check() (err ConfigError) {
-	err = t.RuleList.check()
-	if err != nil && err.IsError() {
+func (t *TcpConfig) check(report *checkReport) (err ConfigError) {
+	err = t.RuleList.check(report)
+	if err != nil && err.IsError() && !report.keepChecking() {
 		return err
 	}
 
-	for _, f := range t.Forward {
-		err = f.check()
-		if err != nil && err.IsError() {
+	for i, f := range t.Forward {
+		leave := report.enterPath(indexPath("forward", i))
+		err = f.check(report)
+		leave()
+
+		if err != nil && err.IsError() && !report.keepChecking() {
 			return err
 		}
 	}
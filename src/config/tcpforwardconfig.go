@@ -3,16 +3,22 @@ package config
 import (
 	"fmt"
 	"github.com/SongZihuan/huan-springboard/src/ipcheck"
+	"github.com/SongZihuan/huan-springboard/src/resolver"
+	"github.com/SongZihuan/huan-springboard/src/sockopt"
 	"github.com/SongZihuan/huan-springboard/src/utils"
 	"net"
+	"reflect"
+	"time"
 )
 
 type TcpForwardConfig struct {
-	SrcPort         int64            `yaml:"src"`
+	Src             PortRange        `yaml:"src"`    // 端口或端口范围，例如 30000-30100
+	Listen          []string         `yaml:"listen"` // 监听地址（IP），留空表示全部地址
 	DestAddress     string           `yaml:"dest"`
 	IPv4DestAddress string           `yaml:"ipv4-dest"`
 	IPv6DestAddress string           `yaml:"ipv6-dest"`
-	AllowCross      utils.StringBool `yaml:"allow-cross"` // 允许 ipv4 -> ipv6 或 ipv6 -> ipv4
+	AllowCross      utils.StringBool `yaml:"allow-cross"`    // 允许 ipv4 -> ipv6 或 ipv6 -> ipv4
+	CrossFallback   utils.StringBool `yaml:"cross-fallback"` // 同时有 ipv4、ipv6 目标时，同协议族的目标连接失败后连接另一协议族的目标（Happy Eyeballs）
 
 	IPv4SrcServerProxy utils.StringBool `yaml:"ipv4-src-proxy"`
 	IPv6SrcServerProxy utils.StringBool `yaml:"ipv6-src-proxy"`
@@ -23,23 +29,79 @@ type TcpForwardConfig struct {
 	IPv6DestRequestProxy        utils.StringBool `yaml:"ipv6-dest-proxy"`
 	IPv6DestRequestProxyVersion int              `yaml:"ipv6-dest-proxy-version"`
 
-	ResolveIPv4SrcAddress  *net.TCPAddr `yaml:"-"`
-	ResolveIPv4DestAddress *net.TCPAddr `yaml:"-"`
+	UnixSrcServerProxy          utils.StringBool `yaml:"unix-src-proxy"`
+	UnixDestRequestProxy        utils.StringBool `yaml:"unix-dest-proxy"`
+	UnixDestRequestProxyVersion int              `yaml:"unix-dest-proxy-version"`
 
-	ResolveIPv6SrcAddress  *net.TCPAddr `yaml:"-"`
-	ResolveIPv6DestAddress *net.TCPAddr `yaml:"-"`
+	Transparent utils.StringBool `yaml:"transparent"` // 透明代理（IP_TRANSPARENT，仅 Linux）：以来访 IP 为源地址连接目标，替代 ipv4-dest-proxy 和 ipv6-dest-proxy
+
+	Binds []*ListenBind `yaml:"-"` // 解析后的监听地址，每一项单独监听
+
+	DestRefreshSeconds int64 `yaml:"dest-refresh-seconds"` // 目标主机名重新解析的间隔（秒），-1 表示不重新解析
+
+	Upstream UpstreamConfig `yaml:"upstream"` // 连接目标的选项
+
+	IPv4DestTarget         *resolver.Target `yaml:"-"` // ipv4 目标地址（主机名的全部 A 记录）
+	IPv6DestTarget         *resolver.Target `yaml:"-"` // ipv6 目标地址（主机名的全部 AAAA 记录）
+	ResolveUnixDestAddress *net.UnixAddr    `yaml:"-"` // dest 为 unix: 开头时转发到 unix socket
+
+	IPv4DestPortRange bool `yaml:"-"` // 目标端口为范围，IPv4DestTarget 的端口为第一个端口
+	IPv6DestPortRange bool `yaml:"-"` // 目标端口为范围，IPv6DestTarget 的端口为第一个端口
 
 	Cross bool `yaml:"-"` // 开启交叉
 }
 
+// IPv4Dest 监听端口 port 对应的 ipv4 目标地址
+func (t *TcpForwardConfig) IPv4Dest(port int64) *resolver.Addr {
+	return destForPort(t.IPv4DestTarget, t.IPv4DestPortRange, t.Src, port)
+}
+
+// IPv6Dest 监听端口 port 对应的 ipv6 目标地址
+func (t *TcpForwardConfig) IPv6Dest(port int64) *resolver.Addr {
+	return destForPort(t.IPv6DestTarget, t.IPv6DestPortRange, t.Src, port)
+}
+
+// Equal 两个转发的配置是否相同，用于重新加载时保留未修改的转发；目标地址只比较配置，不比较解析结果等运行状态
+func (t *TcpForwardConfig) Equal(other *TcpForwardConfig) bool {
+	if t == nil || other == nil {
+		return t == other
+	}
+
+	if !t.IPv4DestTarget.Equal(other.IPv4DestTarget) || !t.IPv6DestTarget.Equal(other.IPv6DestTarget) {
+		return false
+	}
+
+	a, b := *t, *other
+	a.IPv4DestTarget, a.IPv6DestTarget = nil, nil
+	b.IPv4DestTarget, b.IPv6DestTarget = nil, nil
+	return reflect.DeepEqual(a, b)
+}
+
 func (t *TcpForwardConfig) setDefault() {
 	t.AllowCross.SetDefaultEnable()
+	t.CrossFallback.SetDefaultDisable()
+
+	if t.DestRefreshSeconds == 0 {
+		t.DestRefreshSeconds = 60
+	}
+
+	t.Upstream.setDefault()
 
 	t.IPv4SrcServerProxy.SetDefaultEnable()
 	t.IPv6SrcServerProxy.SetDefaultEnable()
 
-	t.IPv4DestRequestProxy.SetDefaultEnable()
-	t.IPv6DestRequestProxy.SetDefaultEnable()
+	t.Transparent.SetDefaultDisable()
+
+	if t.Transparent.IsEnable(false) { // 透明代理时不使用 Proxy 协议
+		t.IPv4DestRequestProxy.SetDefaultDisable()
+		t.IPv6DestRequestProxy.SetDefaultDisable()
+	} else {
+		t.IPv4DestRequestProxy.SetDefaultEnable()
+		t.IPv6DestRequestProxy.SetDefaultEnable()
+	}
+
+	t.UnixSrcServerProxy.SetDefaultDisable()
+	t.UnixDestRequestProxy.SetDefaultEnable()
 
 	if t.IPv4DestRequestProxyVersion <= 0 && t.IPv4DestRequestProxyVersion != -1 { // -1 表示使用最新版; 0 表示默认（使用版本1）
 		t.IPv4DestRequestProxyVersion = 1
@@ -49,79 +111,130 @@ func (t *TcpForwardConfig) setDefault() {
 		t.IPv6DestRequestProxyVersion = 1
 	}
 
+	if t.UnixDestRequestProxyVersion <= 0 && t.UnixDestRequestProxyVersion != -1 { // -1 表示使用最新版; 0 表示默认（使用版本1）
+		t.UnixDestRequestProxyVersion = 1
+	}
+
 	return
 }
 
-func (t *TcpForwardConfig) check() (cfgErr ConfigError) {
-	if t.SrcPort <= 0 || t.SrcPort > 65535 { // 一般不建议使用端口号0
-		return NewConfigError("src point must be between 1 and 65535")
+func (t *TcpForwardConfig) check(report *checkReport) (cfgErr ConfigError) {
+	if t.Src.IsZero() && onlyUnixListen(t.Listen) {
+		// 只监听 unix socket 时可以不设置端口
+	} else if err := t.Src.check(); err != nil {
+		return report.error(fmt.Sprintf("src %s", err.Error()))
 	}
 
+	for _, dest := range []string{t.DestAddress, t.IPv4DestAddress, t.IPv6DestAddress} {
+		if err := checkDestRange(dest, t.Src); err != nil {
+			return report.error(fmt.Sprintf("dest address '%s' not valid: %s", dest, err.Error()))
+		}
+	}
+
+	if t.Transparent.IsEnable(false) {
+		if !sockopt.Supported {
+			return report.error("transparent is only supported on linux")
+		} else if t.IPv4DestRequestProxy.IsEnable(false) || t.IPv6DestRequestProxy.IsEnable(false) {
+			return report.error("transparent can not be used with ipv4-dest-proxy or ipv6-dest-proxy")
+		}
+	}
+
+	if t.CrossFallback.IsEnable(false) && (t.IPv4DestRequestProxy.IsEnable(false) || t.IPv6DestRequestProxy.IsEnable(false)) {
+		_ = report.warning("cross-fallback with ipv4-dest-proxy or ipv6-dest-proxy: connections that fall back to the other address family send a proxy header without the client address")
+	}
+
+	if t.DestRefreshSeconds < -1 {
+		return report.error("dest-refresh-seconds must be greater than 0 or equal to -1")
+	}
+
+	cfgErr = report.checkPath("upstream", t.Upstream.check)
+	if cfgErr != nil && cfgErr.IsError() && !report.keepChecking() {
+		return cfgErr
+	}
+
+	refresh := time.Duration(t.DestRefreshSeconds) * time.Second
+	if t.DestRefreshSeconds == -1 {
+		refresh = 0
+	}
+
+	unixDest, err := resolveUnixDest(t.DestAddress)
+	if err != nil {
+		return report.error(fmt.Sprintf("unix dest address not valid: %s", err.Error()))
+	}
+	t.ResolveUnixDestAddress = unixDest
+
 	if ipcheck.SupportIPv4() {
 		if t.IPv4DestAddress != "" {
-			ip4, err := net.ResolveTCPAddr("tcp4", t.IPv4DestAddress)
+			target4, isRange, err := resolveDest("tcp4", t.IPv4DestAddress, refresh)
 			if err != nil {
-				return NewConfigError(fmt.Sprintf("ipv4 dest address not valid: %s", err.Error()))
+				return report.error(fmt.Sprintf("ipv4 dest address not valid: %s", err.Error()))
 			}
 
-			t.ResolveIPv4DestAddress = ip4
+			t.IPv4DestTarget = target4
+			t.IPv4DestPortRange = isRange
 		} else if t.DestAddress != "" {
-			ip4, err := net.ResolveTCPAddr("tcp4", t.DestAddress)
+			target4, isRange, err := resolveDest("tcp4", t.DestAddress, refresh)
 			if err == nil {
-				t.ResolveIPv4DestAddress = ip4
+				t.IPv4DestTarget = target4
+				t.IPv4DestPortRange = isRange
 			}
 		} else if t.AllowCross.IsEnable() && t.IPv6DestAddress != "" {
 			// 如果 IPv6DestAddress 可以解析为 ipv4 那么就可以直接转发
-			ip4, err := net.ResolveTCPAddr("tcp4", t.IPv6DestAddress)
+			target4, isRange, err := resolveDest("tcp4", t.IPv6DestAddress, refresh)
 			if err == nil {
-				t.ResolveIPv4DestAddress = ip4
+				t.IPv4DestTarget = target4
+				t.IPv4DestPortRange = isRange
 			}
 		}
 	}
 
 	if ipcheck.SupportIPv6() {
 		if t.IPv6DestAddress != "" {
-			ip6, err := net.ResolveTCPAddr("tcp6", t.IPv6DestAddress)
+			target6, isRange, err := resolveDest("tcp6", t.IPv6DestAddress, refresh)
 			if err != nil {
-				return NewConfigError(fmt.Sprintf("ipv6 dest address not valid: %s", err.Error()))
+				return report.error(fmt.Sprintf("ipv6 dest address not valid: %s", err.Error()))
 			}
 
-			t.ResolveIPv6DestAddress = ip6
+			t.IPv6DestTarget = target6
+			t.IPv6DestPortRange = isRange
 		} else if t.DestAddress != "" {
-			ip6, err := net.ResolveTCPAddr("tcp6", t.DestAddress)
+			target6, isRange, err := resolveDest("tcp6", t.DestAddress, refresh)
 			if err == nil {
-				t.ResolveIPv6DestAddress = ip6
+				t.IPv6DestTarget = target6
+				t.IPv6DestPortRange = isRange
 			}
 		} else if t.AllowCross.IsEnable() && t.IPv4DestAddress != "" {
 			// 如果 IPv4DestAddress 可以解析为 ipv6 那么就可以直接转发
-			ip6, err := net.ResolveTCPAddr("tcp6", t.IPv4DestAddress)
+			target6, isRange, err := resolveDest("tcp6", t.IPv4DestAddress, refresh)
 			if err == nil {
-				t.ResolveIPv6DestAddress = ip6
+				t.IPv6DestTarget = target6
+				t.IPv6DestPortRange = isRange
 			}
 		}
 	}
 
-	{
-		ip4, err := net.ResolveTCPAddr("tcp4", fmt.Sprintf(":%d", t.SrcPort))
-		if err != nil {
-			return NewConfigError(fmt.Sprintf("ipv4 src address not valid: %s", err.Error()))
-		}
-
-		t.ResolveIPv4SrcAddress = ip4
+	binds, cfgErr := resolveListen(report, t.Listen, t.Src)
+	if cfgErr != nil {
+		return cfgErr
+	}
+	t.Binds = binds
 
-		ip6, err := net.ResolveTCPAddr("tcp6", fmt.Sprintf(":%d", t.SrcPort))
-		if err != nil {
-			return NewConfigError(fmt.Sprintf("ipv6 src address not valid: %s", err.Error()))
+	for _, b := range binds {
+		if b.Unix != nil && (t.IPv4DestPortRange || t.IPv6DestPortRange) {
+			return report.error(fmt.Sprintf("listen address '%s' can not be used with a dest port range", b.Key()))
 		}
-
-		t.ResolveIPv6SrcAddress = ip6
 	}
 
-	if t.ResolveIPv4DestAddress == nil && t.ResolveIPv6DestAddress == nil {
-		return NewConfigError("dest address not valid")
+	if t.IPv4DestTarget == nil && t.IPv6DestTarget == nil && t.ResolveUnixDestAddress == nil {
+		return report.error("dest address not valid")
 	}
 
-	t.Cross = t.AllowCross.IsEnable(true) && ipcheck.SupportIPv4() && ipcheck.SupportIPv6() && (t.ResolveIPv4DestAddress == nil || t.ResolveIPv6DestAddress == nil)
+	t.Cross = t.AllowCross.IsEnable(true) && ipcheck.SupportIPv4() && ipcheck.SupportIPv6() && (t.IPv4DestTarget == nil || t.IPv6DestTarget == nil)
+
+	// 透明代理只能以来访 IP 连接同协议族的目标
+	if t.Transparent.IsEnable(false) && ((t.Cross && t.ResolveUnixDestAddress == nil) || t.CrossFallback.IsEnable(false)) {
+		return report.error("transparent can not be used with cross forwarding (allow-cross without an ipv4 or ipv6 dest) or cross-fallback")
+	}
 
 	return nil
 }
@@ -9,8 +9,8 @@ func (t *TcpRuleConfig) setDefault() {
 	return
 }
 
-func (t *TcpRuleConfig) check() (err ConfigError) {
-	err = t.RuleConfig.check()
+func (t *TcpRuleConfig) check(report *checkReport) (err ConfigError) {
+	err = t.RuleConfig.check(report)
 	if err != nil && err.IsError() {
 		return err
 	}
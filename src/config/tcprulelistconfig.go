@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"github.com/SongZihuan/huan-springboard/src/network"
 	"github.com/SongZihuan/huan-springboard/src/utils"
 )
@@ -61,17 +62,20 @@ func (t *TcpRuleListConfig) setDefault() {
 	return
 }
 
-func (t *TcpRuleListConfig) check() (err ConfigError) {
-	for _, r := range t.RuleList {
-		err := r.check()
-		if err != nil && err.IsError() {
+func (t *TcpRuleListConfig) check(report *checkReport) (err ConfigError) {
+	for i, r := range t.RuleList {
+		leave := report.enterPath(indexPath("rules", i))
+		err := r.check(report)
+		leave()
+
+		if err != nil && err.IsError() && !report.keepChecking() {
 			return err
 		}
 	}
 
 	if t.InterfaceName != "" {
 		if _, ok := network.Iface[t.InterfaceName]; !ok {
-			return NewConfigError("bad interface name")
+			return report.error(fmt.Sprintf("bad interface name '%s'", t.InterfaceName))
 		}
 
 		t.SentLimit = utils.ReadBytes(t.TransmitBytesOfCycle)
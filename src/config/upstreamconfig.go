@@ -0,0 +1,126 @@
+package config
+
+import (
+	"fmt"
+	"github.com/SongZihuan/huan-springboard/src/sockopt"
+	"github.com/SongZihuan/huan-springboard/src/utils"
+	"net"
+	"time"
+)
+
+// UpstreamConfig 转发时连接目标使用的选项
+type UpstreamConfig struct {
+	IPv4Bind         string           `yaml:"ipv4-bind"`         // 连接 ipv4 目标时使用的本地地址，留空表示由系统选择
+	IPv6Bind         string           `yaml:"ipv6-bind"`         // 连接 ipv6 目标时使用的本地地址，留空表示由系统选择
+	Device           string           `yaml:"device"`            // 绑定网卡（SO_BINDTODEVICE，仅 Linux）
+	Mark             int64            `yaml:"mark"`              // SO_MARK（仅 Linux），用于策略路由，0 表示不设置
+	KeepAlive        utils.StringBool `yaml:"keepalive"`         // TCP keepalive
+	KeepAliveSeconds int64            `yaml:"keepalive-seconds"` // 空闲多久后开始发送 keepalive 探测（以及探测的间隔），0 表示默认（15秒）
+	KeepAliveCount   int64            `yaml:"keepalive-count"`   // 探测失败多少次后断开（TCP_KEEPCNT，仅 Linux），0 表示使用系统默认值
+	NoDelay          utils.StringBool `yaml:"nodelay"`           // TCP_NODELAY
+
+	ConnectTimeoutSeconds int64 `yaml:"connect-timeout-seconds"` // 连接目标的超时（秒），包括按 Happy Eyeballs 的方式连接时的全部尝试，0 表示默认（10秒）
+
+	ResolveIPv4Bind *net.TCPAddr `yaml:"-"`
+	ResolveIPv6Bind *net.TCPAddr `yaml:"-"`
+}
+
+func (u *UpstreamConfig) setDefault() {
+	u.KeepAlive.SetDefaultEnable()
+	u.NoDelay.SetDefaultEnable()
+
+	if u.ConnectTimeoutSeconds == 0 {
+		u.ConnectTimeoutSeconds = 10
+	}
+
+	return
+}
+
+func (u *UpstreamConfig) check(report *checkReport) (cfgErr ConfigError) {
+	if u.IPv4Bind != "" {
+		ip := net.ParseIP(u.IPv4Bind)
+		if ip == nil || ip.To4() == nil {
+			return report.error(fmt.Sprintf("ipv4-bind '%s' is not an ipv4 address", u.IPv4Bind))
+		}
+		u.ResolveIPv4Bind = &net.TCPAddr{IP: ip.To4()}
+	}
+
+	if u.IPv6Bind != "" {
+		ip := net.ParseIP(u.IPv6Bind)
+		if ip == nil || ip.To4() != nil {
+			return report.error(fmt.Sprintf("ipv6-bind '%s' is not an ipv6 address", u.IPv6Bind))
+		}
+		u.ResolveIPv6Bind = &net.TCPAddr{IP: ip}
+	}
+
+	if u.Mark < 0 || u.Mark > 0xFFFFFFFF {
+		return report.error("mark must be between 0 and 4294967295")
+	} else if u.KeepAliveSeconds < 0 {
+		return report.error("keepalive-seconds must be greater than or equal to 0")
+	} else if u.KeepAliveCount < 0 {
+		return report.error("keepalive-count must be greater than or equal to 0")
+	} else if u.ConnectTimeoutSeconds < 0 {
+		return report.error("connect-timeout-seconds must be greater than 0")
+	}
+
+	if !sockopt.Supported && (u.Device != "" || u.Mark != 0 || u.KeepAliveCount != 0) {
+		return report.error("device, mark and keepalive-count are only supported on linux")
+	}
+
+	if u.Device != "" {
+		if _, err := net.InterfaceByName(u.Device); err != nil {
+			// 网卡可能稍后才创建（例如 VRF），只给出警告
+			_ = report.warning(fmt.Sprintf("device '%s' not found: %s", u.Device, err.Error()))
+		}
+	}
+
+	return nil
+}
+
+// ConnectTimeout 连接目标的超时
+func (u *UpstreamConfig) ConnectTimeout() time.Duration {
+	return time.Duration(u.ConnectTimeoutSeconds) * time.Second
+}
+
+// Dialer 连接 network（tcp4、tcp6 或 unix）目标时使用的 net.Dialer
+func (u *UpstreamConfig) Dialer(network string) *net.Dialer {
+	return u.dialer(network, nil)
+}
+
+// TransparentDialer 透明代理时使用的 net.Dialer：以来访 IP（端口由系统选择）为源地址连接目标，来访地址需要与目标为同一协议族
+func (u *UpstreamConfig) TransparentDialer(network string, remote *net.TCPAddr) *net.Dialer {
+	return u.dialer(network, &net.TCPAddr{IP: remote.IP, Zone: remote.Zone})
+}
+
+// dialer transparent 不为 nil 时以该地址为源地址并设置 IP_TRANSPARENT，此时不使用 ipv4-bind 和 ipv6-bind
+func (u *UpstreamConfig) dialer(network string, transparent *net.TCPAddr) *net.Dialer {
+	if network == "unix" {
+		return &net.Dialer{Timeout: u.ConnectTimeout()}
+	}
+
+	opt := &sockopt.Options{
+		Device:      u.Device,
+		Mark:        uint32(u.Mark),
+		Transparent: transparent != nil,
+	}
+
+	res := &net.Dialer{Timeout: u.ConnectTimeout()}
+
+	if !u.KeepAlive.IsEnable(true) {
+		res.KeepAlive = -1 // 负数表示关闭 keepalive
+	} else {
+		res.KeepAlive = time.Duration(u.KeepAliveSeconds) * time.Second // 0 表示默认（15秒）
+		opt.KeepAliveCount = int(u.KeepAliveCount)
+	}
+
+	if transparent != nil {
+		res.LocalAddr = transparent
+	} else if network == "tcp4" && u.ResolveIPv4Bind != nil {
+		res.LocalAddr = u.ResolveIPv4Bind
+	} else if network == "tcp6" && u.ResolveIPv6Bind != nil {
+		res.LocalAddr = u.ResolveIPv6Bind
+	}
+
+	res.Control = opt.Control()
+	return res
+}
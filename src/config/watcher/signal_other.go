@@ -0,0 +1,8 @@
+//go:build !unix
+
+package watcher
+
+// WatchReloadSignal 当前系统不支持 SIGHUP，不做任何处理
+func WatchReloadSignal(reload func()) (stop func()) {
+	return func() {}
+}
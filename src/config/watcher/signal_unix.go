@@ -0,0 +1,32 @@
+//go:build unix
+
+package watcher
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// WatchReloadSignal 监听 SIGHUP，收到后调用 reload 重新加载配置文件（不依赖文件监听），返回停止监听的函数
+func WatchReloadSignal(reload func()) (stop func()) {
+	sigchan := make(chan os.Signal, 1)
+	stopchan := make(chan bool)
+	signal.Notify(sigchan, syscall.SIGHUP)
+
+	go func() {
+		for {
+			select {
+			case <-stopchan:
+				return
+			case <-sigchan:
+				reload()
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(sigchan)
+		close(stopchan)
+	}
+}
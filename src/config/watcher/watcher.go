@@ -6,11 +6,118 @@ import (
 	"github.com/SongZihuan/huan-springboard/src/logger"
 	"github.com/SongZihuan/huan-springboard/src/utils"
 	"github.com/fsnotify/fsnotify"
+	"path/filepath"
+	"time"
 )
 
+// debounceTime 最后一次事件后等待的时间，合并连续的写入事件，避免读取写入一半的文件
+const debounceTime = 500 * time.Millisecond
+
 var watcher *fsnotify.Watcher
 
-func WatcherConfigFile() error {
+// configFiles 监听的配置文件：主配置文件（可以是符号链接）、include 的文件以及 include 的通配符
+type configFiles struct {
+	watcher    *fsnotify.Watcher
+	configPath string
+	configDir  string
+
+	realPath string          // 主配置文件的链接目标
+	files    map[string]bool // include 的文件
+	patterns []string        // include 的通配符，匹配新增的文件
+	dirs     map[string]bool // 正在监听的目录
+}
+
+// followLink 更新主配置文件的链接目标，返回链接目标是否变化
+func (c *configFiles) followLink() bool {
+	realPath, err := filepath.EvalSymlinks(c.configPath)
+	if err != nil {
+		return false // 文件不存在（例如正在替换），等待下一次事件
+	}
+
+	realPath, err = utils.CleanFilePathAbs(realPath)
+	if err != nil || realPath == c.realPath {
+		return false
+	}
+
+	changed := c.realPath != ""
+	c.realPath = realPath
+	c.refreshDirs()
+
+	return changed
+}
+
+// refresh 重新加载配置后更新 include 的文件和通配符
+func (c *configFiles) refresh() {
+	c.files = make(map[string]bool, 10)
+	for _, f := range config.GetConfigFiles() {
+		c.files[f] = true
+	}
+
+	c.patterns = config.GetIncludePatterns()
+	c.refreshDirs()
+}
+
+// refreshDirs 监听全部文件和通配符所在的目录，不再需要的目录停止监听
+func (c *configFiles) refreshDirs() {
+	dirs := map[string]bool{c.configDir: true}
+
+	if c.realPath != "" {
+		dirs[filepath.Dir(c.realPath)] = true
+	}
+
+	for f := range c.files {
+		dirs[filepath.Dir(f)] = true
+	}
+
+	for _, p := range c.patterns {
+		dirs[filepath.Dir(p)] = true
+	}
+
+	for dir := range c.dirs {
+		if !dirs[dir] {
+			_ = c.watcher.Remove(dir)
+		}
+	}
+
+	for dir := range dirs {
+		if c.dirs[dir] {
+			continue
+		}
+
+		err := c.watcher.Add(dir)
+		if err != nil {
+			logger.Warnf("Watch config dir %s error: %s", dir, err.Error())
+			delete(dirs, dir) // 下次重新尝试
+		}
+	}
+
+	c.dirs = dirs
+}
+
+// match 事件是否与配置文件有关
+func (c *configFiles) match(name string) bool {
+	name, err := utils.CleanFilePathAbs(name)
+	if err != nil {
+		return false
+	}
+
+	if name == c.configPath || name == c.realPath || c.files[name] {
+		return true
+	}
+
+	for _, p := range c.patterns {
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WatcherConfigFile 监听配置文件（以及 include 的文件和目录）的修改，修改后调用 reload。
+// 配置文件可以是符号链接（例如 Kubernetes ConfigMap 通过替换 ..data 符号链接原子更新），
+// 此时同时监听链接目标所在的目录，并在链接目标变化时重新加载。
+func WatcherConfigFile(reload func()) error {
 	if watcher != nil {
 		return nil
 	}
@@ -31,6 +138,15 @@ func WatcherConfigFile() error {
 		return err
 	}
 
+	files := &configFiles{
+		watcher:    _watcher,
+		configPath: config.GetConfigPathFile(),
+		configDir:  watcherDir,
+		dirs:       map[string]bool{watcherDir: true},
+	}
+	files.followLink()
+	files.refresh()
+
 	// Start listening for events.
 	go func() {
 		defer func() {
@@ -43,6 +159,10 @@ func WatcherConfigFile() error {
 			logger.Warnf("Auto reload stop.")
 		}()
 
+		debounce := time.NewTimer(debounceTime)
+		debounce.Stop()
+		defer debounce.Stop()
+
 	OutSideCycle:
 		for {
 			select {
@@ -54,24 +174,24 @@ func WatcherConfigFile() error {
 				// github.com/fsnotify/fsnotify v1.8.0
 				// 根据2024.1月的消息，暂时无法导出RenameFrom，无法跟着重命名
 				// issues: https://github.com/fsnotify/fsnotify/issues/630
-				if !utils.FilePathEqual(event.Name, config.GetConfigPathFile()) {
+				// 因此不跟踪事件本身，而是在目录发生变化时重新解析配置文件的链接目标
+				linkChanged := files.followLink()
+
+				if !linkChanged && !files.match(event.Name) {
 					continue OutSideCycle
 				}
 
-				if event.Has(fsnotify.Write) || event.Has(fsnotify.Create) {
-					err := config.ReloadConfig()
-					if err != nil && err.IsError() {
-						logger.Errorf("Config file reload error: %s", err.Error())
-					} else if err != nil && err.IsWarning() {
-						logger.Warnf("Config file reload error: %s", err.Warning())
-					} else {
-						logger.Infof("%s", "Config file reload success")
+				if linkChanged || event.Has(fsnotify.Write) || event.Has(fsnotify.Create) {
+					debounce.Reset(debounceTime)
+				} else if event.Has(fsnotify.Rename) || event.Has(fsnotify.Remove) {
+					logger.Warnf("Config file %s has been rename or remove", event.Name)
+					if !utils.FilePathEqual(event.Name, files.configPath) {
+						debounce.Reset(debounceTime) // include 的文件被删除，重新合并
 					}
-				} else if event.Has(fsnotify.Rename) {
-					logger.Warnf("%s", "Config file has been rename")
-				} else if event.Has(fsnotify.Remove) {
-					logger.Warnf("%s", "Config file has been remove")
 				}
+			case <-debounce.C:
+				reload()
+				files.refresh()
 			case err, ok := <-_watcher.Errors:
 				if !ok || errors.Is(err, fsnotify.ErrClosed) {
 					return
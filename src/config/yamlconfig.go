@@ -1,19 +1,30 @@
 package config
 
 import (
-	"gopkg.in/yaml.v3"
-	"os"
+	"slices"
 )
 
 type YamlConfig struct {
 	GlobalConfig `yaml:",inline"`
 
+	Include []string `yaml:"include,omitempty"` // 合并其他配置文件：文件、通配符或者目录
+
+	Log    LogConfig    `yaml:"log"`
 	TCP    TcpConfig    `yaml:"tcp"`
 	SSH    SshConfig    `yaml:"ssh"`
 	API    ApiConfig    `yaml:"api"`
 	SMTP   SMTPConfig   `yaml:"smtp"`
+	Notify NotifyConfig `yaml:"notify"`
 	Redis  RedisConfig  `yaml:"redis"`
 	SQLite SQLiteConfig `yaml:"sqlite"`
+	Admin  AdminConfig  `yaml:"admin"`
+
+	files    []string            // 加载的全部文件，第一个为主配置文件
+	contents [][]byte            // 加载的全部文件的原始内容，与 files 对应，用于保存快照
+	patterns []string            // include 的通配符（包括目录），用于监听新增的文件
+	origins  map[string][]string // 合并的列表中每一项来自的文件
+
+	decodeIssues []*ConfigIssue // 解析时类型或格式不正确的选项，检查时作为错误报告
 }
 
 func (y *YamlConfig) Init() error {
@@ -22,63 +33,77 @@ func (y *YamlConfig) Init() error {
 
 func (y *YamlConfig) setDefault() {
 	y.GlobalConfig.setDefault()
+	y.Log.setDefault()
 	y.TCP.setDefault()
 	y.SSH.setDefault()
 	y.API.setDefault()
 	y.SMTP.setDefault()
+	y.Notify.setDefault()
 	y.Redis.setDefault()
 	y.SQLite.setDefault()
+	y.Admin.setDefault()
 }
 
-func (y *YamlConfig) check() (err ConfigError) {
-	err = y.GlobalConfig.Check()
-	if err != nil && err.IsError() {
-		return err
-	}
+func (y *YamlConfig) check(report *checkReport) (err ConfigError) {
+	for _, i := range y.decodeIssues {
+		leave := report.enterPath(i.Path)
+		err = report.error(i.Msg)
+		leave()
 
-	err = y.TCP.check()
-	if err != nil && err.IsError() {
-		return err
+		if !report.keepChecking() {
+			return err
+		}
 	}
 
-	err = y.SSH.check()
-	if err != nil && err.IsError() {
+	err = y.GlobalConfig.Check(report)
+	if err != nil && err.IsError() && !report.keepChecking() {
 		return err
 	}
 
-	err = y.API.check()
-	if err != nil && err.IsError() {
-		return err
+	sections := []struct {
+		name  string
+		check func(report *checkReport) ConfigError
+	}{
+		{"log", y.Log.check},
+		{"tcp", y.TCP.check},
+		{"ssh", y.SSH.check},
+		{"api", y.API.check},
+		{"smtp", y.SMTP.check},
+		{"notify", y.Notify.check},
+		{"redis", y.Redis.check},
+		{"sqlite", y.SQLite.check},
+		{"admin", y.Admin.check},
 	}
 
-	err = y.SMTP.check()
-	if err != nil && err.IsError() {
-		return err
-	}
+	for _, section := range sections {
+		leave := report.enterPath(section.name)
+		err = section.check(report)
+		leave()
 
-	err = y.Redis.check()
-	if err != nil && err.IsError() {
-		return err
-	}
-
-	err = y.SQLite.check()
-	if err != nil && err.IsError() {
-		return err
+		if err != nil && err.IsError() && !report.keepChecking() {
+			return err
+		}
 	}
 
 	return nil
 }
 
 func (y *YamlConfig) parser(filepath string) ParserError {
-	file, err := os.ReadFile(filepath)
-	if err != nil {
-		return NewParserError(err, err.Error())
-	}
+	y.files = make([]string, 0, 1)
+	y.contents = make([][]byte, 0, 1)
+	y.patterns = make([]string, 0)
+	y.origins = make(map[string][]string, 5)
+	y.decodeIssues = nil
 
-	err = yaml.Unmarshal(file, y)
-	if err != nil {
-		return NewParserError(err, err.Error())
-	}
+	return y.parseFile(filepath, make(map[string]bool, 1))
+}
 
-	return nil
+// Files 加载的全部配置文件（绝对路径），第一个为主配置文件
+func (y *YamlConfig) Files() []string {
+	return slices.Clone(y.files)
+}
+
+// IncludePatterns include 的通配符（绝对路径，目录已转换为通配符）
+func (y *YamlConfig) IncludePatterns() []string {
+	return slices.Clone(y.patterns)
 }
@@ -0,0 +1,309 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/SongZihuan/huan-springboard/src/logger"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	BanScopeTCP = "tcp"
+	BanScopeSSH = "ssh"
+)
+
+// 封禁目标类型，ip 和 cidr 存储在同一张表中（cidr 的值包含 /）
+const (
+	BanTargetIP       = "ip"
+	BanTargetCIDR     = "cidr"
+	BanTargetNation   = "nation"
+	BanTargetProvince = "province"
+	BanTargetCity     = "city"
+	BanTargetISP      = "isp"
+)
+
+var BanTargets = []string{BanTargetIP, BanTargetCIDR, BanTargetNation, BanTargetProvince, BanTargetCity, BanTargetISP}
+
+// Ban 一条封禁记录（不区分表），用于命令行管理
+type Ban struct {
+	ID      uint       `json:"id"`
+	Scope   string     `json:"scope"`
+	Target  string     `json:"target"`
+	Value   string     `json:"value"`
+	StartAt *time.Time `json:"start_at"` // 为空表示立即生效
+	StopAt  *time.Time `json:"stop_at"`  // 为空表示永久
+}
+
+// Active 封禁在 now 时是否生效
+func (b *Ban) Active(now time.Time) bool {
+	return (b.StartAt == nil || !now.Before(*b.StartAt)) && (b.StopAt == nil || !now.After(*b.StopAt))
+}
+
+type banRow struct {
+	ID      uint         `gorm:"column:id"`
+	Value   string       `gorm:"column:value"`
+	StartAt sql.NullTime `gorm:"column:start_at"`
+	StopAt  sql.NullTime `gorm:"column:stop_at"`
+}
+
+// banTable 封禁目标对应的表名和列名
+func banTable(scope string, target string) (table string, column string, err error) {
+	if scope != BanScopeTCP && scope != BanScopeSSH {
+		return "", "", fmt.Errorf("bad scope '%s', must be tcp/ssh", scope)
+	}
+
+	switch target {
+	case BanTargetIP, BanTargetCIDR:
+		return scope + "_banned_ip", "ip", nil
+	case BanTargetNation:
+		return scope + "_banned_location_nation", "nation", nil
+	case BanTargetProvince:
+		return scope + "_banned_location_province", "province", nil
+	case BanTargetCity:
+		return scope + "_banned_location_city", "city", nil
+	case BanTargetISP:
+		return scope + "_banned_location_isp", "isp", nil
+	default:
+		return "", "", fmt.Errorf("bad target '%s', must be %s", target, strings.Join(BanTargets, "/"))
+	}
+}
+
+// NormalizeBanValue 检查并规范化封禁的值（ip 和 cidr 转换为标准格式）
+func NormalizeBanValue(target string, value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", fmt.Errorf("%s is empty", target)
+	}
+
+	switch target {
+	case BanTargetIP:
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return "", fmt.Errorf("bad ip '%s'", value)
+		}
+		return ip.String(), nil
+	case BanTargetCIDR:
+		_, ipnet, err := net.ParseCIDR(value)
+		if err != nil {
+			return "", fmt.Errorf("bad cidr '%s'", value)
+		}
+		return ipnet.String(), nil
+	}
+
+	if len(value) > 50 {
+		return "", fmt.Errorf("%s '%s' is too long", target, value)
+	}
+
+	return value, nil
+}
+
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: localTime(*t), Valid: true}
+}
+
+func timeOf(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// AddBan 添加封禁，startAt 为空表示立即生效，stopAt 为空表示永久
+func AddBan(scope string, target string, value string, startAt *time.Time, stopAt *time.Time) (*Ban, error) {
+	table, column, err := banTable(scope, target)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err = NormalizeBanValue(target, value)
+	if err != nil {
+		return nil, err
+	}
+
+	if startAt != nil && stopAt != nil && !stopAt.After(*startAt) {
+		return nil, fmt.Errorf("stop time must be after start time")
+	}
+
+	row := map[string]any{
+		column:     value,
+		"start_at": nullTime(startAt),
+		"stop_at":  nullTime(stopAt),
+	}
+
+	err = db.Table(table).Create(row).Error
+	if err != nil {
+		return nil, err
+	}
+
+	invalidateCIDRCache(scope)
+
+	var res banRow
+	err = db.Table(table).Select("id, "+column+" AS value, start_at, stop_at").Where(column+" = ?", value).Order("id desc").First(&res).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &Ban{
+		ID:      res.ID,
+		Scope:   scope,
+		Target:  target,
+		Value:   res.Value,
+		StartAt: timeOf(res.StartAt),
+		StopAt:  timeOf(res.StopAt),
+	}, nil
+}
+
+// RemoveBan 解除封禁：将生效中和未生效的记录的结束时间设置为当前时间（保留记录），返回修改的记录数
+func RemoveBan(scope string, target string, value string) (int64, error) {
+	table, column, err := banTable(scope, target)
+	if err != nil {
+		return 0, err
+	}
+
+	value, err = NormalizeBanValue(target, value)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	res := db.Table(table).Where(column+" = ? AND (stop_at IS NULL OR stop_at > ?)", value, now).Update("stop_at", now)
+	if res.Error != nil {
+		return 0, res.Error
+	}
+
+	invalidateCIDRCache(scope)
+	return res.RowsAffected, nil
+}
+
+// ListBans 列出封禁记录，all 为 false 时只列出生效中和未生效（尚未开始）的记录
+func ListBans(scope string, target string, all bool) ([]*Ban, error) {
+	table, column, err := banTable(scope, target)
+	if err != nil {
+		return nil, err
+	}
+
+	query := db.Table(table).Select("id, " + column + " AS value, start_at, stop_at")
+
+	switch target {
+	case BanTargetIP:
+		query = query.Where(column + " NOT LIKE '%/%'")
+	case BanTargetCIDR:
+		query = query.Where(column + " LIKE '%/%'")
+	}
+
+	if !all {
+		query = query.Where("stop_at IS NULL OR stop_at > ?", time.Now())
+	}
+
+	var rows []banRow
+	err = query.Order("id asc").Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*Ban, 0, len(rows))
+	for _, r := range rows {
+		res = append(res, &Ban{
+			ID:      r.ID,
+			Scope:   scope,
+			Target:  target,
+			Value:   r.Value,
+			StartAt: timeOf(r.StartAt),
+			StopAt:  timeOf(r.StopAt),
+		})
+	}
+
+	return res, nil
+}
+
+// checkCIDRAllow IP 没有单独的封禁记录时，检查所在网段是否被封禁，返回 true 表示放行
+func checkCIDRAllow(scope string, ip string) bool {
+	banned, err := checkCIDRBanned(scope, ip)
+	if err != nil {
+		logger.Errorf("CheckCIDR from DB failed: %s", err.Error())
+		return true
+	}
+
+	return !banned
+}
+
+// cidrCacheTTL 生效中的 CIDR 封禁的缓存时间：本进程修改封禁时立即失效，其他进程（ban 子命令）的修改在缓存过期后生效
+const cidrCacheTTL = 10 * time.Second
+
+type cidrBan struct {
+	ipnet *net.IPNet
+	ban   *Ban
+}
+
+type cidrCache struct {
+	bans   []*cidrBan
+	expire time.Time
+}
+
+var cidrCacheLock sync.Mutex
+var cidrCacheMap = make(map[string]*cidrCache) // 键为 scope
+
+// invalidateCIDRCache 修改封禁后清除缓存
+func invalidateCIDRCache(scope string) {
+	cidrCacheLock.Lock()
+	defer cidrCacheLock.Unlock()
+
+	delete(cidrCacheMap, scope)
+}
+
+// loadCIDRBans 读取生效中和未生效（尚未开始）的 CIDR 封禁，已失效的记录在查询时过滤
+func loadCIDRBans(scope string, now time.Time) ([]*cidrBan, error) {
+	cidrCacheLock.Lock()
+	defer cidrCacheLock.Unlock()
+
+	if c, ok := cidrCacheMap[scope]; ok && now.Before(c.expire) {
+		return c.bans, nil
+	}
+
+	bans, err := ListBans(scope, BanTargetCIDR, false)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*cidrBan, 0, len(bans))
+	for _, b := range bans {
+		_, ipnet, err := net.ParseCIDR(b.Value)
+		if err != nil {
+			continue
+		}
+
+		res = append(res, &cidrBan{ipnet: ipnet, ban: b})
+	}
+
+	cidrCacheMap[scope] = &cidrCache{bans: res, expire: now.Add(cidrCacheTTL)}
+	return res, nil
+}
+
+// checkCIDRBanned 检查 ip 是否被 CIDR 封禁（任意一条生效中的记录）
+func checkCIDRBanned(scope string, ip string) (bool, error) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false, nil
+	}
+
+	now := time.Now()
+
+	bans, err := loadCIDRBans(scope, now)
+	if err != nil {
+		return false, err
+	}
+
+	for _, b := range bans {
+		if b.ipnet.Contains(addr) && b.ban.Active(now) { // 缓存期间记录可能开始或失效
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,128 @@
+package database
+
+import (
+	"gorm.io/gorm/logger"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// openTestDB 使用临时数据库替换 db
+func openTestDB(t *testing.T) {
+	_db, err := openSQLite(filepath.Join(t.TempDir(), "test.db"), logger.Default.LogMode(logger.Silent))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old := db
+	db = _db
+	t.Cleanup(func() {
+		db = old
+		invalidateCIDRCache(BanScopeTCP)
+		invalidateCIDRCache(BanScopeSSH)
+	})
+}
+
+func TestNormalizeBanValue(t *testing.T) {
+	tests := []struct {
+		target  string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{BanTargetIP, " 192.0.2.1 ", "192.0.2.1", false},
+		{BanTargetIP, "2001:0db8::0001", "2001:db8::1", false},
+		{BanTargetIP, "::ffff:192.0.2.1", "192.0.2.1", false},
+		{BanTargetIP, "192.0.2.256", "", true},
+		{BanTargetIP, "192.0.2.0/24", "", true},
+		{BanTargetIP, "", "", true},
+		{BanTargetCIDR, "10.1.2.3/16", "10.1.0.0/16", false},
+		{BanTargetCIDR, "2001:db8::1/32", "2001:db8::/32", false},
+		{BanTargetCIDR, "10.0.0.0/33", "", true},
+		{BanTargetCIDR, "10.0.0.1", "", true},
+		{BanTargetNation, " 中国 ", "中国", false},
+		{BanTargetISP, "   ", "", true},
+		{BanTargetCity, string(make([]byte, 51)), "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := NormalizeBanValue(tt.target, tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NormalizeBanValue(%s, %q) error = %v, wantErr %v", tt.target, tt.value, err, tt.wantErr)
+		} else if got != tt.want {
+			t.Errorf("NormalizeBanValue(%s, %q) = %q, want %q", tt.target, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestCheckCIDRBanned(t *testing.T) {
+	openTestDB(t)
+
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	bans := []struct {
+		scope   string
+		value   string
+		startAt *time.Time
+		stopAt  *time.Time
+	}{
+		{BanScopeTCP, "10.1.0.0/16", nil, nil},
+		{BanScopeTCP, "10.2.0.0/16", nil, &future},
+		{BanScopeTCP, "10.3.0.0/16", &future, nil},
+		{BanScopeTCP, "2001:db8::/32", nil, nil},
+		{BanScopeSSH, "10.4.0.0/16", nil, nil},
+	}
+	for _, b := range bans {
+		if _, err := AddBan(b.scope, BanTargetCIDR, b.value, b.startAt, b.stopAt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 已失效的记录
+	err := db.Table("tcp_banned_ip").Create(map[string]any{"ip": "10.5.0.0/16", "start_at": nullTime(&past), "stop_at": nullTime(&past)}).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		scope string
+		ip    string
+		want  bool
+	}{
+		{BanScopeTCP, "10.1.2.3", true},
+		{BanScopeTCP, "10.2.2.3", true},
+		{BanScopeTCP, "10.3.2.3", false}, // 尚未开始
+		{BanScopeTCP, "10.4.2.3", false}, // 其他 scope
+		{BanScopeTCP, "10.5.2.3", false}, // 已失效
+		{BanScopeTCP, "10.6.2.3", false},
+		{BanScopeTCP, "2001:db8::1", true},
+		{BanScopeTCP, "2001:db9::1", false},
+		{BanScopeTCP, "not-an-ip", false},
+		{BanScopeSSH, "10.4.2.3", true},
+		{BanScopeSSH, "10.1.2.3", false},
+	}
+
+	for _, tt := range tests {
+		got, err := checkCIDRBanned(tt.scope, tt.ip)
+		if err != nil {
+			t.Fatal(err)
+		} else if got != tt.want {
+			t.Errorf("checkCIDRBanned(%s, %s) = %v, want %v", tt.scope, tt.ip, got, tt.want)
+		}
+	}
+
+	// 解除封禁后缓存立即失效
+	if _, err = RemoveBan(BanScopeTCP, BanTargetCIDR, "10.1.0.0/16"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, _ := checkCIDRBanned(BanScopeTCP, "10.1.2.3"); got {
+		t.Error("10.1.2.3 is still banned after unban")
+	}
+
+	if got := checkCIDRAllow(BanScopeTCP, "10.2.2.3"); got {
+		t.Error("checkCIDRAllow(10.2.2.3) = true, want false")
+	}
+}
@@ -139,6 +139,33 @@ func (c *Cleaner) clean() {
 			logger.Errorf("clean ssh connect record error: %s", err.Error())
 		}
 	}()
+
+	c.swg.Add(1)
+	go func() {
+		defer c.swg.Done()
+
+		defer func() {
+			r := recover()
+			if r != nil {
+				if err, ok := r.(error); ok {
+					logger.Panicf("Database clean notify dead letter panic error: %s", err.Error())
+				} else {
+					logger.Panicf("Database clean notify dead letter panic: %v", r)
+				}
+			}
+		}()
+
+		if config.GetConfig().SQLite.Clean.NotifyDeadSaveTime == -1 {
+			logger.Errorf("skip clean notify dead letter")
+			return
+		}
+
+		logger.Infof("start clean notify dead letter")
+		err := CleanNotifyDeadLetter(config.GetConfig().SQLite.Clean.NotifyDeadSaveTime)
+		if err != nil {
+			logger.Errorf("clean notify dead letter error: %s", err.Error())
+		}
+	}()
 }
 
 func (c *Cleaner) Stop() error {
@@ -17,7 +17,7 @@ func TcpCheckIP(ip string) bool {
 	var res TcpBannedIP
 	err := db.Model(&TcpBannedIP{}).Where("ip = ?", ip).Order("id desc").First(&res).Error
 	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
-		return true
+		return checkCIDRAllow(BanScopeTCP, ip)
 	} else if err != nil {
 		logger.Errorf("CheckIP from DB failed: %s", err.Error())
 		return true
@@ -25,9 +25,9 @@ func TcpCheckIP(ip string) bool {
 
 	now := time.Now()
 	if res.StartAt.Valid && now.Before(res.StartAt.Time) {
-		return true // 未生效规则
+		return checkCIDRAllow(BanScopeTCP, ip) // 未生效规则
 	} else if res.StopAt.Valid && now.After(res.StopAt.Time) {
-		return true // 已失效规则
+		return checkCIDRAllow(BanScopeTCP, ip) // 已失效规则
 	}
 
 	return false
@@ -133,7 +133,7 @@ func SshCheckIP(ip string) bool {
 	var res SshBannedIP
 	err := db.Model(&SshBannedIP{}).Where("ip = ?", ip).Order("id desc").First(&res).Error
 	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
-		return true
+		return checkCIDRAllow(BanScopeSSH, ip)
 	} else if err != nil {
 		logger.Errorf("CheckIP from DB failed: %s", err.Error())
 		return true
@@ -141,9 +141,9 @@ func SshCheckIP(ip string) bool {
 
 	now := time.Now()
 	if res.StartAt.Valid && now.Before(res.StartAt.Time) {
-		return true // 未生效规则
+		return checkCIDRAllow(BanScopeSSH, ip) // 未生效规则
 	} else if res.StopAt.Valid && now.After(res.StopAt.Time) {
-		return true // 已失效规则
+		return checkCIDRAllow(BanScopeSSH, ip) // 已失效规则
 	}
 
 	return false
@@ -245,7 +245,7 @@ func SshCheckLocationISP(isp string) bool {
 	return false
 }
 
-func AddSshConnectRecord(from string, fromIP net.IP, to *net.TCPAddr, accept bool, t time.Time, mark string) (*SshConnectRecord, error) {
+func AddSshConnectRecord(from string, fromIP net.IP, to net.Addr, accept bool, t time.Time, mark string) (*SshConnectRecord, error) {
 	if fromIP == nil {
 		fromIP = net.ParseIP(from)
 		if fromIP == nil {
@@ -267,9 +267,9 @@ func AddSshConnectRecord(from string, fromIP net.IP, to *net.TCPAddr, accept boo
 
 	record := SshConnectRecord{
 		From:   fromIP.String(),
-		To:     to.String(),
+		To:     addrString(to),
 		Accept: accept,
-		Time:   t,
+		Time:   localTime(t),
 		Mark:   mark,
 	}
 	err := db.Create(&record).Error
@@ -316,7 +316,15 @@ func UpdateSshConnectRecord(record *SshConnectRecord, mark string) (err error) {
 	return nil
 }
 
-func FindSshConnectRecord(from string, fromIP net.IP, to *net.TCPAddr, limit int, after time.Time) ([]SshConnectRecord, error) {
+// addrString 目标地址（ip:port 或 unix socket 路径），nil 时为空字符串
+func addrString(addr net.Addr) string {
+	if addr == nil {
+		return ""
+	}
+	return addr.String()
+}
+
+func FindSshConnectRecord(from string, fromIP net.IP, to net.Addr, limit int, after time.Time) ([]SshConnectRecord, error) {
 	var res []SshConnectRecord
 
 	if fromIP == nil {
@@ -334,7 +342,7 @@ func FindSshConnectRecord(from string, fromIP net.IP, to *net.TCPAddr, limit int
 		}
 	}
 
-	err := db.Model(&SshConnectRecord{}).Where("`time` > ? AND `to` = ? AND `from` = ?", after, to.String(), fromIP.String()).Order("time asc").Limit(limit).Find(&res).Error
+	err := db.Model(&SshConnectRecord{}).Where("`time` > ? AND `to` = ? AND `from` = ?", localTime(after), addrString(to), fromIP.String()).Order("time asc").Limit(limit).Find(&res).Error
 	if err != nil {
 		return nil, err
 	}
@@ -357,7 +365,7 @@ func AddIfaceRecord(name string, bytesSent uint64, bytesRecv uint64, t time.Time
 		Name:      name,
 		BytesSent: bytesSent,
 		BytesRecv: bytesRecv,
-		Time:      t,
+		Time:      localTime(t),
 	}
 	err := db.Create(&record).Error
 	if err != nil {
@@ -394,7 +402,7 @@ func FindIfaceLastRecord(name string) (*IfaceRecord, error) {
 
 func FindIfaceRecord(name string, before time.Time) (*IfaceRecord, error) {
 	var res IfaceRecord
-	err := db.Model(&IfaceRecord{}).Where("`name` = ? AND `time` < ?", name, before).Order("time desc").First(&res).Error
+	err := db.Model(&IfaceRecord{}).Where("`name` = ? AND `time` < ?", name, localTime(before)).Order("time desc").First(&res).Error
 	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, ErrNotFound
 	} else if err != nil {
@@ -5,6 +5,8 @@ import (
 	"github.com/SongZihuan/huan-springboard/src/config"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"time"
 )
 
 var db *gorm.DB
@@ -14,24 +16,58 @@ func InitSQLite() error {
 		panic("config is not ready")
 	}
 
-	_db, err := gorm.Open(sqlite.Open(config.GetConfig().SQLite.Path), &gorm.Config{
-		Logger: newDBLogger(),
+	return initSQLite(newDBLogger())
+}
+
+// InitSQLiteSilent 不输出 SQL 日志，用于命令行子命令（错误通过返回值处理）
+func InitSQLiteSilent() error {
+	if !config.IsReady() {
+		panic("config is not ready")
+	}
+
+	return initSQLite(logger.Default.LogMode(logger.Silent))
+}
+
+func initSQLite(dbLogger logger.Interface) error {
+	_db, err := openSQLite(config.GetConfig().SQLite.Path, dbLogger)
+	if err != nil {
+		return err
+	}
+
+	db = _db
+	return nil
+}
+
+// openSQLite 打开数据库并迁移全部表
+func openSQLite(path string, dbLogger logger.Interface) (*gorm.DB, error) {
+	_db, err := gorm.Open(sqlite.Open(path), &gorm.Config{
+		Logger: dbLogger,
 	})
 	if err != nil {
-		return fmt.Errorf("connect to sqlite (%s) failed: %s", config.GetConfig().SQLite.Path, err)
+		return nil, fmt.Errorf("connect to sqlite (%s) failed: %s", path, err)
 	}
 
 	err = _db.AutoMigrate(&TcpBannedIP{}, &TcpBannedLocationNation{},
 		&TcpBannedLocationProvince{}, &TcpBannedLocationCity{},
 		&TcpBannedLocationISP{}, &SshBannedIP{}, &SshBannedLocationNation{},
 		&SshBannedLocationProvince{}, &SshBannedLocationCity{},
-		&SshBannedLocationISP{}, &SshConnectRecord{}, &IfaceRecord{})
+		&SshBannedLocationISP{}, &SshConnectRecord{}, &IfaceRecord{},
+		&NotifyQueue{})
 	if err != nil {
-		return fmt.Errorf("auto migrate sqlite (%s) failed: %s", config.GetConfig().SQLite.Path, err)
+		return nil, fmt.Errorf("auto migrate sqlite (%s) failed: %s", path, err)
 	}
 
-	db = _db
-	return nil
+	return _db, nil
+}
+
+// localTime go-sqlite3 将时间保存为带时区偏移的字符串，按字符串比较，
+// 因此写入和查询条件中的时间统一转换为本地时区（与 time.Now() 相同）
+func localTime(t time.Time) time.Time {
+	return t.In(time.Local)
+}
+
+func IsReady() bool {
+	return db != nil
 }
 
 func CloseSQLite() {
@@ -141,3 +141,18 @@ type IfaceRecord struct {
 func (*IfaceRecord) TableName() string {
 	return "iface_record"
 }
+
+type NotifyQueue struct {
+	Model
+	Channel   string    `gorm:"column:channel;type:VARCHAR(100);not null;"`
+	Message   string    `gorm:"column:message;type:TEXT;not null;"` // JSON 格式的消息
+	Attempts  int64     `gorm:"column:attempts;not null;"`
+	Dead      bool      `gorm:"column:dead;not null;"` // 超过最大重试次数，进入死信
+	LastError string    `gorm:"column:last_error;type:TEXT;not null;"`
+	NextAt    time.Time `gorm:"column:next_at;not null;"`
+	CreatedAt time.Time `gorm:"column:created_at;not null;"`
+}
+
+func (*NotifyQueue) TableName() string {
+	return "notify_queue"
+}
@@ -0,0 +1,79 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+func AddNotifyQueue(channel string, message string, t time.Time) (*NotifyQueue, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database is not ready")
+	}
+
+	record := NotifyQueue{
+		Channel:   channel,
+		Message:   message,
+		Attempts:  0,
+		Dead:      false,
+		LastError: "",
+		NextAt:    localTime(t),
+		CreatedAt: localTime(t),
+	}
+	err := db.Create(&record).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+// FindDueNotifyQueue 查找需要发送的消息，before 为零值时忽略重试时间（用于停止前清空队列），exclude 为跳过的消息 ID
+func FindDueNotifyQueue(before time.Time, exclude []uint, limit int) ([]NotifyQueue, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database is not ready")
+	}
+
+	var res []NotifyQueue
+
+	query := db.Model(&NotifyQueue{}).Where("`dead` = ?", false)
+	if !before.IsZero() {
+		query = query.Where("`next_at` <= ?", localTime(before))
+	}
+
+	if len(exclude) != 0 {
+		query = query.Where("`id` NOT IN ?", exclude)
+	}
+
+	err := query.Order("next_at asc").Limit(limit).Find(&res).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func UpdateNotifyQueue(record *NotifyQueue) error {
+	if db == nil {
+		return fmt.Errorf("database is not ready")
+	}
+
+	return db.Save(record).Error
+}
+
+func DeleteNotifyQueue(record *NotifyQueue) error {
+	if db == nil {
+		return fmt.Errorf("database is not ready")
+	}
+
+	return db.Unscoped().Delete(record).Error
+}
+
+func CleanNotifyDeadLetter(keep time.Duration) error {
+	dl := time.Now().Add(-1 * keep)
+	err := db.Unscoped().Model(&NotifyQueue{}).Where("`dead` = ? AND `created_at` < ?", true, dl).Delete(&NotifyQueue{}).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
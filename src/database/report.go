@@ -0,0 +1,239 @@
+package database
+
+import (
+	"database/sql"
+	"gorm.io/gorm"
+	"sort"
+	"time"
+)
+
+// ConnectRecordFilter 连接记录的查询条件，字段为空表示不限制
+type ConnectRecordFilter struct {
+	IP     string     `json:"ip"` // 来访 IP
+	To     string     `json:"to"` // 转发目标，例如 127.0.0.1:22
+	Since  *time.Time `json:"since"`
+	Until  *time.Time `json:"until"`
+	Accept *bool      `json:"accept"`
+}
+
+func (f *ConnectRecordFilter) apply(query *gorm.DB) *gorm.DB {
+	if f == nil {
+		return query
+	}
+
+	if f.IP != "" {
+		query = query.Where("`from` = ?", f.IP)
+	}
+
+	if f.To != "" {
+		query = query.Where("`to` = ?", f.To)
+	}
+
+	if f.Since != nil {
+		query = query.Where("`time` >= ?", localTime(*f.Since))
+	}
+
+	if f.Until != nil {
+		query = query.Where("`time` < ?", localTime(*f.Until))
+	}
+
+	if f.Accept != nil {
+		query = query.Where("`accept` = ?", *f.Accept)
+	}
+
+	return query
+}
+
+// ConnectRecord 一条连接记录，用于查询和导出
+type ConnectRecord struct {
+	ID            uint      `json:"id"`
+	Time          time.Time `json:"time"`
+	From          string    `json:"from"`
+	To            string    `json:"to"`
+	Accept        bool      `json:"accept"`
+	TimeConsuming *int64    `json:"time_consuming"` // 单位：毫秒，为空表示连接未记录断开（拒绝或连接中）
+	Mark          string    `json:"mark"`
+}
+
+// FindConnectRecords 按时间倒序查询连接记录，limit 小于等于 0 表示不限制
+func FindConnectRecords(filter *ConnectRecordFilter, limit int) ([]*ConnectRecord, error) {
+	var rows []SshConnectRecord
+
+	query := filter.apply(db.Model(&SshConnectRecord{})).Order("`time` desc, `id` desc")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	err := query.Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*ConnectRecord, 0, len(rows))
+	for _, r := range rows {
+		var timeConsuming *int64 = nil
+		if r.TimeConsuming.Valid {
+			timeConsuming = &r.TimeConsuming.Int64
+		}
+
+		res = append(res, &ConnectRecord{
+			ID:            r.ID,
+			Time:          r.Time,
+			From:          r.From,
+			To:            r.To,
+			Accept:        r.Accept,
+			TimeConsuming: timeConsuming,
+			Mark:          r.Mark,
+		})
+	}
+
+	return res, nil
+}
+
+// ConnectCount 按来访 IP 或转发目标分组的连接数
+type ConnectCount struct {
+	Value    string `json:"value"`
+	Total    int64  `json:"total"`
+	Accepted int64  `json:"accepted"`
+	Rejected int64  `json:"rejected"`
+}
+
+// HourlyCount 每小时的连接数
+type HourlyCount struct {
+	Hour     time.Time `json:"hour"`
+	Total    int64     `json:"total"`
+	Accepted int64     `json:"accepted"`
+	Rejected int64     `json:"rejected"`
+}
+
+// ConnectReport 连接记录的统计
+type ConnectReport struct {
+	Total           int64           `json:"total"`
+	Accepted        int64           `json:"accepted"`
+	Rejected        int64           `json:"rejected"`
+	AcceptRatio     float64         `json:"accept_ratio"` // 没有记录时为 0
+	TopRejectedIPs  []*ConnectCount `json:"top_rejected_ips"`
+	TopDestinations []*ConnectCount `json:"top_destinations"`
+	Hourly          []*HourlyCount  `json:"hourly"` // 从第一条到最后一条记录，没有连接的小时也会列出
+}
+
+const countColumns = "COUNT(*) AS total, " +
+	"SUM(CASE WHEN `accept` THEN 1 ELSE 0 END) AS accepted, " +
+	"SUM(CASE WHEN `accept` THEN 0 ELSE 1 END) AS rejected"
+
+// ReportConnectRecords 统计连接记录：接受率、被拒绝最多的 IP、连接最多的转发目标以及每小时的连接数（按 loc 时区划分小时）
+func ReportConnectRecords(filter *ConnectRecordFilter, top int, loc *time.Location) (*ConnectReport, error) {
+	res := ConnectReport{
+		TopRejectedIPs:  make([]*ConnectCount, 0, top),
+		TopDestinations: make([]*ConnectCount, 0, top),
+	}
+
+	var total ConnectCount
+	err := filter.apply(db.Model(&SshConnectRecord{})).Select(countColumns).Scan(&total).Error
+	if err != nil {
+		return nil, err
+	}
+
+	res.Total = total.Total
+	res.Accepted = total.Accepted
+	res.Rejected = total.Rejected
+	if res.Total > 0 {
+		res.AcceptRatio = float64(res.Accepted) / float64(res.Total)
+	}
+
+	err = filter.apply(db.Model(&SshConnectRecord{})).
+		Select("`from` AS value, " + countColumns).
+		Group("`from`").Having("rejected > 0").Order("rejected desc, value asc").Limit(top).
+		Scan(&res.TopRejectedIPs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = filter.apply(db.Model(&SshConnectRecord{})).
+		Select("`to` AS value, " + countColumns).
+		Group("`to`").Order("total desc, value asc").Limit(top).
+		Scan(&res.TopDestinations).Error
+	if err != nil {
+		return nil, err
+	}
+
+	res.Hourly, err = hourlyConnectRecords(filter, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// hourlyConnectRecords 时区可能不是整小时偏移，因此在程序中按小时划分，而不是使用 SQLite 的 strftime
+func hourlyConnectRecords(filter *ConnectRecordFilter, loc *time.Location) ([]*HourlyCount, error) {
+	rows, err := filter.apply(db.Model(&SshConnectRecord{})).Select("`time`, `accept`").Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	hours := make(map[int64]*HourlyCount, 24)
+	for rows.Next() {
+		var t sql.NullTime
+		var accept bool
+
+		err = rows.Scan(&t, &accept)
+		if err != nil {
+			return nil, err
+		} else if !t.Valid {
+			continue
+		}
+
+		local := t.Time.In(loc)
+		hour := time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), 0, 0, 0, loc)
+
+		h, ok := hours[hour.Unix()]
+		if !ok {
+			h = &HourlyCount{Hour: hour}
+			hours[hour.Unix()] = h
+		}
+
+		h.Total++
+		if accept {
+			h.Accepted++
+		} else {
+			h.Rejected++
+		}
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*HourlyCount, 0, len(hours))
+	for _, h := range hours {
+		res = append(res, h)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Hour.Before(res[j].Hour)
+	})
+
+	if len(res) <= 1 {
+		return res, nil
+	}
+
+	// 补全没有连接的小时
+	full := make([]*HourlyCount, 0, len(res))
+	for _, h := range res {
+		for len(full) != 0 {
+			next := full[len(full)-1].Hour.Add(time.Hour)
+			if !next.Before(h.Hour) {
+				break
+			}
+			full = append(full, &HourlyCount{Hour: next})
+		}
+		full = append(full, h)
+	}
+
+	return full, nil
+}
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// 配置的时区与本地时区不同时，查询条件与记录的时间应按同一时刻比较
+func TestConnectRecordFilterTimeZone(t *testing.T) {
+	oldLocal := time.Local
+	time.Local = time.FixedZone("UTC-5", -5*3600)
+	defer func() {
+		time.Local = oldLocal
+	}()
+
+	openTestDB(t)
+
+	at := time.Date(2025, 2, 16, 8, 0, 0, 0, time.UTC)
+	to := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 22}
+
+	_, err := AddSshConnectRecord("", net.IPv4(192, 0, 2, 1), to, true, at, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = AddSshConnectRecord("", net.IPv4(192, 0, 2, 2), to, false, at.Add(2*time.Hour), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zone := time.FixedZone("UTC+8", 8*3600)
+	since := at.Add(-time.Minute).In(zone)
+	until := at.Add(time.Minute).In(zone)
+
+	records, err := FindConnectRecords(&ConnectRecordFilter{Since: &since, Until: &until}, 0)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(records) != 1 || records[0].From != "192.0.2.1" {
+		t.Fatalf("FindConnectRecords = %v, want the record of 192.0.2.1", records)
+	}
+
+	report, err := ReportConnectRecords(&ConnectRecordFilter{Since: &since}, 10, zone)
+	if err != nil {
+		t.Fatal(err)
+	} else if report.Total != 2 || report.Accepted != 1 || report.Rejected != 1 {
+		t.Errorf("report total/accepted/rejected = %d/%d/%d, want 2/1/1", report.Total, report.Accepted, report.Rejected)
+	} else if len(report.Hourly) != 3 || !report.Hourly[0].Hour.Equal(at) {
+		t.Errorf("hourly = %v, want 3 hours from %s", report.Hourly, at)
+	}
+
+	after := at.Add(-time.Hour).In(zone)
+	res, err := FindSshConnectRecord("", net.IPv4(192, 0, 2, 1), to, 10, after)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(res) != 1 {
+		t.Errorf("FindSshConnectRecord = %d records, want 1", len(res))
+	}
+}
+
+// 封禁的时间在其他时区解析时，应按同一时刻判断是否生效
+func TestBanTimeZone(t *testing.T) {
+	oldLocal := time.Local
+	time.Local = time.FixedZone("UTC-5", -5*3600)
+	defer func() {
+		time.Local = oldLocal
+	}()
+
+	openTestDB(t)
+
+	zone := time.FixedZone("UTC+8", 8*3600)
+	stopAt := time.Now().Add(-time.Minute).In(zone) // 已结束，但在 +08:00 下字符串更大
+
+	_, err := AddBan(BanScopeTCP, BanTargetCIDR, "10.9.0.0/16", nil, &stopAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bans, err := ListBans(BanScopeTCP, BanTargetCIDR, false)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(bans) != 0 {
+		t.Errorf("ListBans = %d bans, want 0 (ban has stopped)", len(bans))
+	}
+
+	if banned, _ := checkCIDRBanned(BanScopeTCP, "10.9.0.1"); banned {
+		t.Error("10.9.0.1 is banned by a stopped ban")
+	}
+}
@@ -0,0 +1,65 @@
+package flagparser
+
+import "fmt"
+
+const (
+	CommandCheck    = "check"
+	CommandSimulate = "simulate"
+	CommandBan      = "ban"
+	CommandUnban    = "unban"
+	CommandBans     = "bans"
+	CommandReport   = "report"
+)
+
+type commandInfo struct {
+	name  string
+	usage string
+}
+
+// commands 支持的子命令，例如：huanspringboard --config config.yaml check
+var commands = []commandInfo{
+	{
+		name:  CommandCheck,
+		usage: "Check the configuration file and print all errors and warnings with their YAML path, then exit. The exit code is non-zero if there is any error. Same as the --check option.",
+	},
+	{
+		name:  CommandSimulate,
+		usage: "Run the rule check of an IP in dry-run mode and print the verdict, the matched rule and why the other rules were skipped. Usage: simulate [--ssh] [--port port] [--nation nation] [--province province] [--city city] [--isp isp] [--json] ip",
+	},
+	{
+		name:  CommandBan,
+		usage: "Add a ban to the database (or to redis for a ssh ip with --redis). The target is ip, cidr, nation, province, city or isp. Usage: ban [--scope tcp|ssh|all] [--start time] [--duration duration | --until time] [--redis] [--json] target value",
+	},
+	{
+		name:  CommandUnban,
+		usage: "Stop the active and pending bans of a target in the database, a ssh ip is also removed from redis. Usage: unban [--scope tcp|ssh|all] [--json] target value",
+	},
+	{
+		name:  CommandBans,
+		usage: "List the bans in the database and the ssh ip bans in redis. Usage: bans list [--scope tcp|ssh|all] [--target target] [--all] [--json]",
+	},
+	{
+		name:  CommandReport,
+		usage: "List the ssh connection records, or summarize them with the accept ratio, the top rejected ips, the top destinations and the hourly histogram. Usage: report [--ip ip] [--to address] [--since time] [--until time] [--accept true|false] [--limit n] [--top n] [--format table|csv|json] list|summary",
+	},
+}
+
+func checkCommand(command string, args []string) error {
+	if command == "" {
+		return nil
+	}
+
+	for _, c := range commands {
+		if c.name != command {
+			continue
+		}
+
+		if command == CommandCheck && len(args) != 0 {
+			return fmt.Errorf("command %s takes no arguments, put the options before the command", command)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("unknown command: %s", command)
+}
@@ -49,6 +49,24 @@ type flagData struct {
 	NotAutoReloadShortName string
 	NotAutoReloadUsage     string
 
+	CheckData      bool
+	CheckName      string
+	CheckShortName string
+	CheckUsage     string
+
+	StateDirData      string
+	StateDirName      string
+	StateDirShortName string
+	StateDirUsage     string
+
+	FallbackLastGoodData      bool
+	FallbackLastGoodName      string
+	FallbackLastGoodShortName string
+	FallbackLastGoodUsage     string
+
+	command     string
+	commandArgs []string
+
 	Usage string
 }
 
@@ -88,6 +106,21 @@ func initData() {
 		NotAutoReloadShortName: "",
 		NotAutoReloadUsage:     fmt.Sprintf("%s", "Disable automatic detection of configuration file changes and reloading of system programs. This feature is enabled by default. This feature consumes a certain amount of performance. If your performance is not enough, you can choose to disable it."),
 
+		CheckData:      false,
+		CheckName:      "check",
+		CheckShortName: "",
+		CheckUsage:     fmt.Sprintf("%s", "Check the configuration file and print all errors and warnings with their YAML path, then exit. The exit code is non-zero if there is any error. No socket is listened and Redis/SQLite are not connected. Same as the check command."),
+
+		StateDirData:      "",
+		StateDirName:      "state-dir",
+		StateDirShortName: "",
+		StateDirUsage:     fmt.Sprintf("%s", "The directory where the last successfully applied configuration (last-known-good) is saved. The snapshot is updated after the service starts and after each successful reload. The option is a string, empty (the default) disables the snapshot."),
+
+		FallbackLastGoodData:      false,
+		FallbackLastGoodName:      "fallback-last-good",
+		FallbackLastGoodShortName: "",
+		FallbackLastGoodUsage:     fmt.Sprintf("%s", "When the configuration file fails validation at startup, start with the last-known-good configuration saved in --state-dir instead of refusing to start, and send a config-rollback notification."),
+
 		Usage: "",
 	}
 
@@ -236,6 +269,18 @@ func (d *flagData) writeUsage() {
 		result.WriteString("\n\n")
 	}
 
+	result.WriteString(utils.FormatTextToWidth(fmt.Sprintf("Commands of %s (after the options):", utils.GetArgs0Name()), utils.NormalConsoleWidth))
+	result.WriteString("\n")
+
+	for _, c := range commands {
+		result.WriteString(fmt.Sprintf("%s%s", OptionIdent, utils.FormatTextToWidth(c.name, utils.NormalConsoleWidth-len(OptionIdent))))
+		result.WriteString("\n")
+
+		usegae := utils.FormatTextToWidthAndPrefix(c.usage, UsagePrefixWidth, utils.NormalConsoleWidth)
+		result.WriteString(usegae)
+		result.WriteString("\n\n")
+	}
+
 	d.Usage = strings.TrimRight(result.String(), "\n")
 }
 
@@ -264,6 +309,12 @@ func (d *flagData) setFlag() {
 
 	flag.BoolVar(&d.NotAutoReloadData, data.NotAutoReloadName, data.NotAutoReloadData, data.NotAutoReloadUsage)
 
+	flag.BoolVar(&d.CheckData, data.CheckName, data.CheckData, data.CheckUsage)
+
+	flag.StringVar(&d.StateDirData, data.StateDirName, data.StateDirData, data.StateDirUsage)
+
+	flag.BoolVar(&d.FallbackLastGoodData, data.FallbackLastGoodName, data.FallbackLastGoodData, data.FallbackLastGoodUsage)
+
 	flag.Usage = func() {
 		_, _ = d.PrintUsage()
 	}
@@ -282,6 +333,12 @@ func (d *flagData) parser() {
 
 	flag.Parse()
 
+	// 第一个非选项参数为子命令，其余参数交给子命令处理
+	if flag.NArg() > 0 {
+		d.command = flag.Arg(0)
+		d.commandArgs = flag.Args()[1:]
+	}
+
 	d.setDefault()
 	d.flagParser = true
 }
@@ -290,7 +347,7 @@ func (d *flagData) setDefault() {
 	if d.ConfigFileData == "" {
 		d.ConfigFileData = "config.yaml"
 
-		if d.OutputConfigFileData == "" {
+		if d.OutputConfigFileData == "" && d.command == "" { // 子命令不输出配置文件
 			d.OutputConfigFileData = "config.output.yaml"
 		}
 	}
@@ -412,6 +469,46 @@ func (d *flagData) OutputConfigFile() string {
 	return d.OutputConfigFileData
 }
 
+func (d *flagData) Check() bool {
+	if !d.isReady() {
+		panic("flag not ready")
+	}
+
+	return d.CheckData
+}
+
+func (d *flagData) StateDir() string {
+	if !d.isReady() {
+		panic("flag not ready")
+	}
+
+	return d.StateDirData
+}
+
+func (d *flagData) FallbackLastGood() bool {
+	if !d.isReady() {
+		panic("flag not ready")
+	}
+
+	return d.FallbackLastGoodData
+}
+
+func (d *flagData) Command() string {
+	if !d.isReady() {
+		panic("flag not ready")
+	}
+
+	return d.command
+}
+
+func (d *flagData) CommandArgs() []string {
+	if !d.isReady() {
+		panic("flag not ready")
+	}
+
+	return d.commandArgs
+}
+
 func (d *flagData) SetOutput(writer io.Writer) {
 	flag.CommandLine.SetOutput(writer)
 }
@@ -74,6 +74,11 @@ func InitFlag() (err error) {
 }
 
 func checkFlag() error {
+	err := checkCommand(Command(), CommandArgs())
+	if err != nil {
+		return err
+	}
+
 	if !utils.IsExists(ConfigFile()) {
 		return fmt.Errorf("config file not exists")
 	}
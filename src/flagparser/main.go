@@ -110,6 +110,27 @@ func RunAutoReload() bool {
 	return !NotRunAutoReload()
 }
 
+func StateDir() string {
+	return data.StateDir()
+}
+
+func FallbackLastGood() bool {
+	return data.FallbackLastGood()
+}
+
+func Command() string {
+	return data.Command()
+}
+
+func CommandArgs() []string {
+	return data.CommandArgs()
+}
+
+// CheckMode 只检查配置文件（--check 选项或 check 子命令）
+func CheckMode() bool {
+	return data.Check() || data.Command() == CommandCheck
+}
+
 func SetOutput(writer io.Writer) {
 	data.SetOutput(writer)
 }
@@ -0,0 +1,148 @@
+package forward
+
+import (
+	"context"
+	"fmt"
+	"github.com/SongZihuan/huan-springboard/src/config"
+	"github.com/SongZihuan/huan-springboard/src/logger"
+	"github.com/SongZihuan/huan-springboard/src/resolver"
+	"github.com/pires/go-proxyproto"
+	"net"
+	"time"
+)
+
+// Dial 按连接选项连接目标：主机名的全部地址（以及备用协议族的地址）按 Happy Eyeballs 的方式连接；
+// 透明代理时以来访地址 remote 的 IP 为源地址连接目标，协议族不同时返回错误（remote 为 nil 时不使用透明代理）
+func (l *Listener) Dial(remote *net.TCPAddr) (conn net.Conn, err error) {
+	if target, ok := l.Target.(*resolver.Addr); ok {
+		addrs := target.TCPAddrs()
+		if l.fallback != nil {
+			addrs = resolver.Interleave(addrs, l.fallback.TCPAddrs())
+		}
+
+		dialer := l.upstream.Dialer
+		if l.transparent && remote != nil {
+			if (l.targetNetwork == "tcp4") != (remote.IP.To4() != nil) {
+				// 例如 unix socket 监听时 PROXY 协议头中的来访地址与目标的协议族不同，不能降级为普通连接
+				return nil, fmt.Errorf("transparent proxy can not connect to a %s dest from client %s", l.targetNetwork, remote.IP.String())
+			}
+
+			dialer = func(network string) *net.Dialer {
+				return l.upstream.TransparentDialer(network, remote)
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), l.upstream.ConnectTimeout()) // 全部尝试共用一个超时
+		defer cancel()
+
+		conn, err = resolver.DialHappyEyeballs(ctx, dialer, addrs)
+	} else {
+		conn, err = l.upstream.Dialer(l.targetNetwork).Dial(l.targetNetwork, l.Target.String())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if tc, ok := conn.(*net.TCPConn); ok && !l.upstream.NoDelay.IsEnable(true) {
+		_ = tc.SetNoDelay(false) // Go 默认开启 TCP_NODELAY
+	}
+
+	return conn, nil
+}
+
+// WriteProxyHeader 开启了 dest-proxy 时向目标写入来访连接 conn 的 PROXY 协议头
+func (l *Listener) WriteProxyHeader(target net.Conn, conn net.Conn) error {
+	if !l.destProxy {
+		return nil
+	}
+
+	header := ProxyHeader(byte(l.destProxyVersion), conn.RemoteAddr(), conn.LocalAddr(), target.RemoteAddr())
+	_, err := header.WriteTo(target)
+	return err
+}
+
+// ProxyHeader 生成转发到目标时的 PROXY 协议头：目标为 unix socket 时，目标地址使用来访连接的本地地址（或来源 PROXY 协议头中的目标地址）；
+// 来访地址与目标地址无法组成同一类型的协议头时（例如本地进程通过 unix socket 连接后转发到 TCP，
+// 或者来访地址与目标地址为不同协议族的 TCP 地址），v1 使用 UNKNOWN，v2 使用 LOCAL
+func ProxyHeader(version byte, remoteAddr net.Addr, localAddr net.Addr, targetAddr net.Addr) *proxyproto.Header {
+	if remote, ok := remoteAddr.(*net.TCPAddr); ok {
+		switch target := targetAddr.(type) {
+		case *net.UnixAddr:
+			targetAddr = localAddr
+		case *net.TCPAddr:
+			if (remote.IP.To4() == nil) != (target.IP.To4() == nil) {
+				targetAddr = nil // 跨协议族转发（交叉回源或者 Happy Eyeballs 连接到了备用协议族）时无法表示来访地址
+			}
+		}
+	}
+
+	return proxyproto.HeaderProxyFromAddrs(version, remoteAddr, targetAddr)
+}
+
+// Refresh 定期重新解析目标主机名，解析失败时保留上一次成功解析的地址，stopchan 关闭时返回
+func Refresh(stopchan chan bool, targets []*resolver.Target, log *logger.Entry) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopchan:
+			return
+		case <-ticker.C:
+			// pass
+		}
+
+		for _, target := range targets {
+			res := target.RefreshIfExpired()
+			if res == nil {
+				continue
+			} else if res.Err != nil {
+				log.Warnf("resolve dest %s failed, keep the last addresses %v: %s", target.String(), res.Old, res.Err.Error())
+			} else if res.Changed() {
+				log.Infof("dest %s resolved to %v (was %v)", target.String(), res.New, res.Old)
+			}
+		}
+	}
+}
+
+// AccessRecord 创建访问日志记录，来源使用 PROXY 协议时 Client 为代理的地址，Original 为协议头中的来访地址
+func AccessRecord(component string, conn net.Conn, port int64, targetAddr net.Addr, now time.Time) *logger.AccessRecord {
+	res := &logger.AccessRecord{
+		Component: component,
+		Port:      port,
+		Backend:   targetAddr.String(),
+		Start:     now,
+	}
+
+	if pc, ok := conn.(*proxyproto.Conn); ok {
+		res.Client = pc.Raw().RemoteAddr().String()
+		if pc.ProxyHeader() != nil {
+			res.Original = pc.RemoteAddr().String()
+		}
+	} else {
+		res.Client = conn.RemoteAddr().String()
+	}
+
+	return res
+}
+
+// Log 返回带有组件、端口和来访地址字段的日志，port 为 0 时使用转发的端口（或端口范围）
+func Log(component string, src config.PortRange, port int64, remoteAddr string) *logger.Entry {
+	fields := logger.Fields{
+		logger.FieldComponent: component,
+		logger.FieldPort:      src.Value(),
+	}
+
+	if port != 0 {
+		fields[logger.FieldPort] = port
+	}
+
+	if remoteAddr != "" {
+		if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+			remoteAddr = host
+		}
+		fields[logger.FieldRemoteIP] = remoteAddr
+	}
+
+	return logger.With(fields)
+}
@@ -0,0 +1,285 @@
+package forward
+
+import (
+	"fmt"
+	"github.com/SongZihuan/huan-springboard/src/config"
+	"github.com/SongZihuan/huan-springboard/src/ipcheck"
+	"github.com/SongZihuan/huan-springboard/src/resolver"
+	"github.com/pires/go-proxyproto"
+	"net"
+	"os"
+)
+
+// Settings 一个转发的监听和连接目标的选项（tcp 转发和 ssh 转发共用）
+type Settings struct {
+	Src      config.PortRange
+	IPv4Dest func(port int64) *resolver.Addr // 监听端口对应的 ipv4 目标地址，没有时返回 nil
+	IPv6Dest func(port int64) *resolver.Addr // 监听端口对应的 ipv6 目标地址，没有时返回 nil
+	UnixDest *net.UnixAddr                   // 转发到 unix socket，nil 表示不使用
+	Targets  []*resolver.Target              // 需要定期重新解析的目标
+
+	CrossFallback bool // 另一协议族的目标地址作为备用地址
+	Cross         bool // 没有同协议族的目标地址时转发到另一协议族
+
+	IPv4SrcProxy bool
+	IPv6SrcProxy bool
+	UnixSrcProxy bool
+
+	IPv4DestProxy        bool
+	IPv4DestProxyVersion int
+	IPv6DestProxy        bool
+	IPv6DestProxyVersion int
+	UnixDestProxy        bool
+	UnixDestProxyVersion int
+
+	Upstream    *config.UpstreamConfig
+	Transparent bool
+}
+
+func TcpSettings(c *config.TcpForwardConfig) *Settings {
+	return &Settings{
+		Src:                  c.Src,
+		IPv4Dest:             c.IPv4Dest,
+		IPv6Dest:             c.IPv6Dest,
+		UnixDest:             c.ResolveUnixDestAddress,
+		Targets:              targets(c.IPv4DestTarget, c.IPv6DestTarget),
+		CrossFallback:        c.CrossFallback.IsEnable(false),
+		Cross:                c.Cross,
+		IPv4SrcProxy:         c.IPv4SrcServerProxy.IsEnable(true),
+		IPv6SrcProxy:         c.IPv6SrcServerProxy.IsEnable(true),
+		UnixSrcProxy:         c.UnixSrcServerProxy.IsEnable(false),
+		IPv4DestProxy:        c.IPv4DestRequestProxy.IsEnable(true),
+		IPv4DestProxyVersion: c.IPv4DestRequestProxyVersion,
+		IPv6DestProxy:        c.IPv6DestRequestProxy.IsEnable(true),
+		IPv6DestProxyVersion: c.IPv6DestRequestProxyVersion,
+		UnixDestProxy:        c.UnixDestRequestProxy.IsEnable(true),
+		UnixDestProxyVersion: c.UnixDestRequestProxyVersion,
+		Upstream:             &c.Upstream,
+		Transparent:          c.Transparent.IsEnable(false),
+	}
+}
+
+func SshSettings(c *config.SshForwardConfig) *Settings {
+	return &Settings{
+		Src:                  c.Src,
+		IPv4Dest:             c.IPv4Dest,
+		IPv6Dest:             c.IPv6Dest,
+		UnixDest:             c.ResolveUnixDestAddress,
+		Targets:              targets(c.IPv4DestTarget, c.IPv6DestTarget),
+		CrossFallback:        c.CrossFallback.IsEnable(false),
+		Cross:                c.Cross,
+		IPv4SrcProxy:         c.IPv4SrcServerProxy.IsEnable(true),
+		IPv6SrcProxy:         c.IPv6SrcServerProxy.IsEnable(true),
+		UnixSrcProxy:         c.UnixSrcServerProxy.IsEnable(false),
+		IPv4DestProxy:        c.IPv4DestRequestProxy.IsEnable(true),
+		IPv4DestProxyVersion: c.IPv4DestRequestProxyVersion,
+		IPv6DestProxy:        c.IPv6DestRequestProxy.IsEnable(true),
+		IPv6DestProxyVersion: c.IPv6DestRequestProxyVersion,
+		UnixDestProxy:        c.UnixDestRequestProxy.IsEnable(true),
+		UnixDestProxyVersion: c.UnixDestRequestProxyVersion,
+		Upstream:             &c.Upstream,
+		Transparent:          c.Transparent.IsEnable(false),
+	}
+}
+
+func targets(list ...*resolver.Target) []*resolver.Target {
+	res := make([]*resolver.Target, 0, len(list))
+	for _, t := range list {
+		if t != nil {
+			res = append(res, t)
+		}
+	}
+	return res
+}
+
+// Listener 一个端口、一个协议族（或者一个 unix socket）的监听
+type Listener struct {
+	Ln      net.Listener
+	Port    int64
+	Network string   // tcp4、tcp6 或 unix
+	Cross   bool     // 转发到另一协议族
+	Target  net.Addr // 目标地址：*resolver.Addr 或者 *net.UnixAddr
+
+	fallback         *resolver.Addr // 备用协议族的目标地址，与 Target 一起按 Happy Eyeballs 的方式连接，nil 表示不使用
+	targetNetwork    string
+	destProxy        bool
+	destProxyVersion int
+	upstream         *config.UpstreamConfig // 连接目标的选项
+	transparent      bool                   // 透明代理：以来访 IP 为源地址连接 ipv4、ipv6 目标
+}
+
+// Listen 按监听地址监听：unix socket，或者端口范围中的每个端口、每个协议族各一个监听；任一监听失败时关闭已经打开的监听
+func Listen(s *Settings, bind *config.ListenBind) (lns []*Listener, err error) {
+	lns = make([]*Listener, 0, 2*s.Src.Count())
+	defer func() {
+		if err != nil {
+			for _, l := range lns {
+				_ = l.Ln.Close()
+			}
+		}
+	}()
+
+	add := func(l *Listener, listenErr error) {
+		if listenErr != nil {
+			err = listenErr
+		} else if l != nil {
+			lns = append(lns, l)
+		}
+	}
+
+	if bind.Unix != nil {
+		add(s.listenUnix(bind.Unix))
+	}
+
+	for port := s.Src.First; err == nil && bind.Unix == nil && port <= s.Src.Last; port++ {
+		if ipcheck.SupportIPv4() && bind.IPv4 != nil {
+			add(s.listen("tcp4", bind.IPv4, port))
+		}
+
+		if err == nil && ipcheck.SupportIPv6() && bind.IPv6 != nil {
+			add(s.listen("tcp6", bind.IPv6, port))
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	} else if len(lns) == 0 {
+		return nil, fmt.Errorf("no listen address")
+	}
+
+	return lns, nil
+}
+
+// listen 监听一个端口的一个协议族，没有可用的目标地址时返回 nil
+func (s *Settings) listen(network string, bindAddr *net.TCPAddr, port int64) (*Listener, error) {
+	var target, crossTarget *resolver.Addr
+	var srcProxy, destProxy bool
+	var destProxyVersion int
+	var crossNetwork string
+
+	if network == "tcp4" {
+		target, crossTarget, crossNetwork = s.IPv4Dest(port), s.IPv6Dest(port), "tcp6"
+		srcProxy, destProxy, destProxyVersion = s.IPv4SrcProxy, s.IPv4DestProxy, s.IPv4DestProxyVersion
+	} else {
+		target, crossTarget, crossNetwork = s.IPv6Dest(port), s.IPv4Dest(port), "tcp4"
+		srcProxy, destProxy, destProxyVersion = s.IPv6SrcProxy, s.IPv6DestProxy, s.IPv6DestProxyVersion
+	}
+
+	res := &Listener{
+		Port:        port,
+		Network:     network,
+		upstream:    s.Upstream,
+		transparent: s.Transparent,
+	}
+
+	if target != nil {
+		res.Target = target
+		res.targetNetwork = network
+		res.destProxy = destProxy
+		res.destProxyVersion = destProxyVersion
+
+		if s.CrossFallback && crossTarget != nil {
+			res.fallback = crossTarget
+		}
+	} else if s.UnixDest != nil {
+		res.Target = s.UnixDest
+		res.targetNetwork = "unix"
+		res.destProxy = destProxy
+		res.destProxyVersion = destProxyVersion
+	} else if s.Cross && crossTarget != nil {
+		// 交叉转发时不使用 PROXY 协议
+		srcProxy = false
+		res.Cross = true
+		res.Target = crossTarget
+		res.targetNetwork = crossNetwork
+	} else {
+		return nil, nil
+	}
+
+	addr := &net.TCPAddr{IP: bindAddr.IP, Port: int(port), Zone: bindAddr.Zone}
+	ln, err := net.ListenTCP(network, addr)
+	if err != nil {
+		return nil, fmt.Errorf("listen %s on %s failed: %s", addr.String(), network, err.Error())
+	}
+
+	if srcProxy {
+		res.Ln = &proxyproto.Listener{
+			Listener: ln,
+		}
+	} else {
+		res.Ln = ln
+	}
+
+	return res, nil
+}
+
+// listenUnix 监听 unix socket，优先转发到 unix socket 目标，其次为 ipv4、ipv6 目标（两者都有且开启了 cross-fallback 时按 Happy Eyeballs 的方式连接）
+func (s *Settings) listenUnix(bindAddr *net.UnixAddr) (*Listener, error) {
+	res := &Listener{
+		Network:          "unix",
+		upstream:         s.Upstream,
+		transparent:      s.Transparent,
+		destProxy:        s.UnixDestProxy,
+		destProxyVersion: s.UnixDestProxyVersion,
+	}
+
+	if s.UnixDest != nil {
+		res.Target, res.targetNetwork = s.UnixDest, "unix"
+	} else if target := s.IPv4Dest(s.Src.First); target != nil {
+		res.Target, res.targetNetwork = target, "tcp4"
+		if s.CrossFallback {
+			res.fallback = s.IPv6Dest(s.Src.First)
+		}
+	} else if target := s.IPv6Dest(s.Src.First); target != nil {
+		res.Target, res.targetNetwork = target, "tcp6"
+	} else {
+		return nil, nil
+	}
+
+	err := removeStaleSocket(bindAddr.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	ln, err := net.ListenUnix("unix", bindAddr)
+	if err != nil {
+		return nil, fmt.Errorf("listen %s on unix failed: %s", bindAddr.Name, err.Error())
+	}
+
+	if s.UnixSrcProxy {
+		res.Ln = &proxyproto.Listener{
+			Listener: ln,
+		}
+	} else {
+		res.Ln = ln
+	}
+
+	return res, nil
+}
+
+// removeStaleSocket 删除上次运行遗留的 socket 文件，socket 仍有进程在监听时返回错误
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil
+	} else if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("listen %s on unix failed: file exists and is not a socket", path)
+	}
+
+	if conn, err := net.Dial("unix", path); err == nil {
+		_ = conn.Close()
+		return fmt.Errorf("listen %s on unix failed: address already in use", path)
+	}
+
+	return os.Remove(path)
+}
+
+// RemoteTCPAddr 来访地址：unix socket 监听时为 PROXY 协议中的来访地址，本地进程直接连接时为 nil
+func (l *Listener) RemoteTCPAddr(remoteAddr net.Addr) (*net.TCPAddr, error) {
+	if l.Network == "unix" {
+		res, _ := remoteAddr.(*net.TCPAddr)
+		return res, nil
+	}
+
+	return net.ResolveTCPAddr(l.Network, remoteAddr.String())
+}
@@ -0,0 +1,165 @@
+package forward
+
+import (
+	"fmt"
+	"github.com/SongZihuan/huan-springboard/src/config"
+	"github.com/SongZihuan/huan-springboard/src/ipcheck"
+	"github.com/SongZihuan/huan-springboard/src/resolver"
+	"github.com/pires/go-proxyproto"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// freePort 返回一个当前空闲的本地端口
+func freePort(t *testing.T) int64 {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = ln.Close()
+	}()
+	return int64(ln.Addr().(*net.TCPAddr).Port)
+}
+
+func testSettings(t *testing.T, src config.PortRange) *Settings {
+	target, err := resolver.NewTarget("tcp4", "127.0.0.1", 0, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Settings{
+		Src:      src,
+		IPv4Dest: func(port int64) *resolver.Addr { return target.Addr(int(port)) },
+		IPv6Dest: func(port int64) *resolver.Addr { return nil },
+		Upstream: new(config.UpstreamConfig),
+	}
+}
+
+// 端口范围中的某个端口监听失败时，已经打开的监听全部关闭
+func TestListenCloseOnError(t *testing.T) {
+	if !ipcheck.SupportIPv4() {
+		t.Skip("ipv4 is not supported")
+	}
+
+	first := freePort(t)
+	busy, err := net.Listen("tcp4", fmt.Sprintf("127.0.0.1:%d", first+1))
+	if err != nil {
+		t.Skipf("port %d is in use", first+1)
+	}
+	defer func() {
+		_ = busy.Close()
+	}()
+	last := first + 1
+
+	bind := &config.ListenBind{IPv4: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)}}
+	src := config.PortRange{First: first, Last: first}
+
+	lns, err := Listen(testSettings(t, src), bind)
+	if err != nil {
+		t.Fatalf("listen %d: %s", first, err.Error())
+	} else if len(lns) != 1 || lns[0].Port != first || lns[0].Network != "tcp4" {
+		t.Fatalf("listen %d: got %d listeners", first, len(lns))
+	}
+	_ = lns[0].Ln.Close()
+
+	src.Last = last
+	_, err = Listen(testSettings(t, src), bind)
+	if err == nil {
+		t.Fatalf("listen %d-%d: expected error", first, last)
+	}
+
+	ln, err := net.Listen("tcp4", fmt.Sprintf("127.0.0.1:%d", first))
+	if err != nil {
+		t.Fatalf("port %d is not released: %s", first, err.Error())
+	}
+	_ = ln.Close()
+}
+
+// 来访地址与目标地址无法组成同一类型的协议头时使用 LOCAL
+func TestProxyHeader(t *testing.T) {
+	remote4 := &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 1234}
+	remote6 := &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 1234}
+	local4 := &net.TCPAddr{IP: net.IPv4(192, 0, 2, 2), Port: 22}
+	target4 := &net.TCPAddr{IP: net.IPv4(192, 0, 2, 3), Port: 22}
+	target6 := &net.TCPAddr{IP: net.ParseIP("2001:db8::3"), Port: 22}
+	unix := &net.UnixAddr{Name: "/run/app.sock", Net: "unix"}
+
+	tests := []struct {
+		remote  net.Addr
+		target  net.Addr
+		command proxyproto.ProtocolVersionAndCommand
+		dest    net.Addr
+	}{
+		{remote4, target4, proxyproto.PROXY, target4},
+		{remote4, unix, proxyproto.PROXY, local4},
+		{remote6, target4, proxyproto.LOCAL, nil},
+		{remote4, target6, proxyproto.LOCAL, nil},
+		{unix, target4, proxyproto.LOCAL, nil},
+	}
+
+	for _, test := range tests {
+		header := ProxyHeader(2, test.remote, local4, test.target)
+		if header.Command != test.command {
+			t.Errorf("%s -> %s: command %v, want %v", test.remote, test.target, header.Command, test.command)
+		} else if test.dest != nil && header.DestinationAddr.String() != test.dest.String() {
+			t.Errorf("%s -> %s: dest %s, want %s", test.remote, test.target, header.DestinationAddr, test.dest)
+		}
+	}
+}
+
+// unix socket 监听：只有开启了 unix-src-proxy 时才使用 PROXY 协议头中的来访地址（随后按该地址检查访问规则）
+func TestUnixRemoteTCPAddr(t *testing.T) {
+	client := &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 1234}
+	header := ProxyHeader(1, client, &net.TCPAddr{IP: net.IPv4(192, 0, 2, 2), Port: 80}, &net.TCPAddr{IP: net.IPv4(192, 0, 2, 3), Port: 80})
+
+	tests := []struct {
+		srcProxy   bool
+		sendHeader bool
+		want       *net.TCPAddr
+	}{
+		{false, false, nil},
+		{false, true, nil},
+		{true, false, nil},
+		{true, true, client},
+	}
+
+	for _, test := range tests {
+		path := filepath.Join(t.TempDir(), "app.sock")
+		s := testSettings(t, config.PortRange{})
+		s.UnixSrcProxy = test.srcProxy
+
+		lns, err := Listen(s, &config.ListenBind{Unix: &net.UnixAddr{Name: path, Net: "unix"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		l := lns[0]
+
+		conn, err := net.Dial("unix", path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.sendHeader {
+			_, _ = header.WriteTo(conn)
+		}
+		_, _ = conn.Write([]byte("x"))
+
+		accepted, err := l.Ln.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := l.RemoteTCPAddr(accepted.RemoteAddr())
+		if err != nil {
+			t.Errorf("src proxy %v, header %v: %s", test.srcProxy, test.sendHeader, err.Error())
+		} else if (got == nil) != (test.want == nil) || (got != nil && got.String() != test.want.String()) {
+			t.Errorf("src proxy %v, header %v: remote %v, want %v", test.srcProxy, test.sendHeader, got, test.want)
+		}
+
+		_ = accepted.Close()
+		_ = conn.Close()
+		_ = l.Ln.Close()
+	}
+}
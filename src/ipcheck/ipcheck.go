@@ -28,6 +28,16 @@ func SupportIPv4() bool {
 	return ip4Ok
 }
 
+// AssumeDualStack 不进行网络探测，直接认为同时支持 IPv4 和 IPv6（用于配置检查），需要在第一次调用 SupportIPv4/SupportIPv6 之前调用
+func AssumeDualStack() {
+	ip4Once.Do(func() {
+		ip4Ok = true
+	})
+	ip6Once.Do(func() {
+		ip6Ok = true
+	})
+}
+
 func checkSupportIPv4() bool {
 	ip4, err := net.ResolveIPAddr("ip4", ip4Str)
 	if err != nil {
@@ -0,0 +1,184 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/SongZihuan/huan-springboard/src/config"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 访问日志中的处理结果
+const (
+	AccessAccept = "accept" // 允许并完成转发
+	AccessReject = "reject" // 被规则拒绝
+	AccessError  = "error"  // 允许但未能建立转发（例如无法连接后端）
+)
+
+// 访问日志中的关闭原因
+const (
+	CloseClient = "client-closed"  // 客户端先关闭
+	CloseTarget = "backend-closed" // 后端先关闭
+	CloseServer = "server-stopped" // 服务停止时被关闭
+)
+
+// AccessRecord 一个连接的访问记录，在连接结束时写入
+type AccessRecord struct {
+	Component string    // tcp/ssh
+	Port      int64     // 转发的源端口
+	Client    string    // 客户端地址（TCP 对端地址）
+	Original  string    // PROXY 协议头中的原始来访地址，没有时为空
+	Backend   string    // 后端地址
+	Decision  string    // accept/reject/error
+	Rule      string    // 命中的规则
+	Start     time.Time // 连接建立时间
+	BytesIn   int64     // 客户端发往后端的字节数
+	BytesOut  int64     // 后端发往客户端的字节数
+	Reason    string    // 关闭原因或拒绝原因
+}
+
+type accessLogger struct {
+	console    bool
+	format     string
+	writer     io.Writer
+	file       *RotateWriter
+	fileFormat string
+}
+
+var globalAccessLogger *accessLogger = nil
+
+func initAccessLogger(writer io.Writer) error {
+	accessConfig := config.GetConfig().Log.Access
+	if accessConfig.Enable.IsDisable(true) {
+		globalAccessLogger = nil
+		return nil
+	}
+
+	res := &accessLogger{
+		console:    accessConfig.Console.IsEnable(true),
+		format:     accessConfig.Format,
+		writer:     writer,
+		fileFormat: accessConfig.File.Format,
+	}
+
+	if accessConfig.File.Path != "" {
+		file, err := NewRotateWriter(&accessConfig.File)
+		if err != nil {
+			return err
+		}
+		res.file = file
+	}
+
+	globalAccessLogger = res
+	return nil
+}
+
+func closeAccessLogger() {
+	if globalAccessLogger == nil || globalAccessLogger.file == nil {
+		return
+	}
+
+	_ = globalAccessLogger.file.Close()
+}
+
+// WriteAccess 写入一条访问日志，未启用访问日志时忽略
+func WriteAccess(r *AccessRecord) {
+	a := globalAccessLogger
+	if a == nil || r == nil {
+		return
+	}
+
+	end := time.Now()
+
+	if a.console {
+		_, _ = a.writer.Write(formatAccess(a.format, r, end))
+	}
+
+	if a.file != nil {
+		_, _ = a.file.Write(formatAccess(a.fileFormat, r, end))
+	}
+}
+
+func formatAccess(format string, r *AccessRecord, end time.Time) []byte {
+	if format == config.LogFormatJSON {
+		return formatAccessJSON(r, end)
+	}
+	return formatAccessText(r, end)
+}
+
+// formatAccessText 类似 combined 的格式：
+// client original - [start] "TCP port backend" decision bytes_in bytes_out duration "rule" "reason"
+func formatAccessText(r *AccessRecord, end time.Time) []byte {
+	var buf bytes.Buffer
+
+	_, _ = fmt.Fprintf(&buf, "%s %s - [%s] \"%s %d %s\" %s %d %d %.3f %s %s\n",
+		accessField(r.Client),
+		accessField(r.Original),
+		r.Start.In(config.TimeZone()).Format("02/Jan/2006:15:04:05 -0700"),
+		strings.ToUpper(r.Component),
+		r.Port,
+		accessField(r.Backend),
+		accessField(r.Decision),
+		r.BytesIn,
+		r.BytesOut,
+		end.Sub(r.Start).Seconds(),
+		strconv.Quote(r.Rule),
+		strconv.Quote(r.Reason))
+
+	return buf.Bytes()
+}
+
+func formatAccessJSON(r *AccessRecord, end time.Time) []byte {
+	data, err := json.Marshal(struct {
+		Time       string `json:"time"`
+		Component  string `json:"component"`
+		Port       int64  `json:"port"`
+		Client     string `json:"client"`
+		Original   string `json:"original,omitempty"`
+		Backend    string `json:"backend"`
+		Decision   string `json:"decision"`
+		Rule       string `json:"rule"`
+		Start      string `json:"start"`
+		DurationMS int64  `json:"duration_ms"`
+		BytesIn    int64  `json:"bytes_in"`
+		BytesOut   int64  `json:"bytes_out"`
+		Reason     string `json:"reason"`
+	}{
+		Time:       end.In(config.TimeZone()).Format(time.RFC3339Nano),
+		Component:  r.Component,
+		Port:       r.Port,
+		Client:     r.Client,
+		Original:   r.Original,
+		Backend:    r.Backend,
+		Decision:   r.Decision,
+		Rule:       r.Rule,
+		Start:      r.Start.In(config.TimeZone()).Format(time.RFC3339Nano),
+		DurationMS: end.Sub(r.Start).Milliseconds(),
+		BytesIn:    r.BytesIn,
+		BytesOut:   r.BytesOut,
+		Reason:     r.Reason,
+	})
+	if err != nil {
+		return nil
+	}
+
+	return append(data, '\n')
+}
+
+// CloseReason 返回连接的关闭原因，err 不为空时附加错误信息
+func CloseReason(side string, err error) string {
+	if err == nil {
+		return side
+	}
+	return fmt.Sprintf("%s: %s", side, err.Error())
+}
+
+func accessField(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,79 @@
+package logger
+
+import (
+	"fmt"
+)
+
+// 常用字段名称
+const (
+	FieldComponent = "component" // 组件，例如 tcp、ssh、notify
+	FieldPort      = "port"      // 转发的源端口
+	FieldRemoteIP  = "remote_ip" // 来访IP
+	FieldReason    = "reason"    // 原因
+)
+
+type Fields map[string]any
+
+// Entry 带有结构化字段的日志，文本格式中以 key=value 附加在消息后，JSON 格式中作为独立字段
+type Entry struct {
+	logger *Logger
+	fields Fields
+}
+
+func (l *Logger) With(fields Fields) *Entry {
+	return &Entry{
+		logger: l,
+		fields: fields,
+	}
+}
+
+// With 返回带有字段的新 Entry，字段与已有字段合并
+func (e *Entry) With(fields Fields) *Entry {
+	res := make(Fields, len(e.fields)+len(fields))
+	for k, v := range e.fields {
+		res[k] = v
+	}
+	for k, v := range fields {
+		res[k] = v
+	}
+
+	return &Entry{
+		logger: e.logger,
+		fields: res,
+	}
+}
+
+func (e *Entry) Debugf(format string, args ...interface{}) {
+	if e.logger == nil || !e.logger.enabled(levelDebug) {
+		return
+	}
+	e.logger.output(kindDebug, e.fields, fmt.Sprintf(format, args...))
+}
+
+func (e *Entry) Infof(format string, args ...interface{}) {
+	if e.logger == nil || !e.logger.enabled(levelInfo) {
+		return
+	}
+	e.logger.output(kindInfo, e.fields, fmt.Sprintf(format, args...))
+}
+
+func (e *Entry) Warnf(format string, args ...interface{}) {
+	if e.logger == nil || !e.logger.enabled(levelWarn) {
+		return
+	}
+	e.logger.output(kindWarn, e.fields, fmt.Sprintf(format, args...))
+}
+
+func (e *Entry) Errorf(format string, args ...interface{}) {
+	if e.logger == nil || !e.logger.enabled(levelError) {
+		return
+	}
+	e.logger.output(kindError, e.fields, fmt.Sprintf(format, args...))
+}
+
+func (e *Entry) Panicf(format string, args ...interface{}) {
+	if e.logger == nil || !e.logger.enabled(levelPanic) {
+		return
+	}
+	e.logger.output(kindPanic, e.fields, fmt.Sprintf(format, args...))
+}
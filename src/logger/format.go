@@ -0,0 +1,102 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/SongZihuan/huan-springboard/src/config"
+	"sort"
+	"time"
+)
+
+type logKind struct {
+	text     string // 文本格式中的标签
+	level    string // JSON 格式中的 level 字段
+	severity string // syslog/journald 严重性按哪个日志级别的配置取值
+	err      bool   // 控制台输出到 errWriter
+}
+
+var (
+	kindExecutable = logKind{text: "Executable", level: "executable", severity: "info"}
+	kindTag        = logKind{text: "Tag", level: "tag", severity: "debug"}
+	kindDebug      = logKind{text: "Debug", level: "debug", severity: "debug"}
+	kindInfo       = logKind{text: "Info", level: "info", severity: "info"}
+	kindWarn       = logKind{text: "Warning", level: "warn", severity: "warn"}
+	kindError      = logKind{text: "Error", level: "error", severity: "error", err: true}
+	kindPanic      = logKind{text: "Panic", level: "panic", severity: "panic", err: true}
+)
+
+// formatText 文本格式：[Info name]: msg key=value，withTime 为真时在行首添加时间
+func formatText(kind logKind, name string, t time.Time, fields Fields, msg string, withTime bool) []byte {
+	var buf bytes.Buffer
+
+	if withTime {
+		buf.WriteString(t.In(config.TimeZone()).Format(time.DateTime))
+		buf.WriteString(" ")
+	}
+
+	_, _ = fmt.Fprintf(&buf, "[%s %s]: %s", kind.text, name, msg)
+
+	for _, k := range sortedKeys(fields) {
+		_, _ = fmt.Fprintf(&buf, " %s=%v", k, fields[k])
+	}
+
+	buf.WriteString("\n")
+	return buf.Bytes()
+}
+
+// formatJSON JSON 格式，每行一个对象，固定字段在前，其余字段按名称排序
+func formatJSON(kind logKind, name string, t time.Time, fields Fields, msg string) []byte {
+	var buf bytes.Buffer
+
+	buf.WriteString(`{"time":`)
+	writeJSONValue(&buf, t.In(config.TimeZone()).Format(time.RFC3339Nano))
+	buf.WriteString(`,"level":`)
+	writeJSONValue(&buf, kind.level)
+	buf.WriteString(`,"app":`)
+	writeJSONValue(&buf, name)
+	buf.WriteString(`,"msg":`)
+	writeJSONValue(&buf, msg)
+
+	for _, k := range sortedKeys(fields) {
+		switch k {
+		case "time", "level", "app", "msg":
+			continue
+		}
+
+		buf.WriteString(",")
+		writeJSONValue(&buf, k)
+		buf.WriteString(":")
+		writeJSONValue(&buf, fields[k])
+	}
+
+	buf.WriteString("}\n")
+	return buf.Bytes()
+}
+
+func writeJSONValue(buf *bytes.Buffer, v any) {
+	if err, ok := v.(error); ok {
+		v = err.Error()
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprint(v))
+	}
+
+	buf.Write(data)
+}
+
+func sortedKeys(fields Fields) []string {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	res := make([]string, 0, len(fields))
+	for k := range fields {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+
+	return res
+}
@@ -0,0 +1,122 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/SongZihuan/huan-springboard/src/config"
+	"github.com/SongZihuan/huan-springboard/src/utils"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// journaldMaxMessage MESSAGE 字段的最大长度，避免超过数据报大小
+const journaldMaxMessage = 64 * 1024
+
+// journaldTarget 使用 journald 原生协议输出，日志字段作为独立的 journald 字段（例如 COMPONENT、REMOTE_IP）
+type journaldTarget struct {
+	lock  sync.Mutex
+	state targetState
+
+	addr       *net.UnixAddr
+	identifier string
+	conn       *net.UnixConn
+}
+
+func newJournaldTarget(c *config.LogJournaldConfig) (*journaldTarget, error) {
+	_, err := os.Stat(c.Socket)
+	if err != nil {
+		return nil, fmt.Errorf("journald socket %s not found: %s", c.Socket, err.Error())
+	}
+
+	return &journaldTarget{
+		state:      targetState{name: "journald"},
+		addr:       &net.UnixAddr{Name: c.Socket, Net: "unixgram"},
+		identifier: c.Identifier,
+	}, nil
+}
+
+func (j *journaldTarget) write(severity int, kind logKind, t time.Time, fields Fields, msg string) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	var buf bytes.Buffer
+
+	journaldField(&buf, "MESSAGE", utils.TruncateUTF8Ellipsis(msg, journaldMaxMessage))
+	journaldField(&buf, "PRIORITY", strconv.Itoa(severity))
+	journaldField(&buf, "SYSLOG_IDENTIFIER", j.identifier)
+	journaldField(&buf, "SYSLOG_PID", strconv.Itoa(os.Getpid()))
+	journaldField(&buf, "LOG_LEVEL", kind.level)
+
+	for _, k := range sortedKeys(fields) {
+		name := upperFieldName(k)
+		switch name {
+		case "", "MESSAGE", "PRIORITY", "SYSLOG_IDENTIFIER", "SYSLOG_PID", "LOG_LEVEL":
+			continue
+		}
+
+		v := fields[k]
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		journaldField(&buf, name, fmt.Sprint(v))
+	}
+
+	err := j.send(buf.Bytes())
+	if err != nil {
+		// journald 重启后需要重新连接
+		err = j.send(buf.Bytes())
+	}
+
+	j.state.report(err)
+}
+
+func (j *journaldTarget) send(data []byte) error {
+	if j.conn == nil {
+		conn, err := net.DialUnix("unixgram", nil, j.addr)
+		if err != nil {
+			return err
+		}
+		j.conn = conn
+	}
+
+	_, err := j.conn.Write(data)
+	if err != nil {
+		_ = j.conn.Close()
+		j.conn = nil
+		return err
+	}
+
+	return nil
+}
+
+func (j *journaldTarget) close() {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	if j.conn != nil {
+		_ = j.conn.Close()
+		j.conn = nil
+	}
+}
+
+// journaldField 写入一个字段，值中包含换行时使用二进制格式：名称、换行、64位小端长度、值、换行
+func journaldField(buf *bytes.Buffer, name string, value string) {
+	if !strings.Contains(value, "\n") {
+		buf.WriteString(name)
+		buf.WriteString("=")
+		buf.WriteString(value)
+		buf.WriteString("\n")
+		return
+	}
+
+	buf.WriteString(name)
+	buf.WriteString("\n")
+	_ = binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value)
+	buf.WriteString("\n")
+}
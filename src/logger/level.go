@@ -0,0 +1,118 @@
+package logger
+
+import (
+	"fmt"
+	"github.com/SongZihuan/huan-springboard/src/config"
+	"strings"
+)
+
+// levelOrder 从最详细到最简略
+var levelOrder = []LoggerLevel{LevelDebug, LevelInfo, LevelWarn, LevelError, LevelPanic, LevelNone}
+
+func (l *Logger) enabled(level loggerLevel) bool {
+	return loggerLevel(l.logLevel.Load()) <= level
+}
+
+func (l *Logger) Level() LoggerLevel {
+	current := loggerLevel(l.logLevel.Load())
+	for name, level := range levelMap {
+		if level == current {
+			return name
+		}
+	}
+	return LevelNone
+}
+
+func (l *Logger) SetLevel(level LoggerLevel) error {
+	logLevel, ok := levelMap[LoggerLevel(strings.ToLower(string(level)))]
+	if !ok {
+		return fmt.Errorf("invalid log level: %s", level)
+	}
+
+	l.logLevel.Store(int64(logLevel))
+	return nil
+}
+
+func (l *Logger) LogTag() bool {
+	return l.logTag.Load()
+}
+
+func (l *Logger) SetLogTag(logTag bool) {
+	l.logTag.Store(logTag)
+}
+
+// StepLevel 按 debug/info/warn/error/panic/none 的顺序调整日志级别，step 为负数表示更详细，到达两端后不再变化
+func (l *Logger) StepLevel(step int) LoggerLevel {
+	for {
+		old := l.logLevel.Load()
+
+		index := 0
+		for i, name := range levelOrder {
+			if int64(levelMap[name]) == old {
+				index = i
+				break
+			}
+		}
+
+		index = min(max(index+step, 0), len(levelOrder)-1)
+		res := levelOrder[index]
+
+		if l.logLevel.CompareAndSwap(old, int64(levelMap[res])) {
+			return res
+		}
+	}
+}
+
+func GetLevel() LoggerLevel {
+	if !IsReady() {
+		return LevelNone
+	}
+	return globalLogger.Level()
+}
+
+func GetLogTag() bool {
+	if !IsReady() {
+		return false
+	}
+	return globalLogger.LogTag()
+}
+
+// Update 修改日志级别和标签（为 nil 表示不修改），source 为修改来源，发生变化时记录一条日志
+func Update(source string, level *LoggerLevel, logTag *bool) error {
+	if !IsReady() {
+		return fmt.Errorf("logger is not ready")
+	}
+
+	oldLevel, oldTag := globalLogger.Level(), globalLogger.LogTag()
+
+	if level != nil {
+		err := globalLogger.SetLevel(*level)
+		if err != nil {
+			return err
+		}
+	}
+
+	if logTag != nil {
+		globalLogger.SetLogTag(*logTag)
+	}
+
+	globalLogger.levelChanged(source, oldLevel, oldTag)
+	return nil
+}
+
+// ApplyConfig 按当前配置重新设置日志级别和标签，配置重新加载后调用（会覆盖运行时的修改）
+func ApplyConfig() error {
+	level := LoggerLevel(config.GetConfig().GlobalConfig.LogLevel)
+	logTag := config.GetConfig().LogTag.ToBool(true)
+	return Update("config reload", &level, &logTag)
+}
+
+// levelChanged 级别或标签发生变化时记录日志，不经过级别检查，保证级别调高后仍能看到这条日志
+func (l *Logger) levelChanged(source string, oldLevel LoggerLevel, oldTag bool) {
+	level, logTag := l.Level(), l.LogTag()
+	if level == oldLevel && logTag == oldTag {
+		return
+	}
+
+	l.output(kindWarn, Fields{"source": source}, fmt.Sprintf("log level changed: %s -> %s, tag: %v -> %v", oldLevel, level, oldTag, logTag))
+}
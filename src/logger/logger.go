@@ -7,6 +7,8 @@ import (
 	"github.com/mattn/go-isatty"
 	"io"
 	"os"
+	"sync/atomic"
+	"time"
 )
 
 type LoggerLevel string
@@ -41,11 +43,15 @@ var levelMap = map[LoggerLevel]loggerLevel{
 }
 
 type Logger struct {
-	level      LoggerLevel
-	logLevel   loggerLevel
-	logTag     bool
+	logLevel   atomic.Int64 // loggerLevel，可在运行时修改
+	logTag     atomic.Bool
 	warnWriter io.Writer
 	errWriter  io.Writer
+	console    bool
+	format     string
+	file       *RotateWriter
+	fileFormat string
+	targets    []logTarget // syslog、journald
 	args0      string
 	args0Name  string
 }
@@ -73,16 +79,46 @@ func InitLogger(warnWriter, errWriter io.Writer) error {
 		errWriter = DefaultErrorWriter
 	}
 
+	logConfig := config.GetConfig().Log
+
 	logger := &Logger{
-		level:      level,
-		logLevel:   logLevel,
-		logTag:     config.GetConfig().LogTag.ToBool(true),
-		warnWriter: os.Stdout,
-		errWriter:  os.Stderr,
+		warnWriter: warnWriter,
+		errWriter:  errWriter,
+		console:    logConfig.Console.IsEnable(true),
+		format:     logConfig.Format,
+		fileFormat: logConfig.File.Format,
 		args0:      utils.GetArgs0(),
 		args0Name:  utils.GetArgs0Name(),
 	}
 
+	logger.logLevel.Store(int64(logLevel))
+	logger.logTag.Store(config.GetConfig().LogTag.ToBool(true))
+
+	if logConfig.File.Path != "" {
+		file, err := NewRotateWriter(&logConfig.File)
+		if err != nil {
+			return err
+		}
+		logger.file = file
+	}
+
+	if logConfig.Syslog.Enable.IsEnable(false) {
+		logger.targets = append(logger.targets, newSyslogTarget(&logConfig.Syslog))
+	}
+
+	if logConfig.Journald.Enable.IsEnable(false) {
+		journald, err := newJournaldTarget(&logConfig.Journald)
+		if err != nil {
+			return err
+		}
+		logger.targets = append(logger.targets, journald)
+	}
+
+	err := initAccessLogger(warnWriter)
+	if err != nil {
+		return err
+	}
+
 	globalLogger = logger
 	return nil
 }
@@ -91,12 +127,65 @@ func IsReady() bool {
 	return globalLogger != nil
 }
 
+// CloseLogger 关闭日志文件（包括访问日志）和 syslog、journald 连接
+func CloseLogger() {
+	closeAccessLogger()
+
+	if !IsReady() {
+		return
+	}
+
+	for _, t := range globalLogger.targets {
+		t.close()
+	}
+
+	if globalLogger.file != nil {
+		_ = globalLogger.file.Close()
+	}
+}
+
+func (l *Logger) output(kind logKind, fields Fields, msg string) {
+	l.outputAs(kind, l.args0Name, fields, msg)
+}
+
+func (l *Logger) outputAs(kind logKind, name string, fields Fields, msg string) {
+	now := time.Now()
+
+	if l.console {
+		w := l.warnWriter
+		if kind.err {
+			w = l.errWriter
+		}
+
+		if l.format == config.LogFormatJSON {
+			_, _ = w.Write(formatJSON(kind, name, now, fields, msg))
+		} else {
+			_, _ = w.Write(formatText(kind, name, now, fields, msg, false))
+		}
+	}
+
+	if l.file != nil {
+		if l.fileFormat == config.LogFormatJSON {
+			_, _ = l.file.Write(formatJSON(kind, name, now, fields, msg))
+		} else {
+			_, _ = l.file.Write(formatText(kind, name, now, fields, msg, true))
+		}
+	}
+
+	if len(l.targets) > 0 {
+		severity := config.GetConfig().Log.Severity.Severity(kind.severity)
+		for _, t := range l.targets {
+			t.write(severity, kind, now, fields, msg)
+		}
+	}
+}
+
 func (l *Logger) Executablef(format string, args ...interface{}) string {
 	str := fmt.Sprintf(format, args...)
 	if str == "" {
-		_, _ = fmt.Fprintf(l.warnWriter, "[Executable]: %s\n", l.args0)
+		l.outputAs(kindExecutable, l.args0, nil, "")
 	} else {
-		_, _ = fmt.Fprintf(l.warnWriter, "[Executable %s]: %s\n", l.args0, str)
+		l.outputAs(kindExecutable, l.args0, nil, str)
 	}
 	return l.args0
 }
@@ -106,59 +195,54 @@ func (l *Logger) Tagf(format string, args ...interface{}) {
 }
 
 func (l *Logger) TagSkipf(skip int, format string, args ...interface{}) {
-	if !l.logTag {
+	if !l.logTag.Load() {
 		return
 	}
 
 	funcName, file, _, line := utils.GetCallingFunctionInfo(skip + 1)
 
 	str := fmt.Sprintf(format, args...)
-	_, _ = fmt.Fprintf(l.warnWriter, "[Tag %s]: %s %s %s:%d\n", l.args0Name, str, funcName, file, line)
+	l.output(kindTag, Fields{"func": funcName, "file": fmt.Sprintf("%s:%d", file, line)}, str)
 }
 
 func (l *Logger) Debugf(format string, args ...interface{}) {
-	if l.logLevel > levelDebug {
+	if !l.enabled(levelDebug) {
 		return
 	}
 
-	str := fmt.Sprintf(format, args...)
-	_, _ = fmt.Fprintf(l.warnWriter, "[Debug %s]: %s\n", l.args0Name, str)
+	l.output(kindDebug, nil, fmt.Sprintf(format, args...))
 }
 
 func (l *Logger) Infof(format string, args ...interface{}) {
-	if l.logLevel > levelInfo {
+	if !l.enabled(levelInfo) {
 		return
 	}
 
-	str := fmt.Sprintf(format, args...)
-	_, _ = fmt.Fprintf(l.warnWriter, "[Info %s]: %s\n", l.args0Name, str)
+	l.output(kindInfo, nil, fmt.Sprintf(format, args...))
 }
 
 func (l *Logger) Warnf(format string, args ...interface{}) {
-	if l.logLevel > levelWarn {
+	if !l.enabled(levelWarn) {
 		return
 	}
 
-	str := fmt.Sprintf(format, args...)
-	_, _ = fmt.Fprintf(l.warnWriter, "[Warning %s]: %s\n", l.args0Name, str)
+	l.output(kindWarn, nil, fmt.Sprintf(format, args...))
 }
 
 func (l *Logger) Errorf(format string, args ...interface{}) {
-	if l.logLevel > levelError {
+	if !l.enabled(levelError) {
 		return
 	}
 
-	str := fmt.Sprintf(format, args...)
-	_, _ = fmt.Fprintf(l.errWriter, "[Error %s]: %s\n", l.args0Name, str)
+	l.output(kindError, nil, fmt.Sprintf(format, args...))
 }
 
 func (l *Logger) Panicf(format string, args ...interface{}) {
-	if l.logLevel > levelPanic {
+	if !l.enabled(levelPanic) {
 		return
 	}
 
-	str := fmt.Sprintf(format, args...)
-	_, _ = fmt.Fprintf(l.errWriter, "[Panic %s]: %s\n", l.args0Name, str)
+	l.output(kindPanic, nil, fmt.Sprintf(format, args...))
 }
 
 func (l *Logger) Tag(args ...interface{}) {
@@ -166,59 +250,54 @@ func (l *Logger) Tag(args ...interface{}) {
 }
 
 func (l *Logger) TagSkip(skip int, args ...interface{}) {
-	if !l.logTag {
+	if !l.logTag.Load() {
 		return
 	}
 
 	funcName, file, _, line := utils.GetCallingFunctionInfo(skip + 1)
 
 	str := fmt.Sprint(args...)
-	_, _ = fmt.Fprintf(l.warnWriter, "[Tag %s]: %s %s %s:%d\n", l.args0Name, str, funcName, file, line)
+	l.output(kindTag, Fields{"func": funcName, "file": fmt.Sprintf("%s:%d", file, line)}, str)
 }
 
 func (l *Logger) Debug(args ...interface{}) {
-	if l.logLevel > levelDebug {
+	if !l.enabled(levelDebug) {
 		return
 	}
 
-	str := fmt.Sprint(args...)
-	_, _ = fmt.Fprintf(l.warnWriter, "[Debug %s]: %s\n", l.args0Name, str)
+	l.output(kindDebug, nil, fmt.Sprint(args...))
 }
 
 func (l *Logger) Info(args ...interface{}) {
-	if l.logLevel > levelInfo {
+	if !l.enabled(levelInfo) {
 		return
 	}
 
-	str := fmt.Sprint(args...)
-	_, _ = fmt.Fprintf(l.warnWriter, "[Info %s]: %s\n", l.args0Name, str)
+	l.output(kindInfo, nil, fmt.Sprint(args...))
 }
 
 func (l *Logger) Warn(args ...interface{}) {
-	if l.logLevel > levelWarn {
+	if !l.enabled(levelWarn) {
 		return
 	}
 
-	str := fmt.Sprint(args...)
-	_, _ = fmt.Fprintf(l.warnWriter, "[Warning %s]: %s\n", l.args0Name, str)
+	l.output(kindWarn, nil, fmt.Sprint(args...))
 }
 
 func (l *Logger) Error(args ...interface{}) {
-	if l.logLevel > levelError {
+	if !l.enabled(levelError) {
 		return
 	}
 
-	str := fmt.Sprint(args...)
-	_, _ = fmt.Fprintf(l.errWriter, "[Error %s]: %s\n", l.args0Name, str)
+	l.output(kindError, nil, fmt.Sprint(args...))
 }
 
 func (l *Logger) Panic(args ...interface{}) {
-	if l.logLevel > levelPanic {
+	if !l.enabled(levelPanic) {
 		return
 	}
 
-	str := fmt.Sprint(args...)
-	_, _ = fmt.Fprintf(l.errWriter, "[Panic %s]: %s\n", l.args0Name, str)
+	l.output(kindPanic, nil, fmt.Sprint(args...))
 }
 
 func (l *Logger) TagWrite(msg string) {
@@ -226,53 +305,53 @@ func (l *Logger) TagWrite(msg string) {
 }
 
 func (l *Logger) TagSkipWrite(skip int, msg string) {
-	if !l.logTag {
+	if !l.logTag.Load() {
 		return
 	}
 
 	funcName, file, _, line := utils.GetCallingFunctionInfo(skip + 1)
 
-	_, _ = fmt.Fprintf(l.warnWriter, "[Debug %s]: %s %s %s:%d\n", l.args0Name, msg, funcName, file, line)
+	l.output(kindTag, Fields{"func": funcName, "file": fmt.Sprintf("%s:%d", file, line)}, msg)
 }
 
 func (l *Logger) DebugWrite(msg string) {
-	if l.logLevel > levelDebug {
+	if !l.enabled(levelDebug) {
 		return
 	}
 
-	_, _ = fmt.Fprintf(l.warnWriter, "[Debug %s]: %s\n", l.args0Name, msg)
+	l.output(kindDebug, nil, msg)
 }
 
 func (l *Logger) InfoWrite(msg string) {
-	if l.logLevel > levelInfo {
+	if !l.enabled(levelInfo) {
 		return
 	}
 
-	_, _ = fmt.Fprintf(l.warnWriter, "[Info %s]: %s\n", l.args0Name, msg)
+	l.output(kindInfo, nil, msg)
 }
 
 func (l *Logger) WarnWrite(msg string) {
-	if l.logLevel > levelWarn {
+	if !l.enabled(levelWarn) {
 		return
 	}
 
-	_, _ = fmt.Fprintf(l.warnWriter, "[Warning %s]: %s\n", l.args0Name, msg)
+	l.output(kindWarn, nil, msg)
 }
 
 func (l *Logger) ErrorWrite(msg string) {
-	if l.logLevel > levelError {
+	if !l.enabled(levelError) {
 		return
 	}
 
-	_, _ = fmt.Fprintf(l.errWriter, "[Error %s]: %s\n", l.args0Name, msg)
+	l.output(kindError, nil, msg)
 }
 
 func (l *Logger) PanicWrite(msg string) {
-	if l.logLevel > levelPanic {
+	if !l.enabled(levelPanic) {
 		return
 	}
 
-	_, _ = fmt.Fprintf(l.errWriter, "[Panic %s]: %s\n", l.args0Name, msg)
+	l.output(kindPanic, nil, msg)
 }
 
 func (l *Logger) GetDebugWriter() io.Writer {
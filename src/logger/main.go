@@ -11,6 +11,14 @@ func Executablef(format string, args ...interface{}) string {
 	return globalLogger.Executablef(format, args...)
 }
 
+// With 返回带有结构化字段的日志
+func With(fields Fields) *Entry {
+	if !IsReady() {
+		return &Entry{}
+	}
+	return globalLogger.With(fields)
+}
+
 func Tagf(format string, args ...interface{}) {
 	if !IsReady() {
 		return
@@ -0,0 +1,270 @@
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"github.com/SongZihuan/huan-springboard/src/config"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const rotateTimeFormat = "20060102-150405"
+
+// RotateWriter 按大小和时间轮转的日志文件，历史文件按数量和时间清理，可选 gzip 压缩
+type RotateWriter struct {
+	lock sync.Mutex
+	mill sync.Mutex // 串行化压缩和清理
+
+	path       string
+	maxSize    int64         // 单位：字节，0 表示不按大小轮转
+	interval   time.Duration // 0 表示不按时间轮转
+	maxBackups int           // 负数表示不限制
+	maxAge     time.Duration // 负数表示不限制
+	compress   bool
+
+	file   *os.File
+	size   int64
+	period time.Time // 当前文件所属的时间周期
+}
+
+func NewRotateWriter(c *config.LogFileConfig) (*RotateWriter, error) {
+	w := &RotateWriter{
+		path:       c.Path,
+		maxBackups: int(c.MaxBackups),
+		maxAge:     time.Duration(c.MaxAgeDays) * 24 * time.Hour,
+		compress:   c.Compress.IsEnable(true),
+	}
+
+	if c.MaxSizeMB > 0 {
+		w.maxSize = c.MaxSizeMB * 1024 * 1024
+	}
+
+	if c.RotateHours > 0 {
+		w.interval = time.Duration(c.RotateHours) * time.Hour
+	}
+
+	err := w.open()
+	if err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func (w *RotateWriter) Write(p []byte) (n int, err error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.file == nil {
+		err = w.open()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if w.needRotate(int64(len(p))) {
+		err = w.rotate()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n, err = w.file.Write(p)
+	w.size += int64(n)
+
+	return n, err
+}
+
+func (w *RotateWriter) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+func (w *RotateWriter) open() error {
+	err := os.MkdirAll(filepath.Dir(w.path), 0755)
+	if err != nil {
+		return fmt.Errorf("create log dir error: %s", err.Error())
+	}
+
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return fmt.Errorf("open log file error: %s", err.Error())
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("stat log file error: %s", err.Error())
+	}
+
+	w.file = file
+	w.size = info.Size()
+
+	if w.size > 0 {
+		w.period = w.periodStart(info.ModTime())
+	} else {
+		w.period = w.periodStart(time.Now())
+	}
+
+	return nil
+}
+
+func (w *RotateWriter) needRotate(n int64) bool {
+	if w.maxSize > 0 && w.size > 0 && w.size+n > w.maxSize {
+		return true
+	}
+
+	if w.interval > 0 && !w.periodStart(time.Now()).Equal(w.period) {
+		return true
+	}
+
+	return false
+}
+
+// periodStart 返回 t 所在时间周期的开始时间（按配置的时区对齐，例如每天零点）
+func (w *RotateWriter) periodStart(t time.Time) time.Time {
+	if w.interval <= 0 {
+		return time.Time{}
+	}
+
+	t = t.In(config.TimeZone())
+	_, offset := t.Zone()
+	shift := time.Duration(offset) * time.Second
+
+	return t.Add(shift).Truncate(w.interval).Add(-shift)
+}
+
+func (w *RotateWriter) rotate() error {
+	if w.file != nil {
+		_ = w.file.Close()
+		w.file = nil
+	}
+
+	backup := w.path + "." + time.Now().In(config.TimeZone()).Format(rotateTimeFormat)
+	for i := 1; fileExists(backup) || fileExists(backup+".gz"); i++ {
+		backup = fmt.Sprintf("%s.%s-%d", w.path, time.Now().In(config.TimeZone()).Format(rotateTimeFormat), i)
+	}
+
+	err := os.Rename(w.path, backup)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("rotate log file error: %s", err.Error())
+	}
+
+	err = w.open()
+	if err != nil {
+		return err
+	}
+
+	go w.millRun(backup)
+	return nil
+}
+
+// millRun 压缩刚轮转的文件并清理过期的历史文件
+func (w *RotateWriter) millRun(backup string) {
+	w.mill.Lock()
+	defer w.mill.Unlock()
+
+	if w.compress {
+		err := compressFile(backup)
+		if err != nil {
+			_, _ = fmt.Fprintf(DefaultErrorWriter, "compress log file %s error: %s\n", backup, err.Error())
+		}
+	}
+
+	w.cleanBackups()
+}
+
+func (w *RotateWriter) cleanBackups() {
+	if w.maxBackups < 0 && w.maxAge < 0 {
+		return
+	}
+
+	matches, err := filepath.Glob(w.path + ".*")
+	if err != nil {
+		return
+	}
+
+	type backupFile struct {
+		path    string
+		modTime time.Time
+	}
+
+	backups := make([]backupFile, 0, len(matches))
+	for _, m := range matches {
+		suffix := strings.TrimSuffix(strings.TrimPrefix(m, w.path+"."), ".gz")
+		if len(suffix) < len(rotateTimeFormat) {
+			continue
+		}
+
+		if _, err := time.Parse(rotateTimeFormat, suffix[:len(rotateTimeFormat)]); err != nil {
+			continue
+		}
+
+		info, err := os.Stat(m)
+		if err != nil {
+			continue
+		}
+
+		backups = append(backups, backupFile{path: m, modTime: info.ModTime()})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].modTime.After(backups[j].modTime)
+	})
+
+	now := time.Now()
+	for i, b := range backups {
+		if (w.maxBackups >= 0 && i >= w.maxBackups) || (w.maxAge >= 0 && now.Sub(b.modTime) > w.maxAge) {
+			_ = os.Remove(b.path)
+		}
+	}
+}
+
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = src.Close()
+	}()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0640)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(dst)
+	_, err = io.Copy(gz, src)
+	if err == nil {
+		err = gz.Close()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		_ = os.Remove(path + ".gz")
+		return err
+	}
+
+	return os.Remove(path)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package logger
+
+// WatchLevelSignal 当前系统不支持 SIGUSR1/SIGUSR2，不做任何处理
+func WatchLevelSignal() (stop func()) {
+	return func() {}
+}
@@ -0,0 +1,43 @@
+//go:build unix
+
+package logger
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// WatchLevelSignal 监听 SIGUSR1（日志更详细，级别降低一级）和 SIGUSR2（日志更简略，级别提高一级），返回停止监听的函数
+func WatchLevelSignal() (stop func()) {
+	sigchan := make(chan os.Signal, 1)
+	stopchan := make(chan bool)
+	signal.Notify(sigchan, syscall.SIGUSR1, syscall.SIGUSR2)
+
+	go func() {
+		for {
+			select {
+			case <-stopchan:
+				return
+			case sig := <-sigchan:
+				step, source := 1, "SIGUSR2"
+				if sig == syscall.SIGUSR1 {
+					step, source = -1, "SIGUSR1"
+				}
+
+				if !IsReady() {
+					continue
+				}
+
+				oldLevel, oldTag := globalLogger.Level(), globalLogger.LogTag()
+				globalLogger.StepLevel(step)
+				globalLogger.levelChanged(source, oldLevel, oldTag)
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(sigchan)
+		close(stopchan)
+	}
+}
@@ -0,0 +1,198 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/SongZihuan/huan-springboard/src/config"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const syslogTimeout = 5 * time.Second
+
+// syslogSDID 结构化数据的 SD-ID，32473 是 RFC 5612 中保留给文档示例的企业号
+const syslogSDID = "fields@32473"
+
+// syslogTarget 以 RFC 5424 格式输出到 syslog，流式连接（tcp、unix stream）使用 RFC 6587 的长度前缀分帧
+type syslogTarget struct {
+	lock  sync.Mutex
+	state targetState
+
+	network  string
+	address  string
+	facility int
+	appName  string
+	hostname string
+
+	conn   net.Conn
+	stream bool
+}
+
+func newSyslogTarget(c *config.LogSyslogConfig) *syslogTarget {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+
+	res := &syslogTarget{
+		state:    targetState{name: "syslog"},
+		network:  c.Network,
+		address:  c.Address,
+		facility: c.ResolveFacility,
+		appName:  c.AppName,
+		hostname: hostname,
+	}
+
+	if res.appName == "" {
+		res.appName = "-"
+	}
+
+	// 启动时无法连接不视为错误，写入时会重新连接
+	res.state.report(res.dial())
+
+	return res
+}
+
+func (s *syslogTarget) dial() (err error) {
+	switch s.network {
+	case config.SyslogNetworkUnix:
+		s.conn, err = net.DialTimeout("unixgram", s.address, syslogTimeout)
+		s.stream = false
+		if err != nil {
+			s.conn, err = net.DialTimeout("unix", s.address, syslogTimeout)
+			s.stream = true
+		}
+	case config.SyslogNetworkUDP:
+		s.conn, err = net.DialTimeout("udp", s.address, syslogTimeout)
+		s.stream = false
+	case config.SyslogNetworkTCP:
+		s.conn, err = net.DialTimeout("tcp", s.address, syslogTimeout)
+		s.stream = true
+	default:
+		err = fmt.Errorf("unknown network %s", s.network)
+	}
+
+	if err != nil {
+		s.conn = nil
+		return fmt.Errorf("dial %s %s: %s", s.network, s.address, err.Error())
+	}
+
+	return nil
+}
+
+func (s *syslogTarget) write(severity int, kind logKind, t time.Time, fields Fields, msg string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	data := s.format(severity, t, fields, msg)
+
+	err := s.send(data)
+	if err != nil {
+		// 连接可能已被对端关闭，重新连接后再试一次
+		err = s.send(data)
+	}
+
+	s.state.report(err)
+}
+
+func (s *syslogTarget) send(data []byte) error {
+	if s.conn == nil {
+		err := s.dial()
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.stream {
+		data = append([]byte(fmt.Sprintf("%d ", len(data))), data...)
+	}
+
+	_ = s.conn.SetWriteDeadline(time.Now().Add(syslogTimeout))
+	_, err := s.conn.Write(data)
+	if err != nil {
+		_ = s.conn.Close()
+		s.conn = nil
+		return err
+	}
+
+	return nil
+}
+
+func (s *syslogTarget) close() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.conn != nil {
+		_ = s.conn.Close()
+		s.conn = nil
+	}
+}
+
+// format <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [SD] MSG，MSGID 使用 component 字段
+func (s *syslogTarget) format(severity int, t time.Time, fields Fields, msg string) []byte {
+	var buf bytes.Buffer
+
+	msgID := "-"
+	if component, ok := fields[FieldComponent].(string); ok && component != "" {
+		msgID = syslogName(component, 32)
+	}
+
+	_, _ = fmt.Fprintf(&buf, "<%d>1 %s %s %s %d %s ",
+		s.facility*8+severity,
+		t.In(config.TimeZone()).Format("2006-01-02T15:04:05.000000Z07:00"),
+		s.hostname,
+		s.appName,
+		os.Getpid(),
+		msgID)
+
+	if len(fields) == 0 {
+		buf.WriteString("-")
+	} else {
+		buf.WriteString("[" + syslogSDID)
+		for _, k := range sortedKeys(fields) {
+			name := syslogName(k, 32)
+			if name == "-" {
+				continue
+			}
+			_, _ = fmt.Fprintf(&buf, " %s=\"%s\"", name, syslogParamValue(fields[k]))
+		}
+		buf.WriteString("]")
+	}
+
+	if msg != "" {
+		buf.WriteString(" ")
+		buf.WriteString(msg)
+	}
+
+	return buf.Bytes()
+}
+
+// syslogName 只保留 RFC 5424 允许的可打印 ASCII 字符（不含 = ] " 和空格）
+func syslogName(s string, maxLen int) string {
+	res := strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 || r == '=' || r == ']' || r == '"' {
+			return -1
+		}
+		return r
+	}, s)
+
+	if len(res) > maxLen {
+		res = res[:maxLen]
+	}
+
+	if res == "" {
+		return "-"
+	}
+	return res
+}
+
+func syslogParamValue(v any) string {
+	if err, ok := v.(error); ok {
+		v = err.Error()
+	}
+
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(fmt.Sprint(v))
+}
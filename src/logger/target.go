@@ -0,0 +1,43 @@
+package logger
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// logTarget 控制台和文件之外的日志输出目标（syslog、journald）
+type logTarget interface {
+	write(severity int, kind logKind, t time.Time, fields Fields, msg string)
+	close()
+}
+
+// targetState 记录输出目标的失败状态，只在开始失败和恢复时各提示一次，避免刷屏
+type targetState struct {
+	name   string
+	failed bool
+}
+
+func (s *targetState) report(err error) {
+	if err == nil {
+		if s.failed {
+			s.failed = false
+			_, _ = fmt.Fprintf(DefaultErrorWriter, "log %s output recovered\n", s.name)
+		}
+		return
+	}
+
+	if !s.failed {
+		s.failed = true
+		_, _ = fmt.Fprintf(DefaultErrorWriter, "log %s output error: %s\n", s.name, err.Error())
+	}
+}
+
+var fieldNameInvalid = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// upperFieldName 转换为 journald 要求的字段名：大写字母、数字和下划线，不能以下划线或数字开头
+func upperFieldName(name string) string {
+	name = strings.ToUpper(fieldNameInvalid.ReplaceAllString(name, "_"))
+	return strings.TrimLeft(name, "_0123456789")
+}
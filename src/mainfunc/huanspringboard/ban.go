@@ -0,0 +1,391 @@
+package huanspringboard
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/SongZihuan/huan-springboard/src/config"
+	"github.com/SongZihuan/huan-springboard/src/database"
+	"github.com/SongZihuan/huan-springboard/src/redisserver"
+	"github.com/SongZihuan/huan-springboard/src/utils"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	banSourceSQLite = "sqlite"
+	banSourceRedis  = "redis"
+)
+
+// banView 命令行输出的一条封禁，sqlite 和 redis 的封禁使用相同的格式
+type banView struct {
+	Source  string     `json:"source"`
+	ID      uint       `json:"id,omitempty"` // 仅 sqlite
+	Scope   string     `json:"scope"`
+	Target  string     `json:"target"`
+	Value   string     `json:"value"`
+	StartAt *time.Time `json:"start_at"`
+	StopAt  *time.Time `json:"stop_at"`
+	Status  string     `json:"status"` // active/pending/expired
+}
+
+func newBanView(b *database.Ban, now time.Time) *banView {
+	status := "active"
+	if b.StartAt != nil && now.Before(*b.StartAt) {
+		status = "pending"
+	} else if b.StopAt != nil && now.After(*b.StopAt) {
+		status = "expired"
+	}
+
+	return &banView{
+		Source:  banSourceSQLite,
+		ID:      b.ID,
+		Scope:   b.Scope,
+		Target:  b.Target,
+		Value:   b.Value,
+		StartAt: b.StartAt,
+		StopAt:  b.StopAt,
+		Status:  status,
+	}
+}
+
+func newRedisBanView(b *redisserver.SSHIpBan, now time.Time) *banView {
+	var stopAt *time.Time = nil
+	if b.TTL >= 0 {
+		t := now.Add(b.TTL)
+		stopAt = &t
+	}
+
+	return &banView{
+		Source: banSourceRedis,
+		Scope:  database.BanScopeSSH,
+		Target: database.BanTargetIP,
+		Value:  b.IP,
+		StopAt: stopAt,
+		Status: "active",
+	}
+}
+
+// banScopes 解析 --scope，all 表示 tcp 和 ssh
+func banScopes(scope string) ([]string, error) {
+	switch strings.ToLower(scope) {
+	case "", "all":
+		return []string{database.BanScopeTCP, database.BanScopeSSH}, nil
+	case database.BanScopeTCP:
+		return []string{database.BanScopeTCP}, nil
+	case database.BanScopeSSH:
+		return []string{database.BanScopeSSH}, nil
+	default:
+		return nil, fmt.Errorf("bad scope '%s', must be tcp/ssh/all", scope)
+	}
+}
+
+func formatBanTime(t *time.Time, empty string) string {
+	if t == nil {
+		return empty
+	}
+	return t.In(config.TimeZone()).Format(utils.TimeLayout)
+}
+
+func printBans(bans []*banView, jsonOutput bool) error {
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(bans)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "SOURCE\tID\tSCOPE\tTARGET\tVALUE\tSTART\tSTOP\tSTATUS")
+	for _, b := range bans {
+		id := "-"
+		if b.ID != 0 {
+			id = strconv.FormatUint(uint64(b.ID), 10)
+		}
+
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", b.Source, id, b.Scope, b.Target, b.Value, formatBanTime(b.StartAt, "-"), formatBanTime(b.StopAt, "forever"), b.Status)
+	}
+
+	return tw.Flush()
+}
+
+// banTargetArgs 解析 <target> <value> 两个参数
+func banTargetArgs(fs *flag.FlagSet) (target string, value string, err error) {
+	if fs.NArg() != 2 {
+		return "", "", fmt.Errorf("requires <target> <value>, target must be %s", strings.Join(database.BanTargets, "/"))
+	}
+
+	target = strings.ToLower(fs.Arg(0))
+	value, err = database.NormalizeBanValue(target, fs.Arg(1))
+	if err != nil {
+		return "", "", err
+	}
+
+	return target, value, nil
+}
+
+// banCommand 添加封禁：ban [--scope tcp|ssh|all] [--start time] [--duration duration | --until time] [--redis] [--json] <target> <value>
+func banCommand(args []string) (exitcode int) {
+	var scope, start, duration, until string
+	var redis, jsonOutput bool
+
+	fs := flag.NewFlagSet(utils.GetArgs0Name()+" ban", flag.ContinueOnError)
+	fs.StringVar(&scope, "scope", "all", "The scope of the ban: tcp, ssh or all.")
+	fs.StringVar(&start, "start", "", "The start time of the ban, the default is now.")
+	fs.StringVar(&duration, "duration", "", "The duration of the ban, like 30m, 12h or 7d. The default is forever.")
+	fs.StringVar(&until, "until", "", "The stop time of the ban, can not be used with --duration.")
+	fs.BoolVar(&redis, "redis", false, "Ban the ip in redis like the ssh count rules (ssh ip only, --start is not supported).")
+	fs.BoolVar(&jsonOutput, "json", false, "Print the result as json.")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return 2
+	}
+
+	target, value, err := banTargetArgs(fs)
+	if err != nil {
+		return utils.ExitByError(err)
+	}
+
+	scopes, err := banScopes(scope)
+	if err != nil {
+		return utils.ExitByError(err)
+	}
+
+	if duration != "" && until != "" {
+		return utils.ExitByErrorMsg("--duration and --until can not be used together")
+	}
+
+	closeFunc, err := initCommand(true)
+	if err != nil {
+		return utils.ExitByError(err)
+	}
+	defer closeFunc()
+
+	now := time.Now()
+
+	var startAt, stopAt *time.Time = nil, nil
+	if start != "" {
+		t, err := utils.ParseTime(start, config.TimeZone())
+		if err != nil {
+			return utils.ExitByError(err)
+		}
+		startAt = &t
+	}
+
+	if duration != "" {
+		d, err := utils.ParseDuration(duration)
+		if err != nil {
+			return utils.ExitByError(err)
+		}
+
+		t := now.Add(d)
+		if startAt != nil {
+			t = startAt.Add(d)
+		}
+		stopAt = &t
+	} else if until != "" {
+		t, err := utils.ParseTime(until, config.TimeZone())
+		if err != nil {
+			return utils.ExitByError(err)
+		}
+		stopAt = &t
+	}
+
+	if stopAt != nil && !stopAt.After(now) {
+		return utils.ExitByErrorMsg("the stop time has already passed")
+	}
+
+	res := make([]*banView, 0, len(scopes))
+
+	if redis {
+		if len(scopes) != 1 || scopes[0] != database.BanScopeSSH || target != database.BanTargetIP {
+			return utils.ExitByErrorMsg("--redis only supports --scope ssh with an ip target")
+		} else if startAt != nil {
+			return utils.ExitByErrorMsg("--redis does not support --start")
+		}
+
+		var ttl time.Duration = -1 // 永久
+		if stopAt != nil {
+			ttl = stopAt.Sub(now)
+		}
+
+		err = redisserver.BanSSHIp(value, ttl)
+		if err != nil {
+			return utils.ExitByError(err)
+		}
+
+		res = append(res, newRedisBanView(&redisserver.SSHIpBan{IP: value, TTL: ttl}, now))
+	} else {
+		for _, s := range scopes {
+			b, err := database.AddBan(s, target, value, startAt, stopAt)
+			if err != nil {
+				return utils.ExitByError(err)
+			}
+
+			res = append(res, newBanView(b, now))
+		}
+	}
+
+	err = printBans(res, jsonOutput)
+	if err != nil {
+		fmt.Printf("print result error: %s\n", err.Error())
+		return 1
+	}
+
+	return 0
+}
+
+// unbanCommand 解除封禁（sqlite 中的记录保留，结束时间设置为当前时间；ssh 的 ip 同时删除 redis 中的封禁）：
+// unban [--scope tcp|ssh|all] [--json] <target> <value>
+func unbanCommand(args []string) (exitcode int) {
+	var scope string
+	var jsonOutput bool
+
+	fs := flag.NewFlagSet(utils.GetArgs0Name()+" unban", flag.ContinueOnError)
+	fs.StringVar(&scope, "scope", "all", "The scope of the ban: tcp, ssh or all.")
+	fs.BoolVar(&jsonOutput, "json", false, "Print the result as json.")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return 2
+	}
+
+	target, value, err := banTargetArgs(fs)
+	if err != nil {
+		return utils.ExitByError(err)
+	}
+
+	scopes, err := banScopes(scope)
+	if err != nil {
+		return utils.ExitByError(err)
+	}
+
+	closeFunc, err := initCommand(true)
+	if err != nil {
+		return utils.ExitByError(err)
+	}
+	defer closeFunc()
+
+	type unbanResult struct {
+		Source string `json:"source"`
+		Scope  string `json:"scope"`
+		Count  int64  `json:"count"` // 解除的封禁数
+	}
+
+	res := make([]*unbanResult, 0, len(scopes)+1)
+	for _, s := range scopes {
+		n, err := database.RemoveBan(s, target, value)
+		if err != nil {
+			return utils.ExitByError(err)
+		}
+
+		res = append(res, &unbanResult{Source: banSourceSQLite, Scope: s, Count: n})
+
+		if s == database.BanScopeSSH && target == database.BanTargetIP {
+			ok, err := redisserver.UnbanSSHIp(value)
+			if err != nil {
+				return utils.ExitByError(err)
+			}
+
+			r := &unbanResult{Source: banSourceRedis, Scope: s}
+			if ok {
+				r.Count = 1
+			}
+			res = append(res, r)
+		}
+	}
+
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(res)
+		if err != nil {
+			fmt.Printf("print result error: %s\n", err.Error())
+			return 1
+		}
+		return 0
+	}
+
+	for _, r := range res {
+		fmt.Printf("%s %s: %d ban(s) of %s %s removed\n", r.Source, r.Scope, r.Count, target, value)
+	}
+
+	return 0
+}
+
+// bansCommand 查看封禁：bans list [--scope tcp|ssh|all] [--target target] [--all] [--json]
+func bansCommand(args []string) (exitcode int) {
+	if len(args) == 0 || args[0] != "list" {
+		return utils.ExitByErrorMsg("usage: bans list [--scope tcp|ssh|all] [--target target] [--all] [--json]")
+	}
+
+	var scope, target string
+	var all, jsonOutput bool
+
+	fs := flag.NewFlagSet(utils.GetArgs0Name()+" bans list", flag.ContinueOnError)
+	fs.StringVar(&scope, "scope", "all", "The scope of the ban: tcp, ssh or all.")
+	fs.StringVar(&target, "target", "", fmt.Sprintf("Only list this target: %s.", strings.Join(database.BanTargets, "/")))
+	fs.BoolVar(&all, "all", false, "Also list the expired bans.")
+	fs.BoolVar(&jsonOutput, "json", false, "Print the result as json.")
+
+	err := fs.Parse(args[1:])
+	if err != nil {
+		return 2
+	} else if fs.NArg() != 0 {
+		return utils.ExitByErrorMsg("bans list takes no arguments")
+	}
+
+	scopes, err := banScopes(scope)
+	if err != nil {
+		return utils.ExitByError(err)
+	}
+
+	targets := database.BanTargets
+	if target != "" {
+		targets = []string{strings.ToLower(target)}
+	}
+
+	closeFunc, err := initCommand(true)
+	if err != nil {
+		return utils.ExitByError(err)
+	}
+	defer closeFunc()
+
+	now := time.Now()
+	res := make([]*banView, 0, 10)
+
+	for _, s := range scopes {
+		for _, t := range targets {
+			bans, err := database.ListBans(s, t, all)
+			if err != nil {
+				return utils.ExitByError(err)
+			}
+
+			for _, b := range bans {
+				res = append(res, newBanView(b, now))
+			}
+		}
+
+		if s == database.BanScopeSSH && (target == "" || target == database.BanTargetIP) {
+			bans, err := redisserver.ListSSHIpBanned()
+			if err != nil {
+				return utils.ExitByError(err)
+			}
+
+			for _, b := range bans {
+				res = append(res, newRedisBanView(b, now))
+			}
+		}
+	}
+
+	err = printBans(res, jsonOutput)
+	if err != nil {
+		fmt.Printf("print result error: %s\n", err.Error())
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,41 @@
+package huanspringboard
+
+import (
+	"fmt"
+	"github.com/SongZihuan/huan-springboard/src/config"
+	"github.com/SongZihuan/huan-springboard/src/flagparser"
+	"github.com/SongZihuan/huan-springboard/src/ipcheck"
+	"github.com/SongZihuan/huan-springboard/src/utils"
+)
+
+// checkConfig 只检查配置文件并输出全部错误和警告，存在错误时返回非零退出码；不监听端口，不连接 Redis 和 SQLite
+func checkConfig() (exitcode int) {
+	// 不探测网络，按双栈解析 IPv4 和 IPv6 目标地址
+	ipcheck.AssumeDualStack()
+
+	issues, err := config.CheckConfigFile(flagparser.ConfigFile())
+	if err != nil {
+		fmt.Println(utils.FormatTextToWidth(fmt.Sprintf("config %s parser error: %s", flagparser.ConfigFile(), err.Error()), utils.NormalConsoleWidth))
+		return 1
+	}
+
+	errCount := 0
+	warnCount := 0
+	for _, i := range issues {
+		if i.IsError {
+			errCount++
+		} else {
+			warnCount++
+		}
+
+		fmt.Println(utils.FormatTextToWidth(i.String(), utils.NormalConsoleWidth))
+	}
+
+	fmt.Printf("config %s: %d error(s), %d warning(s)\n", flagparser.ConfigFile(), errCount, warnCount)
+
+	if errCount > 0 {
+		return 1
+	}
+
+	return 0
+}
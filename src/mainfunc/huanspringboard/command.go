@@ -0,0 +1,59 @@
+package huanspringboard
+
+import (
+	"github.com/SongZihuan/huan-springboard/src/config"
+	"github.com/SongZihuan/huan-springboard/src/database"
+	"github.com/SongZihuan/huan-springboard/src/flagparser"
+	"github.com/SongZihuan/huan-springboard/src/ipcheck"
+	"github.com/SongZihuan/huan-springboard/src/redisserver"
+	"github.com/SongZihuan/huan-springboard/src/utils"
+)
+
+// runCommand 执行子命令，返回退出码
+func runCommand() (exitcode int) {
+	switch flagparser.Command() {
+	case flagparser.CommandSimulate:
+		return simulateCommand(flagparser.CommandArgs())
+	case flagparser.CommandBan:
+		return banCommand(flagparser.CommandArgs())
+	case flagparser.CommandUnban:
+		return unbanCommand(flagparser.CommandArgs())
+	case flagparser.CommandBans:
+		return bansCommand(flagparser.CommandArgs())
+	case flagparser.CommandReport:
+		return reportCommand(flagparser.CommandArgs())
+	default:
+		return utils.ExitByErrorMsg("unknown command: " + flagparser.Command())
+	}
+}
+
+// initCommand 子命令使用的初始化：加载配置、连接 SQLite 和 Redis（withRedis 为 false 时不连接）；不初始化日志（避免写入服务的日志文件），不启动服务
+func initCommand(withRedis bool) (closeFunc func(), err error) {
+	// 子命令不监听端口，不需要探测网络（探测需要 raw socket 权限）
+	ipcheck.AssumeDualStack()
+
+	cfgErr := config.InitConfig(flagparser.ConfigFile())
+	if cfgErr != nil && cfgErr.IsError() {
+		return nil, cfgErr
+	}
+
+	err = database.InitSQLiteSilent()
+	if err != nil {
+		return nil, err
+	}
+
+	if !withRedis {
+		return database.CloseSQLite, nil
+	}
+
+	err = redisserver.InitRedis()
+	if err != nil {
+		database.CloseSQLite()
+		return nil, err
+	}
+
+	return func() {
+		redisserver.CloseRedis()
+		database.CloseSQLite()
+	}, nil
+}
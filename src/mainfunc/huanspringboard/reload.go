@@ -0,0 +1,45 @@
+package huanspringboard
+
+import (
+	"github.com/SongZihuan/huan-springboard/src/config"
+	"github.com/SongZihuan/huan-springboard/src/logger"
+	"github.com/SongZihuan/huan-springboard/src/sshserver"
+	"github.com/SongZihuan/huan-springboard/src/tcpserver"
+	"sync"
+)
+
+// configReloader 重新加载配置文件（文件监听和 SIGHUP 共用），同一时间只进行一次重新加载
+type configReloader struct {
+	lock   sync.Mutex
+	tcpser *tcpserver.TcpServerGroup
+	sshser *sshserver.SshServerGroup
+}
+
+// reload 重新加载配置文件，成功后应用日志配置、调整转发并保存快照；失败时继续使用原来的配置
+func (r *configReloader) reload(source string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	logger.Infof("Config file reload (%s)...", source)
+
+	err := config.ReloadConfig()
+	if err != nil && err.IsError() {
+		logger.Errorf("Config file reload error: %s", err.Error())
+		return
+	}
+
+	logger.Infof("%s", "Config file reload success") // 警告已经在检查时输出
+
+	applyErr := logger.ApplyConfig()
+	if applyErr != nil {
+		logger.Errorf("Apply log config error: %s", applyErr.Error())
+	}
+
+	r.tcpser.Reconcile()
+	r.sshser.Reconcile()
+
+	saveErr := config.SaveLastGood()
+	if saveErr != nil {
+		logger.Warnf("save last-known-good config failed: %s", saveErr.Error())
+	}
+}
@@ -0,0 +1,79 @@
+package huanspringboard
+
+import (
+	"flag"
+	"fmt"
+	"github.com/SongZihuan/huan-springboard/src/config"
+	"github.com/SongZihuan/huan-springboard/src/database"
+	"github.com/SongZihuan/huan-springboard/src/report"
+	"github.com/SongZihuan/huan-springboard/src/utils"
+	"os"
+)
+
+const (
+	reportList    = "list"
+	reportSummary = "summary"
+)
+
+// reportCommand 查询 ssh 连接记录：
+// report [--ip ip] [--to address] [--since time] [--until time] [--accept true|false] [--limit n] [--top n] [--format table|csv|json] list|summary
+func reportCommand(args []string) (exitcode int) {
+	opts := report.Options{}
+
+	fs := flag.NewFlagSet(utils.GetArgs0Name()+" report", flag.ContinueOnError)
+	fs.StringVar(&opts.IP, "ip", "", "Only the records from this ip.")
+	fs.StringVar(&opts.To, "to", "", "Only the records to this destination, like 127.0.0.1:22.")
+	fs.StringVar(&opts.Since, "since", "", "Only the records since this time, or a duration ago like 24h or 7d.")
+	fs.StringVar(&opts.Until, "until", "", "Only the records before this time, or a duration ago like 24h or 7d.")
+	fs.StringVar(&opts.Accept, "accept", "", "Only the accepted (true) or the rejected (false) records.")
+	fs.IntVar(&opts.Limit, "limit", report.DefaultLimit, "The max number of the listed records, 0 means no limit.")
+	fs.IntVar(&opts.Top, "top", report.DefaultTop, "The number of the top rejected ips and the top destinations in the summary.")
+	fs.StringVar(&opts.Format, "format", report.FormatTable, "The output format: table, csv or json.")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return 2
+	} else if fs.NArg() != 1 || (fs.Arg(0) != reportList && fs.Arg(0) != reportSummary) {
+		return utils.ExitByErrorMsg("report requires list or summary after the options")
+	}
+
+	err = opts.Check()
+	if err != nil {
+		return utils.ExitByError(err)
+	}
+
+	closeFunc, err := initCommand(false)
+	if err != nil {
+		return utils.ExitByError(err)
+	}
+	defer closeFunc()
+
+	filter, err := opts.Filter()
+	if err != nil {
+		return utils.ExitByError(err)
+	}
+
+	if fs.Arg(0) == reportList {
+		var records []*database.ConnectRecord
+		records, err = database.FindConnectRecords(filter, opts.Limit)
+		if err != nil {
+			return utils.ExitByError(err)
+		}
+
+		err = report.WriteRecords(os.Stdout, opts.Format, records)
+	} else {
+		var res *database.ConnectReport
+		res, err = database.ReportConnectRecords(filter, opts.Top, config.TimeZone())
+		if err != nil {
+			return utils.ExitByError(err)
+		}
+
+		err = report.WriteReport(os.Stdout, opts.Format, res)
+	}
+	if err != nil {
+		fmt.Printf("print result error: %s\n", err.Error())
+		return 1
+	}
+
+	return 0
+}
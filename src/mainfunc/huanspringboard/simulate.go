@@ -0,0 +1,74 @@
+package huanspringboard
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/SongZihuan/huan-springboard/src/rulesim"
+	"github.com/SongZihuan/huan-springboard/src/sshserver"
+	"github.com/SongZihuan/huan-springboard/src/tcpserver"
+	"github.com/SongZihuan/huan-springboard/src/utils"
+	"os"
+)
+
+// simulateCommand 模拟来访 IP 的检查流程（dry-run），输出是否放行、命中的规则以及每一步的检查过程
+func simulateCommand(args []string) (exitcode int) {
+	var ssh, jsonOutput bool
+	var loc rulesim.Location
+	req := rulesim.Request{Component: rulesim.ComponentTCP}
+
+	fs := flag.NewFlagSet(utils.GetArgs0Name()+" simulate", flag.ContinueOnError)
+	fs.BoolVar(&ssh, "ssh", false, "Check with the ssh rules instead of the tcp rules.")
+	fs.Int64Var(&req.Port, "port", 0, "The listen port of the forward, ssh uses the count rules and the connection records of this forward.")
+	fs.StringVar(&loc.Nation, "nation", "", "Use this nation instead of querying the ip location.")
+	fs.StringVar(&loc.Province, "province", "", "Use this province instead of querying the ip location.")
+	fs.StringVar(&loc.City, "city", "", "Use this city instead of querying the ip location.")
+	fs.StringVar(&loc.Isp, "isp", "", "Use this isp instead of querying the ip location.")
+	fs.BoolVar(&jsonOutput, "json", false, "Print the result as json.")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return 2
+	} else if fs.NArg() != 1 {
+		return utils.ExitByErrorMsg("simulate requires exactly one ip")
+	}
+
+	req.IP = fs.Arg(0)
+	if ssh {
+		req.Component = rulesim.ComponentSSH
+	}
+
+	if loc != (rulesim.Location{}) {
+		req.Location = &loc
+	}
+
+	closeFunc, err := initCommand(true)
+	if err != nil {
+		return utils.ExitByError(err)
+	}
+	defer closeFunc()
+
+	var res *rulesim.Result
+	if req.Component == rulesim.ComponentSSH {
+		res, err = sshserver.Simulate(&req)
+	} else {
+		res, err = tcpserver.Simulate(&req)
+	}
+	if err != nil {
+		return utils.ExitByError(err)
+	}
+
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(res)
+	} else {
+		err = res.Fprint(os.Stdout)
+	}
+	if err != nil {
+		fmt.Printf("print result error: %s\n", err.Error())
+		return 1
+	}
+
+	return 0
+}
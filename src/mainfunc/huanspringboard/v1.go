@@ -2,6 +2,7 @@ package huanspringboard
 
 import (
 	"errors"
+	"github.com/SongZihuan/huan-springboard/src/adminserver"
 	"github.com/SongZihuan/huan-springboard/src/config"
 	"github.com/SongZihuan/huan-springboard/src/config/watcher"
 	"github.com/SongZihuan/huan-springboard/src/database"
@@ -30,7 +31,7 @@ func MainV1() (exitcode int) {
 
 	err = flagparser.InitFlag()
 	if errors.Is(err, flagparser.StopFlag) {
-		return 0
+		return utils.Exit(0) // 配置未初始化，直接退出，不发送停止通知
 	} else if err != nil {
 		return utils.ExitByError(err)
 	}
@@ -39,6 +40,14 @@ func MainV1() (exitcode int) {
 		return utils.ExitByErrorMsg("flag parser unknown error")
 	}
 
+	if flagparser.CheckMode() {
+		return utils.Exit(checkConfig()) // 配置未初始化，直接退出，不发送停止通知
+	}
+
+	if flagparser.Command() != "" {
+		return utils.Exit(runCommand()) // 子命令不启动服务，直接退出，不发送停止通知
+	}
+
 	utils.SayHellof("%s", "The backend service program starts normally, thank you.")
 	defer func() {
 		if exitcode != 0 {
@@ -65,17 +74,14 @@ func MainV1() (exitcode int) {
 	if !logger.IsReady() {
 		return utils.ExitByErrorMsg("logger unknown error")
 	}
+	defer logger.CloseLogger()
 
-	if flagparser.RunAutoReload() {
-		err = watcher.WatcherConfigFile()
-		if err != nil {
-			return utils.ExitByError(err)
-		}
-		defer watcher.CloseNotifyConfigFile()
+	stopLevelSignal := logger.WatchLevelSignal()
+	defer stopLevelSignal()
 
-		logger.Infof("Auto reload enable.")
-	} else {
-		logger.Infof("Auto reload disable.")
+	rollback := config.GetRollback()
+	if rollback != nil {
+		logger.Errorf("Config file is invalid, start with the last-known-good config saved at %s: %s", rollback.SavedAt.Format(utils.TimeLayout), rollback.Reason)
 	}
 
 	if ipcheck.SupportIPv4() {
@@ -100,6 +106,7 @@ func MainV1() (exitcode int) {
 		logger.Errorf("init smtp fail: %s", err.Error())
 		return 1
 	}
+	defer smtpserver.CloseSmtp()
 
 	err = database.InitSQLite()
 	if err != nil {
@@ -108,6 +115,13 @@ func MainV1() (exitcode int) {
 	}
 	defer database.CloseSQLite()
 
+	err = notify.StartQueue()
+	if err != nil {
+		logger.Errorf("start notify queue fail: %s", err.Error())
+		return 1
+	}
+	defer notify.StopQueue() // 先于数据库关闭，在限定时间内清空队列
+
 	cleaner, err := database.NewCleaner()
 	if err != nil {
 		logger.Errorf("create sqlclear fail: %s", err.Error())
@@ -167,7 +181,45 @@ func MainV1() (exitcode int) {
 		_ = sshser.Stop()
 	}()
 
+	// 转发启动后再监听配置文件的修改，重新加载时调整转发
+	reloader := &configReloader{tcpser: tcpser, sshser: sshser}
+
+	stopReloadSignal := watcher.WatchReloadSignal(func() {
+		reloader.reload("SIGHUP")
+	})
+	defer stopReloadSignal()
+
+	if flagparser.RunAutoReload() {
+		err = watcher.WatcherConfigFile(func() {
+			reloader.reload("file changed")
+		})
+		if err != nil {
+			logger.Errorf("watch config file failed: %s\n", err.Error())
+			return 1
+		}
+		defer watcher.CloseNotifyConfigFile()
+
+		logger.Infof("Auto reload enable.")
+	} else {
+		logger.Infof("Auto reload disable.")
+	}
+
+	err = adminserver.InitAdmin()
+	if err != nil {
+		logger.Errorf("init admin server failed: %s\n", err.Error())
+		return 1
+	}
+	defer adminserver.CloseAdmin()
+
+	err = config.SaveLastGood()
+	if err != nil {
+		logger.Warnf("save last-known-good config failed: %s", err.Error())
+	}
+
 	notify.SendStart() // 此处是Start不是WaitStart
+	if rollback != nil {
+		notify.SendConfigRollback(rollback.Reason)
+	}
 
 	select {
 	case <-config.GetSignalChan():
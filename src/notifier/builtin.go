@@ -0,0 +1,75 @@
+package notifier
+
+import (
+	"errors"
+	"github.com/SongZihuan/huan-springboard/src/config"
+	"github.com/SongZihuan/huan-springboard/src/logger"
+	"github.com/SongZihuan/huan-springboard/src/smtpserver"
+	"github.com/SongZihuan/huan-springboard/src/wxrobot"
+)
+
+type wxRobotNotifier struct {
+	name    string
+	webhook string
+}
+
+func newWxRobotNotifier(name string, webhook string) *wxRobotNotifier {
+	return &wxRobotNotifier{
+		name:    name,
+		webhook: webhook,
+	}
+}
+
+func (w *wxRobotNotifier) Name() string {
+	return w.name
+}
+
+func (w *wxRobotNotifier) Type() string {
+	return config.NotifyTypeWxRobot
+}
+
+func (w *wxRobotNotifier) Send(msg *Message) error {
+	rendered := Render(w.name, msg)
+
+	if msg.AtAll {
+		// 企业微信 Markdown 消息不支持提醒全部成员，改为发送一条文本消息
+		return wxrobot.SendText(w.webhook, rendered.Text, true)
+	}
+
+	return wxrobot.SendMarkdown(w.webhook, rendered.Markdown)
+}
+
+type smtpNotifier struct {
+	name string
+}
+
+func newSmtpNotifier(name string) *smtpNotifier {
+	return &smtpNotifier{
+		name: name,
+	}
+}
+
+func (s *smtpNotifier) Name() string {
+	return s.name
+}
+
+func (s *smtpNotifier) Type() string {
+	return config.NotifyTypeSMTP
+}
+
+func (s *smtpNotifier) Send(msg *Message) error {
+	rendered := Render(s.name, msg)
+
+	err := smtpserver.SendMail(rendered.Subject, rendered.Text, rendered.HTML)
+
+	var deliveryErr *smtpserver.DeliveryError
+	if errors.As(err, &deliveryErr) && deliveryErr.Partial() {
+		// 已投递给部分收件人，不再整体重试，避免重复发送
+		for _, f := range deliveryErr.Failed {
+			logger.Warnf("Notify %s deliver to %s failed: %s", s.name, f.Address, f.Err.Error())
+		}
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,94 @@
+package notifier
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"github.com/SongZihuan/huan-springboard/src/config"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type dingTalkNotifier struct {
+	config *config.NotifyChannelConfig
+	client *http.Client
+}
+
+type dingTalkText struct {
+	Content string `json:"content"`
+}
+
+type dingTalkAt struct {
+	IsAtAll bool `json:"isAtAll"`
+}
+
+type dingTalkReq struct {
+	MsgType string        `json:"msgtype"`
+	Text    *dingTalkText `json:"text"`
+	At      *dingTalkAt   `json:"at"`
+}
+
+type dingTalkResp struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
+
+func newDingTalkNotifier(c *config.NotifyChannelConfig) *dingTalkNotifier {
+	return &dingTalkNotifier{
+		config: c,
+		client: newHttpClient(c.TimeoutSeconds),
+	}
+}
+
+func (d *dingTalkNotifier) Name() string {
+	return d.config.Name
+}
+
+func (d *dingTalkNotifier) Type() string {
+	return config.NotifyTypeDingTalk
+}
+
+func (d *dingTalkNotifier) Send(msg *Message) error {
+	var resp dingTalkResp
+	err := postJSON(d.client, d.signURL(time.Now()), &dingTalkReq{
+		MsgType: "text",
+		Text: &dingTalkText{
+			Content: Render(d.config.Name, msg).Text,
+		},
+		At: &dingTalkAt{
+			IsAtAll: msg.AtAll,
+		},
+	}, &resp)
+	if err != nil {
+		return err
+	}
+
+	if resp.ErrCode != 0 {
+		return fmt.Errorf("send message error [code: %d]: %s", resp.ErrCode, resp.ErrMsg)
+	}
+
+	return nil
+}
+
+// signURL 钉钉加签：HmacSHA256(timestamp + "\n" + secret)，密钥为 secret
+func (d *dingTalkNotifier) signURL(now time.Time) string {
+	if d.config.Secret == "" {
+		return d.config.URL
+	}
+
+	timestamp := fmt.Sprintf("%d", now.UnixMilli())
+
+	mac := hmac.New(sha256.New, []byte(d.config.Secret))
+	mac.Write([]byte(timestamp + "\n" + d.config.Secret))
+	sign := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	sep := "?"
+	if strings.Contains(d.config.URL, "?") {
+		sep = "&"
+	}
+
+	return fmt.Sprintf("%s%stimestamp=%s&sign=%s", d.config.URL, sep, timestamp, url.QueryEscape(sign))
+}
@@ -0,0 +1,89 @@
+package notifier
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"github.com/SongZihuan/huan-springboard/src/config"
+	"net/http"
+	"time"
+)
+
+type feishuNotifier struct {
+	config *config.NotifyChannelConfig
+	client *http.Client
+}
+
+type feishuContent struct {
+	Text string `json:"text"`
+}
+
+type feishuReq struct {
+	Timestamp string         `json:"timestamp,omitempty"`
+	Sign      string         `json:"sign,omitempty"`
+	MsgType   string         `json:"msg_type"`
+	Content   *feishuContent `json:"content"`
+}
+
+type feishuResp struct {
+	Code          int    `json:"code"`
+	Msg           string `json:"msg"`
+	StatusCode    int    `json:"StatusCode"`
+	StatusMessage string `json:"StatusMessage"`
+}
+
+func newFeishuNotifier(c *config.NotifyChannelConfig) *feishuNotifier {
+	return &feishuNotifier{
+		config: c,
+		client: newHttpClient(c.TimeoutSeconds),
+	}
+}
+
+func (f *feishuNotifier) Name() string {
+	return f.config.Name
+}
+
+func (f *feishuNotifier) Type() string {
+	return config.NotifyTypeFeishu
+}
+
+func (f *feishuNotifier) Send(msg *Message) error {
+	text := Render(f.config.Name, msg).Text
+	if msg.AtAll {
+		text = `<at user_id="all">所有人</at> ` + text
+	}
+
+	req := &feishuReq{
+		MsgType: "text",
+		Content: &feishuContent{
+			Text: text,
+		},
+	}
+
+	if f.config.Secret != "" {
+		req.Timestamp, req.Sign = f.sign(time.Now())
+	}
+
+	var resp feishuResp
+	err := postJSON(f.client, f.config.URL, req, &resp)
+	if err != nil {
+		return err
+	}
+
+	if resp.Code != 0 {
+		return fmt.Errorf("send message error [code: %d]: %s", resp.Code, resp.Msg)
+	} else if resp.StatusCode != 0 {
+		return fmt.Errorf("send message error [code: %d]: %s", resp.StatusCode, resp.StatusMessage)
+	}
+
+	return nil
+}
+
+// sign 飞书加签：以 timestamp + "\n" + secret 为密钥，对空字符串做 HmacSHA256
+func (f *feishuNotifier) sign(now time.Time) (string, string) {
+	timestamp := fmt.Sprintf("%d", now.Unix())
+
+	mac := hmac.New(sha256.New, []byte(timestamp+"\n"+f.config.Secret))
+	return timestamp, base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
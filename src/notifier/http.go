@@ -0,0 +1,78 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+func newHttpClient(timeoutSeconds int64) *http.Client {
+	if timeoutSeconds <= 0 {
+		timeoutSeconds = 10
+	}
+
+	return &http.Client{
+		Timeout: time.Duration(timeoutSeconds) * time.Second,
+	}
+}
+
+// doRequest 发送请求并返回响应体，非 2xx 状态码视为错误
+func doRequest(client *http.Client, method string, url string, contentType string, headers map[string]string, body []byte) ([]byte, error) {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("new request error: %s", err.Error())
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http %s error: %s", method, err.Error())
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	respData, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response body error: %s", err.Error())
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return respData, fmt.Errorf("bad status code %d: %s", resp.StatusCode, string(respData))
+	}
+
+	return respData, nil
+}
+
+func postJSON(client *http.Client, url string, data any, resp any) error {
+	reqData, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("json marshal error: %s", err.Error())
+	}
+
+	respData, err := doRequest(client, http.MethodPost, url, "application/json", nil, reqData)
+	if err != nil {
+		return err
+	}
+
+	if resp == nil {
+		return nil
+	}
+
+	err = json.Unmarshal(respData, resp)
+	if err != nil {
+		return fmt.Errorf("json unmarshal response body error: %s", err.Error())
+	}
+
+	return nil
+}
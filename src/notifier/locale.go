@@ -0,0 +1,143 @@
+package notifier
+
+import "github.com/SongZihuan/huan-springboard/src/config"
+
+// localeTemplates 单个语言的内置模板
+type localeTemplates struct {
+	Title    map[string]string // 按事件的标题模板
+	Content  map[string]string // 按事件的正文模板
+	Subject  string            // 邮件主题外框
+	Text     string            // 纯文本外框
+	Markdown string            // Markdown 外框
+	HTML     string            // HTML 外框
+	Labels   map[string]string // label 函数使用的字段名称
+}
+
+var builtinLocales = map[string]*localeTemplates{
+	config.NotifyLocaleZh: {
+		Title: map[string]string{
+			config.NotifyEventStart:          `服务启动完成`,
+			config.NotifyEventWaitStop:       `服务停止`,
+			config.NotifyEventStop:           `服务停止`,
+			config.NotifyEventTcpNotAccept:   `网络高峰`,
+			config.NotifyEventTcpStopAccept:  `网络高峰`,
+			config.NotifyEventTcpReAccept:    `网络平稳`,
+			config.NotifyEventSshBanned:      `SSH异常请求（拒绝）`,
+			config.NotifyEventSshSuccess:     `SSH请求（通过）`,
+			config.NotifyEventConfigRollback: `配置文件回滚`,
+			config.NotifyEventDigest:         `消息汇总（{{.Count}} 条）`,
+		},
+		Content: map[string]string{
+			config.NotifyEventStart:          `服务启动/重启完成。`,
+			config.NotifyEventWaitStop:       `服务即将停止（原因：{{or (truncate 512 .Reason) "无"}}）。`,
+			config.NotifyEventStop:           `服务停止。退出代码：{{.ExitCode}}。剩余协程数：{{.Goroutines}}。`,
+			config.NotifyEventTcpNotAccept:   `网络高峰，Tcp服务暂停接收新请求。`,
+			config.NotifyEventTcpStopAccept:  `网络高峰，Tcp服务全部下线。`,
+			config.NotifyEventTcpReAccept:    `网络平稳，Tcp服务恢复。`,
+			config.NotifyEventSshBanned:      `IP {{.IP}}{{with .Location}}（{{.}}）{{end}} 连接到 {{.Dest}} 被拒（原因：{{or (truncate 512 .Reason) "无"}}）。`,
+			config.NotifyEventSshSuccess:     `IP {{.IP}}{{with .Location}}（{{.}}）{{end}} 连接到 {{.Dest}} 成功（备注：{{or (truncate 512 .Reason) "无"}}）。`,
+			config.NotifyEventConfigRollback: `配置文件检查失败，已使用最后一次成功应用的配置启动（原因：{{or (truncate 512 .Reason) "无"}}）。`,
+			config.NotifyEventDigest: `{{time .StartTime}} 至 {{time .Time}} 共 {{.Count}} 条消息。
+{{- range .Sections}}
+{{label .Name}}：
+{{- range .Items}}
+  {{if .Rank}}{{.Rank}}. {{or (truncate 200 .Name) "无"}}（{{.Count}} 次）{{else}}{{or (truncate 200 .Name) "无"}}：{{.Count}} 条{{end}}
+{{- end}}
+{{- if .Omitted}}
+  其余 {{.Omitted}} 项省略
+{{- end}}
+{{- end}}`,
+		},
+		Subject: `【{{.System}} 消息提醒】 {{.Title}}`,
+		Text:    `【{{.System}} 消息提醒】 {{.Content}}`,
+		Markdown: `**【{{.System}} 消息提醒】 {{.Title}}**
+{{.Content}}
+{{if .IP}}> 来访IP：{{.IP}}
+{{with .Location}}> 位置：{{.}}
+{{end}}> 目标：{{.Dest}}
+{{end}}> 等级：<font color="{{severityColor .Severity}}">{{.Severity}}</font>
+> 时间：{{time .Time}}`,
+		HTML: `<html><body>
+<h3>【{{.System}} 消息提醒】 {{.Title}}</h3>
+<p>{{br .Content}}</p>
+<table border="1" cellpadding="4" cellspacing="0">
+{{if .IP}}<tr><td>来访IP</td><td>{{.IP}}</td></tr>
+{{with .Location}}<tr><td>位置</td><td>{{.}}</td></tr>
+{{end}}<tr><td>目标</td><td>{{.Dest}}</td></tr>
+{{end}}<tr><td>等级</td><td>{{.Severity}}</td></tr>
+<tr><td>时间</td><td>{{time .Time}}</td></tr>
+</table>
+</body></html>`,
+		Labels: map[string]string{
+			DigestSectionEvent:    "按事件",
+			DigestSectionIP:       "按来访IP",
+			DigestSectionReason:   "按原因",
+			DigestSectionDest:     "按目标",
+			DigestSectionOther:    "其他消息",
+			DigestSectionOffender: "被拒次数最多的IP",
+		},
+	},
+	config.NotifyLocaleEn: {
+		Title: map[string]string{
+			config.NotifyEventStart:          `Service started`,
+			config.NotifyEventWaitStop:       `Service stopping`,
+			config.NotifyEventStop:           `Service stopped`,
+			config.NotifyEventTcpNotAccept:   `Network peak`,
+			config.NotifyEventTcpStopAccept:  `Network peak`,
+			config.NotifyEventTcpReAccept:    `Network stable`,
+			config.NotifyEventSshBanned:      `SSH connection rejected`,
+			config.NotifyEventSshSuccess:     `SSH connection accepted`,
+			config.NotifyEventConfigRollback: `Config rollback`,
+			config.NotifyEventDigest:         `Digest ({{.Count}} messages)`,
+		},
+		Content: map[string]string{
+			config.NotifyEventStart:          `The service has started or restarted.`,
+			config.NotifyEventWaitStop:       `The service is about to stop (reason: {{or (truncate 512 .Reason) "none"}}).`,
+			config.NotifyEventStop:           `The service has stopped. Exit code: {{.ExitCode}}. Remaining goroutines: {{.Goroutines}}.`,
+			config.NotifyEventTcpNotAccept:   `Network peak, the TCP service stops accepting new connections.`,
+			config.NotifyEventTcpStopAccept:  `Network peak, all TCP services are offline.`,
+			config.NotifyEventTcpReAccept:    `Network is stable, the TCP service has recovered.`,
+			config.NotifyEventSshBanned:      `IP {{.IP}}{{with .Location}} ({{.}}){{end}} was rejected connecting to {{.Dest}} (reason: {{or (truncate 512 .Reason) "none"}}).`,
+			config.NotifyEventSshSuccess:     `IP {{.IP}}{{with .Location}} ({{.}}){{end}} connected to {{.Dest}} (note: {{or (truncate 512 .Reason) "none"}}).`,
+			config.NotifyEventConfigRollback: `The config file failed validation, the service started with the last-known-good config (reason: {{or (truncate 512 .Reason) "none"}}).`,
+			config.NotifyEventDigest: `{{.Count}} messages from {{time .StartTime}} to {{time .Time}}.
+{{- range .Sections}}
+{{label .Name}}:
+{{- range .Items}}
+  {{if .Rank}}{{.Rank}}. {{or (truncate 200 .Name) "none"}} ({{.Count}} times){{else}}{{or (truncate 200 .Name) "none"}}: {{.Count}}{{end}}
+{{- end}}
+{{- if .Omitted}}
+  {{.Omitted}} more omitted
+{{- end}}
+{{- end}}`,
+		},
+		Subject: `[{{.System}} Notification] {{.Title}}`,
+		Text:    `[{{.System}} Notification] {{.Content}}`,
+		Markdown: `**[{{.System}} Notification] {{.Title}}**
+{{.Content}}
+{{if .IP}}> Client IP: {{.IP}}
+{{with .Location}}> Location: {{.}}
+{{end}}> Destination: {{.Dest}}
+{{end}}> Severity: <font color="{{severityColor .Severity}}">{{.Severity}}</font>
+> Time: {{time .Time}}`,
+		HTML: `<html><body>
+<h3>[{{.System}} Notification] {{.Title}}</h3>
+<p>{{br .Content}}</p>
+<table border="1" cellpadding="4" cellspacing="0">
+{{if .IP}}<tr><td>Client IP</td><td>{{.IP}}</td></tr>
+{{with .Location}}<tr><td>Location</td><td>{{.}}</td></tr>
+{{end}}<tr><td>Destination</td><td>{{.Dest}}</td></tr>
+{{end}}<tr><td>Severity</td><td>{{.Severity}}</td></tr>
+<tr><td>Time</td><td>{{time .Time}}</td></tr>
+</table>
+</body></html>`,
+		Labels: map[string]string{
+			DigestSectionEvent:    "By event",
+			DigestSectionIP:       "By client IP",
+			DigestSectionReason:   "By reason",
+			DigestSectionDest:     "By destination",
+			DigestSectionOther:    "Other messages",
+			DigestSectionOffender: "Most rejected IPs",
+		},
+	},
+}
@@ -0,0 +1,130 @@
+package notifier
+
+import (
+	"fmt"
+	"github.com/SongZihuan/huan-springboard/src/config"
+	"github.com/SongZihuan/huan-springboard/src/logger"
+	"sync"
+	"time"
+)
+
+type Message struct {
+	Event    string    // 事件类型
+	Severity string    // 事件等级
+	Key      string    // 去重键（例如来访IP），为空时使用正文
+	IP       string    // 来访IP（仅连接类事件）
+	Dest     string    // 目标地址（仅连接类事件）
+	Reason   string    // 原因或备注（仅连接类事件）
+	Location string    // 来访IP位置（仅连接类事件）
+	Title    string    // 标题（邮件主题）
+	Content  string    // 正文
+	AtAll    bool      // 是否提醒全部成员（仅部分渠道支持）
+	Time     time.Time // 事件时间
+
+	ExitCode   int              // 退出代码（仅 stop）
+	Goroutines int              // 剩余协程数（仅 stop）
+	Count      int              // 汇总的消息数（仅 digest）
+	StartTime  time.Time        // 汇总的开始时间（仅 digest）
+	Sections   []MessageSection // 汇总的分类计数（仅 digest）
+}
+
+const (
+	DigestSectionEvent    = "event"
+	DigestSectionIP       = "ip"
+	DigestSectionReason   = "reason"
+	DigestSectionDest     = "dest"
+	DigestSectionOther    = "other"
+	DigestSectionOffender = "offender"
+)
+
+type MessageSection struct {
+	Name    string // 分类名称，见 DigestSection 系列常量
+	Items   []MessageCount
+	Omitted int // 省略的项数
+}
+
+type MessageCount struct {
+	Name  string
+	Count int
+	Rank  int // 排名，仅排行榜类分类使用，0 表示不显示排名
+}
+
+type Notifier interface {
+	Name() string
+	Type() string
+	Send(msg *Message) error
+}
+
+var registryLock sync.Mutex
+var registryConfig *config.YamlConfig
+var registry []Notifier
+
+// Notifiers 返回当前配置下的全部推送渠道，配置重载后会自动重建
+func Notifiers() []Notifier {
+	if !config.IsReady() {
+		panic("config is not ready")
+	}
+
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	cfg := config.GetConfig()
+	if registryConfig == cfg {
+		return registry
+	}
+
+	registry = newNotifiers(cfg)
+	registryConfig = cfg
+
+	return registry
+}
+
+func newNotifiers(cfg *config.YamlConfig) []Notifier {
+	res := make([]Notifier, 0, len(cfg.Notify.Channels)+2)
+
+	if cfg.API.Webhook != "" {
+		res = append(res, newWxRobotNotifier(config.NotifyTypeWxRobot, cfg.API.Webhook))
+	}
+
+	if cfg.SMTP.Address != "" && cfg.SMTP.User != "" {
+		res = append(res, newSmtpNotifier(config.NotifyTypeSMTP))
+	}
+
+	for _, t := range cfg.Notify.Templates {
+		err := CheckTemplate(t)
+		if err != nil {
+			logger.Errorf("notify template (event: %s, channel: %s) parse failed, use built-in template: %s", t.Event, t.Channel, err.Error())
+		}
+	}
+
+	for _, c := range cfg.Notify.Channels {
+		n, err := NewNotifier(c)
+		if err != nil {
+			logger.Errorf("create notify channel %s failed: %s", c.Name, err.Error())
+			continue
+		}
+
+		res = append(res, n)
+	}
+
+	return res
+}
+
+func NewNotifier(c *config.NotifyChannelConfig) (Notifier, error) {
+	switch c.Type {
+	case config.NotifyTypeWebhook:
+		return newWebhookNotifier(c)
+	case config.NotifyTypeTelegram:
+		return newTelegramNotifier(c), nil
+	case config.NotifyTypeDingTalk:
+		return newDingTalkNotifier(c), nil
+	case config.NotifyTypeFeishu:
+		return newFeishuNotifier(c), nil
+	case config.NotifyTypeSlack:
+		return newSlackNotifier(c), nil
+	case config.NotifyTypeWxRobot:
+		return newWxRobotNotifier(c.Name, c.URL), nil
+	default:
+		return nil, fmt.Errorf("unknown notify type: %s", c.Type)
+	}
+}
@@ -0,0 +1,347 @@
+package notifier
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/SongZihuan/huan-springboard/src/config"
+	"github.com/SongZihuan/huan-springboard/src/flagparser"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testConfig = `api:
+  app-code: x
+redis:
+  address: 127.0.0.1:6379
+sqlite:
+  path: %s
+`
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "hsb-notifier-test")
+	if err != nil {
+		panic(err)
+	}
+
+	path := filepath.Join(dir, "config.yaml")
+	err = os.WriteFile(path, []byte(fmt.Sprintf(testConfig, filepath.Join(dir, "test.db"))), 0600)
+	if err != nil {
+		panic(err)
+	}
+
+	// 子命令之前的选项由 flagparser 解析，测试自身的选项放在后面
+	os.Args = append([]string{os.Args[0], "--config", path}, os.Args[1:]...)
+	err = flagparser.InitFlag()
+	if err != nil {
+		panic(err)
+	}
+
+	cfgErr := config.InitConfig(path)
+	if cfgErr != nil && cfgErr.IsError() {
+		panic(cfgErr.Error())
+	}
+
+	code := m.Run()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// capture 本地 HTTP 替身收到的请求
+type capture struct {
+	method string
+	path   string
+	query  map[string][]string
+	header http.Header
+	body   []byte
+}
+
+func newStandIn(t *testing.T, resp string) (*httptest.Server, *capture) {
+	c := &capture{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.method = r.Method
+		c.path = r.URL.Path
+		c.query = r.URL.Query()
+		c.header = r.Header.Clone()
+		c.body, _ = io.ReadAll(r.Body)
+		_, _ = w.Write([]byte(resp))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, c
+}
+
+func testMessage(atAll bool) *Message {
+	return &Message{
+		Event:    "unit-test",
+		Severity: config.NotifySeverityWarning,
+		Title:    "test title",
+		Content:  "test content",
+		AtAll:    atAll,
+		Time:     time.Date(2025, 2, 16, 8, 0, 0, 0, time.UTC),
+	}
+}
+
+func decodeBody(t *testing.T, c *capture) map[string]any {
+	var res map[string]any
+	err := json.Unmarshal(c.body, &res)
+	if err != nil {
+		t.Fatalf("request body is not json: %s: %s", err.Error(), string(c.body))
+	}
+	return res
+}
+
+func TestWebhookSend(t *testing.T) {
+	srv, c := newStandIn(t, "")
+
+	n, err := NewNotifier(&config.NotifyChannelConfig{
+		Name:        "hook",
+		Type:        config.NotifyTypeWebhook,
+		URL:         srv.URL + "/notify",
+		Method:      http.MethodPut,
+		ContentType: "application/json",
+		Headers:     map[string]string{"X-Token": "abc"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = n.Send(testMessage(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.method != http.MethodPut || c.path != "/notify" {
+		t.Errorf("request = %s %s, want PUT /notify", c.method, c.path)
+	} else if c.header.Get("X-Token") != "abc" || c.header.Get("Content-Type") != "application/json" {
+		t.Errorf("headers not sent: %v", c.header)
+	}
+
+	body := decodeBody(t, c)
+	if body["system"] != config.GetConfig().SystemName || body["title"] != "test title" || body["content"] != "test content" || body["at_all"] != true {
+		t.Errorf("unexpected body: %v", body)
+	} else if body["time"] != "2025-02-16T08:00:00Z" {
+		t.Errorf("time = %v, want 2025-02-16T08:00:00Z", body["time"])
+	}
+}
+
+func TestWebhookCustomBody(t *testing.T) {
+	srv, c := newStandIn(t, "")
+
+	n, err := NewNotifier(&config.NotifyChannelConfig{
+		Name:        "hook",
+		Type:        config.NotifyTypeWebhook,
+		URL:         srv.URL,
+		Method:      http.MethodPost,
+		ContentType: "text/plain",
+		Body:        `{{.System}}|{{.Title}}|{{.Severity}}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = n.Send(testMessage(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := string(c.body), config.GetConfig().SystemName+"|test title|warning"; got != want {
+		t.Errorf("body = %q", got)
+	}
+}
+
+func TestWebhookBadStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	n, err := NewNotifier(&config.NotifyChannelConfig{Name: "hook", Type: config.NotifyTypeWebhook, URL: srv.URL, Method: http.MethodPost})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = n.Send(testMessage(false)); err == nil {
+		t.Error("expected error for status 500")
+	}
+}
+
+func TestTelegramSend(t *testing.T) {
+	srv, c := newStandIn(t, `{"ok": true}`)
+
+	n, err := NewNotifier(&config.NotifyChannelConfig{
+		Name:   "tg",
+		Type:   config.NotifyTypeTelegram,
+		URL:    srv.URL + "/",
+		Token:  "123:abc",
+		ChatID: "-100",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = n.Send(testMessage(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.path != "/bot123:abc/sendMessage" {
+		t.Errorf("path = %s", c.path)
+	}
+
+	body := decodeBody(t, c)
+	if body["chat_id"] != "-100" || !strings.Contains(fmt.Sprint(body["text"]), "test content") {
+		t.Errorf("unexpected body: %v", body)
+	}
+}
+
+func TestTelegramError(t *testing.T) {
+	srv, _ := newStandIn(t, `{"ok": false, "error_code": 400, "description": "chat not found"}`)
+
+	n, _ := NewNotifier(&config.NotifyChannelConfig{Name: "tg", Type: config.NotifyTypeTelegram, URL: srv.URL, Token: "t", ChatID: "1"})
+	err := n.Send(testMessage(false))
+	if err == nil || !strings.Contains(err.Error(), "chat not found") {
+		t.Errorf("err = %v, want chat not found", err)
+	}
+}
+
+func TestDingTalkSend(t *testing.T) {
+	srv, c := newStandIn(t, `{"errcode": 0, "errmsg": "ok"}`)
+
+	n, _ := NewNotifier(&config.NotifyChannelConfig{
+		Name:   "ding",
+		Type:   config.NotifyTypeDingTalk,
+		URL:    srv.URL + "/robot/send?access_token=tok",
+		Secret: "SECabc",
+	})
+
+	err := n.Send(testMessage(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.query["access_token"][0] != "tok" {
+		t.Errorf("access_token lost: %v", c.query)
+	}
+
+	timestamp := c.query["timestamp"][0]
+	ms, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || time.Since(time.UnixMilli(ms)) > time.Minute {
+		t.Errorf("bad timestamp %s", timestamp)
+	}
+
+	mac := hmac.New(sha256.New, []byte("SECabc"))
+	mac.Write([]byte(timestamp + "\nSECabc"))
+	if want := base64.StdEncoding.EncodeToString(mac.Sum(nil)); c.query["sign"][0] != want {
+		t.Errorf("sign = %s, want %s", c.query["sign"][0], want)
+	}
+
+	body := decodeBody(t, c)
+	at, _ := body["at"].(map[string]any)
+	text, _ := body["text"].(map[string]any)
+	if body["msgtype"] != "text" || at["isAtAll"] != true || !strings.Contains(fmt.Sprint(text["content"]), "test content") {
+		t.Errorf("unexpected body: %v", body)
+	}
+}
+
+func TestDingTalkNoSecret(t *testing.T) {
+	d := newDingTalkNotifier(&config.NotifyChannelConfig{URL: "https://example.com/robot/send?access_token=tok"})
+	if got := d.signURL(time.Now()); got != "https://example.com/robot/send?access_token=tok" {
+		t.Errorf("signURL = %s", got)
+	}
+
+	d = newDingTalkNotifier(&config.NotifyChannelConfig{URL: "https://example.com/robot/send", Secret: "s"})
+	if got := d.signURL(time.UnixMilli(1000)); !strings.HasPrefix(got, "https://example.com/robot/send?timestamp=1000&sign=") {
+		t.Errorf("signURL = %s", got)
+	}
+}
+
+func TestDingTalkError(t *testing.T) {
+	srv, _ := newStandIn(t, `{"errcode": 310000, "errmsg": "sign not match"}`)
+
+	n, _ := NewNotifier(&config.NotifyChannelConfig{Name: "ding", Type: config.NotifyTypeDingTalk, URL: srv.URL})
+	err := n.Send(testMessage(false))
+	if err == nil || !strings.Contains(err.Error(), "310000") {
+		t.Errorf("err = %v, want code 310000", err)
+	}
+}
+
+func TestFeishuSend(t *testing.T) {
+	srv, c := newStandIn(t, `{"code": 0, "msg": "success"}`)
+
+	n, _ := NewNotifier(&config.NotifyChannelConfig{
+		Name:   "feishu",
+		Type:   config.NotifyTypeFeishu,
+		URL:    srv.URL + "/open-apis/bot/v2/hook/xxx",
+		Secret: "sec",
+	})
+
+	err := n.Send(testMessage(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := decodeBody(t, c)
+	timestamp := fmt.Sprint(body["timestamp"])
+
+	mac := hmac.New(sha256.New, []byte(timestamp+"\nsec"))
+	if want := base64.StdEncoding.EncodeToString(mac.Sum(nil)); body["sign"] != want {
+		t.Errorf("sign = %v, want %s", body["sign"], want)
+	}
+
+	content, _ := body["content"].(map[string]any)
+	text := fmt.Sprint(content["text"])
+	if body["msg_type"] != "text" || !strings.HasPrefix(text, `<at user_id="all">`) || !strings.Contains(text, "test content") {
+		t.Errorf("unexpected body: %v", body)
+	}
+}
+
+func TestFeishuError(t *testing.T) {
+	for _, resp := range []string{`{"code": 19021, "msg": "sign match fail"}`, `{"StatusCode": 9499, "StatusMessage": "Bad Request"}`} {
+		srv, c := newStandIn(t, resp)
+
+		n, _ := NewNotifier(&config.NotifyChannelConfig{Name: "feishu", Type: config.NotifyTypeFeishu, URL: srv.URL})
+		if err := n.Send(testMessage(false)); err == nil {
+			t.Errorf("expected error for %s", resp)
+		}
+
+		if body := decodeBody(t, c); body["sign"] != nil || body["timestamp"] != nil {
+			t.Errorf("sign sent without secret: %v", body)
+		}
+	}
+}
+
+func TestSlackSend(t *testing.T) {
+	srv, c := newStandIn(t, "ok")
+
+	n, _ := NewNotifier(&config.NotifyChannelConfig{Name: "slack", Type: config.NotifyTypeSlack, URL: srv.URL + "/services/T/B/x"})
+
+	err := n.Send(testMessage(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := decodeBody(t, c)
+	text := fmt.Sprint(body["text"])
+	if !strings.HasPrefix(text, "<!channel> ") || !strings.Contains(text, "test content") {
+		t.Errorf("unexpected body: %v", body)
+	}
+}
+
+func TestSlackError(t *testing.T) {
+	srv, _ := newStandIn(t, "invalid_payload")
+
+	n, _ := NewNotifier(&config.NotifyChannelConfig{Name: "slack", Type: config.NotifyTypeSlack, URL: srv.URL})
+	err := n.Send(testMessage(false))
+	if err == nil || !strings.Contains(err.Error(), "invalid_payload") {
+		t.Errorf("err = %v, want invalid_payload", err)
+	}
+}
@@ -0,0 +1,57 @@
+package notifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/SongZihuan/huan-springboard/src/config"
+	"net/http"
+	"strings"
+)
+
+type slackNotifier struct {
+	config *config.NotifyChannelConfig
+	client *http.Client
+}
+
+type slackReq struct {
+	Text string `json:"text"`
+}
+
+func newSlackNotifier(c *config.NotifyChannelConfig) *slackNotifier {
+	return &slackNotifier{
+		config: c,
+		client: newHttpClient(c.TimeoutSeconds),
+	}
+}
+
+func (s *slackNotifier) Name() string {
+	return s.config.Name
+}
+
+func (s *slackNotifier) Type() string {
+	return config.NotifyTypeSlack
+}
+
+func (s *slackNotifier) Send(msg *Message) error {
+	text := Render(s.config.Name, msg).Text
+	if msg.AtAll {
+		text = "<!channel> " + text
+	}
+
+	reqData, err := json.Marshal(&slackReq{Text: text})
+	if err != nil {
+		return fmt.Errorf("json marshal error: %s", err.Error())
+	}
+
+	// Incoming Webhook 成功时返回纯文本 ok
+	respData, err := doRequest(s.client, http.MethodPost, s.config.URL, "application/json", nil, reqData)
+	if err != nil {
+		return err
+	}
+
+	if resp := strings.TrimSpace(string(respData)); resp != "ok" {
+		return fmt.Errorf("send message error: %s", resp)
+	}
+
+	return nil
+}
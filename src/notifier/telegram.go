@@ -0,0 +1,58 @@
+package notifier
+
+import (
+	"fmt"
+	"github.com/SongZihuan/huan-springboard/src/config"
+	"net/http"
+	"strings"
+)
+
+type telegramNotifier struct {
+	config *config.NotifyChannelConfig
+	client *http.Client
+}
+
+type telegramReq struct {
+	ChatID string `json:"chat_id"`
+	Text   string `json:"text"`
+}
+
+type telegramResp struct {
+	OK          bool   `json:"ok"`
+	ErrorCode   int    `json:"error_code"`
+	Description string `json:"description"`
+}
+
+func newTelegramNotifier(c *config.NotifyChannelConfig) *telegramNotifier {
+	return &telegramNotifier{
+		config: c,
+		client: newHttpClient(c.TimeoutSeconds),
+	}
+}
+
+func (t *telegramNotifier) Name() string {
+	return t.config.Name
+}
+
+func (t *telegramNotifier) Type() string {
+	return config.NotifyTypeTelegram
+}
+
+func (t *telegramNotifier) Send(msg *Message) error {
+	url := fmt.Sprintf("%s/bot%s/sendMessage", strings.TrimSuffix(t.config.URL, "/"), t.config.Token)
+
+	var resp telegramResp
+	err := postJSON(t.client, url, &telegramReq{
+		ChatID: t.config.ChatID,
+		Text:   Render(t.config.Name, msg).Text,
+	}, &resp)
+	if err != nil {
+		return err
+	}
+
+	if !resp.OK {
+		return fmt.Errorf("send message error [code: %d]: %s", resp.ErrorCode, resp.Description)
+	}
+
+	return nil
+}
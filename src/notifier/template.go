@@ -0,0 +1,243 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/SongZihuan/huan-springboard/src/config"
+	"github.com/SongZihuan/huan-springboard/src/logger"
+	"github.com/SongZihuan/huan-springboard/src/utils"
+	htmltemplate "html/template"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Rendered 按渠道渲染后的消息
+type Rendered struct {
+	Title    string // 标题
+	Subject  string // 邮件主题
+	Content  string // 正文
+	Text     string // 纯文本
+	Markdown string // Markdown
+	HTML     string // HTML
+}
+
+type templateData struct {
+	*Message
+	System     string
+	Locale     string
+	TimeString string
+}
+
+// templateCache 模板编译缓存，键为 类型+语言+模板源码
+var templateCache sync.Map
+
+type compiledTemplate struct {
+	text *template.Template
+	html *htmltemplate.Template
+	err  error
+}
+
+// Fill 使用全局模板（不区分渠道）填充消息的标题和正文，用于路由去重和汇总
+func Fill(msg *Message) *Message {
+	if msg.Title != "" && msg.Content != "" {
+		return msg
+	}
+
+	res := Render("", msg)
+	msg.Title = res.Title
+	msg.Content = res.Content
+
+	return msg
+}
+
+// Render 按渠道渲染消息：优先使用匹配程度最高的自定义模板，其次使用内置模板
+func Render(channel string, msg *Message) *Rendered {
+	cfg := &config.GetConfig().Notify
+
+	locale, ok := builtinLocales[cfg.Locale]
+	if !ok {
+		locale = builtinLocales[config.NotifyLocaleZh]
+	}
+
+	data := newTemplateData(msg, cfg.Locale)
+
+	pick := func(field func(t *config.NotifyTemplateConfig) string) string {
+		best := -1
+		res := ""
+
+		for _, t := range cfg.Templates {
+			score := t.Score(channel, msg.Event)
+			if score > best && field(t) != "" {
+				best = score
+				res = field(t)
+			}
+		}
+
+		return res
+	}
+
+	res := &Rendered{}
+
+	res.Title = renderText(cfg.Locale, pick(func(t *config.NotifyTemplateConfig) string { return t.Title }), locale.Title[msg.Event], msg.Title, data)
+	data.Title = res.Title
+
+	res.Content = renderText(cfg.Locale, pick(func(t *config.NotifyTemplateConfig) string { return t.Content }), locale.Content[msg.Event], msg.Content, data)
+	data.Content = res.Content
+
+	res.Subject = renderText(cfg.Locale, "", locale.Subject, res.Title, data)
+	res.Text = renderText(cfg.Locale, pick(func(t *config.NotifyTemplateConfig) string { return t.Text }), locale.Text, res.Content, data)
+	res.Markdown = renderText(cfg.Locale, pick(func(t *config.NotifyTemplateConfig) string { return t.Markdown }), locale.Markdown, res.Content, data)
+	res.HTML = renderHTML(cfg.Locale, pick(func(t *config.NotifyTemplateConfig) string { return t.HTML }), locale.HTML, htmltemplate.HTMLEscapeString(res.Content), data)
+
+	return res
+}
+
+func newTemplateData(msg *Message, locale string) *templateData {
+	m := *msg // 复制一份，避免修改原消息
+	if m.Time.IsZero() {
+		m.Time = time.Now()
+	}
+
+	return &templateData{
+		Message:    &m,
+		System:     config.GetConfig().SystemName,
+		Locale:     locale,
+		TimeString: m.Time.In(config.TimeZone()).Format(time.RFC3339),
+	}
+}
+
+// renderText 依次尝试自定义模板和内置模板，均不可用时返回 fallback
+func renderText(locale string, custom string, builtin string, fallback string, data *templateData) string {
+	for _, src := range []string{custom, builtin} {
+		if src == "" {
+			continue
+		}
+
+		c := getTemplate(false, locale, src)
+		if c.err != nil {
+			logger.Errorf("notify template parse error: %s", c.err.Error())
+			continue
+		}
+
+		var buf bytes.Buffer
+		err := c.text.Execute(&buf, data)
+		if err != nil {
+			logger.Errorf("notify template execute error: %s", err.Error())
+			continue
+		}
+
+		return strings.TrimSpace(buf.String())
+	}
+
+	return fallback
+}
+
+func renderHTML(locale string, custom string, builtin string, fallback string, data *templateData) string {
+	for _, src := range []string{custom, builtin} {
+		if src == "" {
+			continue
+		}
+
+		c := getTemplate(true, locale, src)
+		if c.err != nil {
+			logger.Errorf("notify html template parse error: %s", c.err.Error())
+			continue
+		}
+
+		var buf bytes.Buffer
+		err := c.html.Execute(&buf, data)
+		if err != nil {
+			logger.Errorf("notify html template execute error: %s", err.Error())
+			continue
+		}
+
+		return buf.String()
+	}
+
+	return fallback
+}
+
+func getTemplate(isHTML bool, locale string, src string) *compiledTemplate {
+	key := fmt.Sprintf("%t\x00%s\x00%s", isHTML, locale, src)
+	if c, ok := templateCache.Load(key); ok {
+		return c.(*compiledTemplate)
+	}
+
+	c := &compiledTemplate{}
+	if isHTML {
+		funcs := htmltemplate.FuncMap(templateFuncs(locale))
+		funcs["br"] = func(s string) htmltemplate.HTML {
+			return htmltemplate.HTML(strings.ReplaceAll(htmltemplate.HTMLEscapeString(s), "\n", "<br>"))
+		}
+		c.html, c.err = htmltemplate.New("notify").Funcs(funcs).Parse(src)
+	} else {
+		c.text, c.err = template.New("notify").Funcs(templateFuncs(locale)).Parse(src)
+	}
+
+	templateCache.Store(key, c)
+	return c
+}
+
+// CheckTemplate 检查自定义模板能否解析
+func CheckTemplate(t *config.NotifyTemplateConfig) error {
+	for _, src := range []string{t.Title, t.Content, t.Text, t.Markdown} {
+		if src == "" {
+			continue
+		}
+
+		if c := getTemplate(false, config.NotifyLocaleZh, src); c.err != nil {
+			return c.err
+		}
+	}
+
+	if t.HTML != "" {
+		if c := getTemplate(true, config.NotifyLocaleZh, t.HTML); c.err != nil {
+			return c.err
+		}
+	}
+
+	return nil
+}
+
+func templateFuncs(locale string) template.FuncMap {
+	labels := builtinLocales[config.NotifyLocaleZh].Labels
+	if l, ok := builtinLocales[locale]; ok {
+		labels = l.Labels
+	}
+
+	return template.FuncMap{
+		"json": func(v any) (string, error) {
+			res, err := json.Marshal(v)
+			if err != nil {
+				return "", err
+			}
+			return string(res), nil
+		},
+		"time": func(t time.Time) string {
+			return t.In(config.TimeZone()).Format(time.DateTime)
+		},
+		"truncate": func(maxBytes int, s string) string {
+			return utils.TruncateUTF8Ellipsis(s, maxBytes)
+		},
+		"label": func(name string) string {
+			if res, ok := labels[name]; ok {
+				return res
+			}
+			return name
+		},
+		"severityColor": func(severity string) string {
+			// 企业微信 Markdown 支持的颜色：info（绿色）、comment（灰色）、warning（橙红色）
+			switch severity {
+			case config.NotifySeverityCritical, config.NotifySeverityWarning:
+				return "warning"
+			case config.NotifySeverityNotice:
+				return "info"
+			default:
+				return "comment"
+			}
+		},
+	}
+}
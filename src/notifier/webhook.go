@@ -0,0 +1,97 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/SongZihuan/huan-springboard/src/config"
+	"net/http"
+	"text/template"
+	"time"
+)
+
+const defaultWebhookBody = `{"system": {{json .System}}, "title": {{json .Title}}, "content": {{json .Content}}, "at_all": {{.AtAll}}, "time": {{json .TimeString}}}`
+
+type webhookNotifier struct {
+	config *config.NotifyChannelConfig
+	client *http.Client
+	body   *template.Template
+}
+
+type webhookTemplateData struct {
+	*Message
+	System     string
+	Text       string
+	Markdown   string
+	HTML       string
+	TimeString string
+}
+
+var webhookFuncMap = template.FuncMap{
+	"json": func(v any) (string, error) {
+		res, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(res), nil
+	},
+}
+
+func newWebhookNotifier(c *config.NotifyChannelConfig) (*webhookNotifier, error) {
+	body := c.Body
+	if body == "" {
+		body = defaultWebhookBody
+	}
+
+	tpl, err := template.New(c.Name).Funcs(webhookFuncMap).Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("parse body template error: %s", err.Error())
+	}
+
+	return &webhookNotifier{
+		config: c,
+		client: newHttpClient(c.TimeoutSeconds),
+		body:   tpl,
+	}, nil
+}
+
+func (w *webhookNotifier) Name() string {
+	return w.config.Name
+}
+
+func (w *webhookNotifier) Type() string {
+	return config.NotifyTypeWebhook
+}
+
+func (w *webhookNotifier) Send(msg *Message) error {
+	t := msg.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	rendered := Render(w.config.Name, msg)
+
+	m := *msg
+	m.Title = rendered.Title
+	m.Content = rendered.Content
+
+	var buf bytes.Buffer
+	err := w.body.Execute(&buf, &webhookTemplateData{
+		Message:    &m,
+		System:     config.GetConfig().SystemName,
+		Text:       rendered.Text,
+		Markdown:   rendered.Markdown,
+		HTML:       rendered.HTML,
+		TimeString: t.In(config.TimeZone()).Format(time.RFC3339),
+	})
+	if err != nil {
+		return fmt.Errorf("execute body template error: %s", err.Error())
+	}
+
+	_, err = doRequest(w.client, w.config.Method, w.config.URL, w.config.ContentType, w.config.Headers, buf.Bytes())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
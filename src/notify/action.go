@@ -2,10 +2,10 @@ package notify
 
 import (
 	"github.com/SongZihuan/huan-springboard/src/config"
-	"github.com/SongZihuan/huan-springboard/src/smtpserver"
-	"github.com/SongZihuan/huan-springboard/src/wxrobot"
+	"github.com/SongZihuan/huan-springboard/src/notifier"
 	"runtime"
-	"sync"
+	"strings"
+	"time"
 )
 
 var hasSendStart = false
@@ -17,8 +17,11 @@ func SendStart() {
 		return
 	}
 
-	go wxrobot.SendStart()
-	go smtpserver.SendStart()
+	asyncSend(&notifier.Message{
+		Event:    config.NotifyEventStart,
+		Severity: config.NotifySeverityNotice,
+		AtAll:    true,
+	})
 
 	hasSendStart = true
 }
@@ -30,8 +33,12 @@ func SendWaitStop(reason string) {
 		return
 	}
 
-	go wxrobot.SendWaitStop(reason)
-	go smtpserver.SendWaitStop(reason)
+	asyncSend(&notifier.Message{
+		Event:    config.NotifyEventWaitStop,
+		Severity: config.NotifySeverityWarning,
+		Reason:   trimMark(reason),
+		AtAll:    true,
+	})
 }
 
 func AsyncSendStop(exitcode int) {
@@ -45,10 +52,7 @@ func AsyncSendStop(exitcode int) {
 		return
 	}
 
-	numGoroutine := runtime.NumGoroutine()
-
-	go wxrobot.SendStop(exitcode, numGoroutine)
-	go smtpserver.SendStop(exitcode, numGoroutine)
+	asyncSend(newStopMessage(exitcode, runtime.NumGoroutine()))
 }
 
 func SyncSendStop(exitcode int) {
@@ -62,22 +66,23 @@ func SyncSendStop(exitcode int) {
 		return
 	}
 
-	var wg sync.WaitGroup
-	wg.Add(2)
-
-	numGoroutine := runtime.NumGoroutine()
-
-	go func() {
-		defer wg.Done()
-		wxrobot.SendStop(exitcode, numGoroutine)
-	}()
+	FlushDigest()
+	syncSend(newStopMessage(exitcode, runtime.NumGoroutine()))
+}
 
-	go func() {
-		defer wg.Done()
-		smtpserver.SendStop(exitcode, numGoroutine)
-	}()
+func newStopMessage(exitcode int, numGoroutine int) *notifier.Message {
+	severity := config.NotifySeverityWarning
+	if exitcode != 0 {
+		severity = config.NotifySeverityCritical
+	}
 
-	wg.Wait()
+	return &notifier.Message{
+		Event:      config.NotifyEventStop,
+		Severity:   severity,
+		ExitCode:   exitcode,
+		Goroutines: numGoroutine,
+		AtAll:      true,
+	}
 }
 
 func SendTcpNotAccept() {
@@ -87,8 +92,11 @@ func SendTcpNotAccept() {
 		return
 	}
 
-	go wxrobot.SendTcpNotAccept()
-	go smtpserver.SendTcpNotAccept()
+	asyncSend(&notifier.Message{
+		Event:    config.NotifyEventTcpNotAccept,
+		Severity: config.NotifySeverityWarning,
+		AtAll:    true,
+	})
 }
 
 func SendTcpStopAccept() {
@@ -98,8 +106,11 @@ func SendTcpStopAccept() {
 		return
 	}
 
-	go wxrobot.SendTcpStopAccept()
-	go smtpserver.SendTcpStopAccept()
+	asyncSend(&notifier.Message{
+		Event:    config.NotifyEventTcpStopAccept,
+		Severity: config.NotifySeverityCritical,
+		AtAll:    true,
+	})
 }
 
 func SendTcpReAccept() {
@@ -109,28 +120,76 @@ func SendTcpReAccept() {
 		return
 	}
 
-	go wxrobot.SendTcpReAccept()
-	go smtpserver.SendTcpReAccept()
+	asyncSend(&notifier.Message{
+		Event:    config.NotifyEventTcpReAccept,
+		Severity: config.NotifySeverityNotice,
+		AtAll:    true,
+	})
+}
+
+func SendSshBanned(ip string, location string, to string, reason string) {
+	if !config.IsReady() {
+		panic("config is not ready")
+	} else if config.GetConfig().Quite.IsEnable(false) {
+		return
+	}
+
+	asyncSend(&notifier.Message{
+		Event:    config.NotifyEventSshBanned,
+		Severity: config.NotifySeverityWarning,
+		Key:      ip + " " + to,
+		IP:       ip,
+		Location: location,
+		Dest:     to,
+		Reason:   trimMark(reason),
+		AtAll:    true,
+	})
 }
 
-func SendSshBanned(ip string, to string, reason string) {
+func SendSshSuccess(ip string, location string, to string, mark string) {
 	if !config.IsReady() {
 		panic("config is not ready")
 	} else if config.GetConfig().Quite.IsEnable(false) {
 		return
 	}
 
-	go wxrobot.SendSshBanned(ip, to, reason)
-	go smtpserver.SendSshBanned(ip, to, reason)
+	asyncSend(&notifier.Message{
+		Event:    config.NotifyEventSshSuccess,
+		Severity: config.NotifySeverityInfo,
+		Key:      ip + " " + to,
+		IP:       ip,
+		Location: location,
+		Dest:     to,
+		Reason:   trimMark(mark),
+		AtAll:    false,
+	})
 }
 
-func SendSshSuccess(ip string, to string, mark string) {
+// SendConfigRollback 启动时配置文件检查失败，改为使用最后一次成功应用的配置
+func SendConfigRollback(reason string) {
 	if !config.IsReady() {
 		panic("config is not ready")
 	} else if config.GetConfig().Quite.IsEnable(false) {
 		return
 	}
 
-	go wxrobot.SendSshSuccess(ip, to, mark)
-	go smtpserver.SendSshSuccess(ip, to, mark)
+	asyncSend(&notifier.Message{
+		Event:    config.NotifyEventConfigRollback,
+		Severity: config.NotifySeverityCritical,
+		Reason:   trimMark(reason),
+		AtAll:    true,
+	})
+}
+
+func trimMark(mark string) string {
+	return strings.TrimSuffix(mark, "。")
+}
+
+// prepareMessage 设置事件时间，并按全局模板生成标题和正文
+func prepareMessage(msg *notifier.Message) *notifier.Message {
+	if msg.Time.IsZero() {
+		msg.Time = time.Now()
+	}
+
+	return notifier.Fill(msg)
 }
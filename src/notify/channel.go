@@ -0,0 +1,152 @@
+package notify
+
+import (
+	"github.com/SongZihuan/huan-springboard/src/config"
+	"github.com/SongZihuan/huan-springboard/src/logger"
+	"github.com/SongZihuan/huan-springboard/src/notifier"
+	"sync"
+	"time"
+)
+
+// channelState 单个渠道的限流和汇总状态
+type channelState struct {
+	lock     sync.Mutex
+	notifier notifier.Notifier
+
+	tokens     float64
+	tokensTime time.Time
+
+	digest      []*notifier.Message
+	digestTimer *time.Timer
+}
+
+var channelLock sync.Mutex
+var channels = make(map[string]*channelState)
+
+func getChannelState(n notifier.Notifier) *channelState {
+	channelLock.Lock()
+	defer channelLock.Unlock()
+
+	c, ok := channels[n.Name()]
+	if ok && c.notifier == n {
+		return c
+	}
+
+	if ok {
+		// 配置重载后渠道重建，旧状态中待发送的汇总提前发送
+		go c.flushDigest()
+	}
+
+	c = &channelState{
+		notifier: n,
+		tokens:   -1,
+	}
+	channels[n.Name()] = c
+
+	return c
+}
+
+// deliver 按渠道策略（限流、汇总）发送消息
+func (c *channelState) deliver(msg *notifier.Message) {
+	policy := config.GetConfig().Notify.ChannelPolicy(c.notifier.Name())
+	if policy == nil || config.NotifySeverityLevel(msg.Severity) >= config.NotifySeverityLevel(config.NotifySeverityCritical) {
+		c.takeToken(policy, msg.Time)
+		c.send(msg)
+		return
+	}
+
+	if policy.IsDigestEvent(msg.Event) {
+		c.addDigest(msg, policy)
+		return
+	}
+
+	if !c.takeToken(policy, msg.Time) {
+		if policy.DigestSeconds > 0 {
+			c.addDigest(msg, policy)
+		} else {
+			logger.Warnf("Notify %s rate limited, drop message: %s", c.notifier.Name(), msg.Title)
+		}
+		return
+	}
+
+	c.send(msg)
+}
+
+func (c *channelState) send(msg *notifier.Message) {
+	sendTo(c.notifier, msg)
+}
+
+// takeToken 令牌桶限流，返回 true 表示允许发送
+func (c *channelState) takeToken(policy *config.NotifyPolicyConfig, now time.Time) bool {
+	if policy == nil || policy.RateLimit <= 0 {
+		return true
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	capacity := float64(policy.RateLimit)
+	rate := capacity / float64(policy.RateLimitSeconds) // 每秒补充的令牌数
+
+	if c.tokens < 0 {
+		c.tokens = capacity
+	} else if now.After(c.tokensTime) {
+		c.tokens = min(capacity, c.tokens+now.Sub(c.tokensTime).Seconds()*rate)
+	}
+	c.tokensTime = now
+
+	if c.tokens < 1 {
+		return false
+	}
+
+	c.tokens -= 1
+	return true
+}
+
+func (c *channelState) addDigest(msg *notifier.Message, policy *config.NotifyPolicyConfig) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.digest = append(c.digest, msg)
+
+	if c.digestTimer == nil {
+		c.digestTimer = time.AfterFunc(time.Duration(policy.DigestSeconds)*time.Second, c.flushDigest)
+	}
+}
+
+func (c *channelState) flushDigest() {
+	c.lock.Lock()
+	msgs := c.digest
+	c.digest = nil
+	if c.digestTimer != nil {
+		c.digestTimer.Stop()
+		c.digestTimer = nil
+	}
+	c.lock.Unlock()
+
+	if len(msgs) == 0 {
+		return
+	}
+
+	c.send(newDigestMessage(msgs))
+}
+
+// FlushDigest 立即同步发送全部渠道中待发送的汇总（用于停止服务前）
+func FlushDigest() {
+	channelLock.Lock()
+	states := make([]*channelState, 0, len(channels))
+	for _, c := range channels {
+		states = append(states, c)
+	}
+	channelLock.Unlock()
+
+	var wg sync.WaitGroup
+	for _, c := range states {
+		wg.Add(1)
+		go func(c *channelState) {
+			defer wg.Done()
+			c.flushDigest()
+		}(c)
+	}
+	wg.Wait()
+}
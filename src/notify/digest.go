@@ -0,0 +1,126 @@
+package notify
+
+import (
+	"github.com/SongZihuan/huan-springboard/src/config"
+	"github.com/SongZihuan/huan-springboard/src/notifier"
+	"sort"
+)
+
+const digestTopN = 10
+const digestTopOffenders = 5
+
+type digestCount struct {
+	Name  string
+	Count int
+}
+
+// newDigestMessage 将多条消息合并为一条汇总消息
+func newDigestMessage(msgs []*notifier.Message) *notifier.Message {
+	start := msgs[0].Time
+	end := msgs[0].Time
+	severity := msgs[0].Severity
+
+	events := make(map[string]int)
+	ips := make(map[string]int)
+	reasons := make(map[string]int)
+	dests := make(map[string]int)
+	offenders := make(map[string]int)
+	other := make(map[string]int)
+
+	for _, m := range msgs {
+		if m.Time.Before(start) {
+			start = m.Time
+		}
+
+		if m.Time.After(end) {
+			end = m.Time
+		}
+
+		if config.NotifySeverityLevel(m.Severity) > config.NotifySeverityLevel(severity) {
+			severity = m.Severity
+		}
+
+		events[m.Event] += 1
+
+		if m.IP == "" {
+			other[m.Title] += 1
+			continue
+		}
+
+		ips[m.IP] += 1
+		dests[m.Dest] += 1
+		reasons[m.Reason] += 1
+
+		if m.Event == config.NotifyEventSshBanned {
+			offenders[m.IP] += 1
+		}
+	}
+
+	sections := make([]notifier.MessageSection, 0, 6)
+	sections = appendDigestSection(sections, notifier.DigestSectionEvent, events, 0, false)
+	sections = appendDigestSection(sections, notifier.DigestSectionIP, ips, digestTopN, false)
+	sections = appendDigestSection(sections, notifier.DigestSectionReason, reasons, digestTopN, false)
+	sections = appendDigestSection(sections, notifier.DigestSectionDest, dests, digestTopN, false)
+	sections = appendDigestSection(sections, notifier.DigestSectionOther, other, digestTopN, false)
+	sections = appendDigestSection(sections, notifier.DigestSectionOffender, offenders, digestTopOffenders, true)
+
+	return notifier.Fill(&notifier.Message{
+		Event:     config.NotifyEventDigest,
+		Severity:  severity,
+		AtAll:     false,
+		Time:      end,
+		Count:     len(msgs),
+		StartTime: start,
+		Sections:  sections,
+	})
+}
+
+// appendDigestSection 按计数从高到低添加分类，limit 大于 0 时只保留前 limit 项，rank 表示是否为排行榜
+func appendDigestSection(sections []notifier.MessageSection, name string, count map[string]int, limit int, rank bool) []notifier.MessageSection {
+	if len(count) == 0 {
+		return sections
+	}
+
+	lst := sortDigestCount(count)
+	section := notifier.MessageSection{
+		Name:  name,
+		Items: make([]notifier.MessageCount, 0, len(lst)),
+	}
+
+	for i, c := range lst {
+		if limit > 0 && i >= limit {
+			if !rank {
+				section.Omitted = len(lst) - limit
+			}
+			break
+		}
+
+		item := notifier.MessageCount{
+			Name:  c.Name,
+			Count: c.Count,
+		}
+		if rank {
+			item.Rank = i + 1
+		}
+
+		section.Items = append(section.Items, item)
+	}
+
+	return append(sections, section)
+}
+
+func sortDigestCount(count map[string]int) []digestCount {
+	res := make([]digestCount, 0, len(count))
+	for k, v := range count {
+		res = append(res, digestCount{Name: k, Count: v})
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Count != res[j].Count {
+			return res[i].Count > res[j].Count
+		}
+		return res[i].Name < res[j].Name
+	})
+
+	return res
+}
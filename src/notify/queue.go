@@ -0,0 +1,274 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/SongZihuan/huan-springboard/src/config"
+	"github.com/SongZihuan/huan-springboard/src/database"
+	"github.com/SongZihuan/huan-springboard/src/logger"
+	"github.com/SongZihuan/huan-springboard/src/notifier"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	queueStatusReady int32 = iota
+	queueStatusRunning
+	queueStatusStopping
+	queueStatusFinished
+)
+
+const queueBatchSize = 50
+const queuePollInterval = 1 * time.Second
+
+// queue 持久化发送队列：消息先写入 SQLite，发送失败后按指数退避重试，超过最大次数进入死信
+type queue struct {
+	status    atomic.Int32
+	abandoned atomic.Bool // 停止时超时放弃的发送不再写回数据库
+	wakeup    chan bool
+	stopchan  chan bool
+	swg       sync.WaitGroup
+}
+
+var queueLock sync.Mutex
+var queueObj *queue = nil
+
+// StartQueue 启动持久化发送队列，需要在数据库初始化后调用
+func StartQueue() error {
+	if !config.IsReady() {
+		panic("config is not ready")
+	}
+
+	if !config.GetConfig().Notify.Queue.Enable.IsEnable(true) {
+		return nil
+	}
+
+	if !database.IsReady() {
+		return fmt.Errorf("database is not ready")
+	}
+
+	queueLock.Lock()
+	defer queueLock.Unlock()
+
+	if queueObj != nil {
+		return nil
+	}
+
+	q := &queue{
+		wakeup:   make(chan bool, 1),
+		stopchan: make(chan bool),
+	}
+	q.status.Store(queueStatusRunning)
+
+	q.swg.Add(1)
+	go q.run()
+
+	queueObj = q
+	return nil
+}
+
+// StopQueue 停止队列，并在 drain-timeout-seconds 内尽量发送剩余消息，未发送的消息保留到下次启动
+func StopQueue() {
+	queueLock.Lock()
+	q := queueObj
+	queueLock.Unlock()
+
+	if q == nil || !q.status.CompareAndSwap(queueStatusRunning, queueStatusStopping) {
+		return
+	}
+
+	close(q.stopchan)
+	q.swg.Wait()
+
+	q.drain(time.Now().Add(time.Duration(config.GetConfig().Notify.Queue.DrainTimeoutSeconds) * time.Second))
+
+	q.status.Store(queueStatusFinished)
+}
+
+func getRunningQueue() *queue {
+	queueLock.Lock()
+	defer queueLock.Unlock()
+
+	if queueObj == nil || queueObj.status.Load() != queueStatusRunning {
+		return nil
+	}
+
+	return queueObj
+}
+
+func (q *queue) run() {
+	defer q.swg.Done()
+
+	defer func() {
+		if r := recover(); r != nil {
+			if err, ok := r.(error); ok {
+				logger.Panicf("notify queue panic error: %s", err.Error())
+			} else {
+				logger.Panicf("notify queue panic: %v", r)
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(queuePollInterval)
+	defer ticker.Stop()
+
+MainCycle:
+	for {
+		q.process(time.Now(), nil)
+
+		select {
+		case <-q.stopchan:
+			break MainCycle
+		case <-q.wakeup:
+			// pass
+		case <-ticker.C:
+			// pass
+		}
+	}
+}
+
+// enqueue 将消息写入队列，失败时返回错误（由调用方直接发送）
+func (q *queue) enqueue(n notifier.Notifier, msg *notifier.Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	_, err = database.AddNotifyQueue(n.Name(), string(data), time.Now())
+	if err != nil {
+		return err
+	}
+
+	select {
+	case q.wakeup <- true:
+	default:
+	}
+
+	return nil
+}
+
+// process 发送一批到期的消息（before 为零值时忽略重试等待时间），返回本批次的数量；
+// tried 不为 nil 时跳过其中的消息，并记录本批次发送的消息 ID
+func (q *queue) process(before time.Time, tried map[uint]bool) int {
+	var exclude []uint
+	for id := range tried {
+		exclude = append(exclude, id)
+	}
+
+	records, err := database.FindDueNotifyQueue(before, exclude, queueBatchSize)
+	if err != nil {
+		logger.Errorf("notify queue load error: %s", err.Error())
+		return 0
+	}
+
+	var wg sync.WaitGroup
+	for i := range records {
+		if tried != nil {
+			tried[records[i].ID] = true
+		}
+
+		wg.Add(1)
+		go func(record *database.NotifyQueue) {
+			defer wg.Done()
+			q.deliver(record)
+		}(&records[i])
+	}
+	wg.Wait()
+
+	return len(records)
+}
+
+// drain 忽略重试等待时间，尽量发送全部剩余消息，直到队列为空、全部失败或超时
+func (q *queue) drain(deadline time.Time) {
+	done := make(chan bool)
+
+	go func() {
+		defer close(done)
+
+		defer func() {
+			if r := recover(); r != nil {
+				if err, ok := r.(error); ok {
+					logger.Panicf("notify queue drain panic error: %s", err.Error())
+				} else {
+					logger.Panicf("notify queue drain panic: %v", r)
+				}
+			}
+		}()
+
+		// 每条消息最多尝试一次，避免反复发送必然失败的消息
+		tried := make(map[uint]bool)
+		for time.Now().Before(deadline) {
+			if q.process(time.Time{}, tried) == 0 {
+				break
+			}
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Until(deadline)):
+		q.abandoned.Store(true)
+		logger.Warnf("notify queue drain timeout, remaining messages will be sent after restart")
+	}
+}
+
+func (q *queue) deliver(record *database.NotifyQueue) {
+	n := findNotifier(record.Channel)
+	if n == nil {
+		q.fail(record, fmt.Errorf("channel not found"), true)
+		return
+	}
+
+	var msg notifier.Message
+	err := json.Unmarshal([]byte(record.Message), &msg)
+	if err != nil {
+		q.fail(record, fmt.Errorf("bad message: %s", err.Error()), true)
+		return
+	}
+
+	err = trySend(n, &msg)
+	if q.abandoned.Load() {
+		return
+	}
+
+	if err != nil {
+		q.fail(record, err, false)
+		return
+	}
+
+	err = database.DeleteNotifyQueue(record)
+	if err != nil {
+		logger.Errorf("notify queue delete record %d error: %s", record.ID, err.Error())
+	}
+}
+
+func (q *queue) fail(record *database.NotifyQueue, reason error, dead bool) {
+	cfg := config.GetConfig().Notify.Queue
+
+	record.Attempts += 1
+	record.LastError = reason.Error()
+
+	if dead || record.Attempts >= cfg.MaxAttempts {
+		record.Dead = true
+		logger.Errorf("Notify %s message %d dead after %d attempts: %s", record.Channel, record.ID, record.Attempts, record.LastError)
+	} else {
+		record.NextAt = time.Now().Add(cfg.RetryDelay(record.Attempts))
+		logger.Warnf("Notify %s message %d send fail (attempt %d), retry at %s: %s", record.Channel, record.ID, record.Attempts, record.NextAt.In(config.TimeZone()).Format(time.DateTime), record.LastError)
+	}
+
+	err := database.UpdateNotifyQueue(record)
+	if err != nil {
+		logger.Errorf("notify queue update record %d error: %s", record.ID, err.Error())
+	}
+}
+
+func findNotifier(name string) notifier.Notifier {
+	for _, n := range notifier.Notifiers() {
+		if n.Name() == name {
+			return n
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package notify
+
+import (
+	"github.com/SongZihuan/huan-springboard/src/config"
+	"github.com/SongZihuan/huan-springboard/src/logger"
+	"github.com/SongZihuan/huan-springboard/src/notifier"
+	"sync"
+	"time"
+)
+
+const windowCleanThreshold = 1024
+
+var windowLock sync.Mutex
+var dedupWindow = make(map[string]time.Time)    // 键：事件 + 去重键，值：窗口结束时间
+var suppressWindow = make(map[string]time.Time) // 键：事件，值：窗口结束时间
+
+// route 按路由规则、去重/抑制窗口和渠道策略选择需要发送的渠道
+func route(msg *notifier.Message) []notifier.Notifier {
+	cfg := config.GetConfig()
+
+	var channels map[string]bool = nil // nil 表示全部渠道
+
+	r := cfg.Notify.MatchRoute(msg.Event, msg.Severity)
+	if r != nil {
+		if len(r.Channels) == 0 {
+			logger.Debugf("notify %s is dropped by route", msg.Event)
+			return nil
+		}
+
+		if !checkWindow(r, msg) {
+			logger.Debugf("notify %s is suppressed by window", msg.Event)
+			return nil
+		}
+
+		channels = make(map[string]bool, len(r.Channels))
+		for _, c := range r.Channels {
+			channels[c] = true
+		}
+	}
+
+	all := notifier.Notifiers()
+	res := make([]notifier.Notifier, 0, len(all))
+
+	for _, n := range all {
+		if channels != nil && !channels[n.Name()] {
+			continue
+		}
+
+		policy := cfg.Notify.ChannelPolicy(n.Name())
+		if policy != nil && !policy.Allow(msg.Severity, msg.Time) {
+			continue
+		}
+
+		res = append(res, n)
+	}
+
+	return res
+}
+
+func checkWindow(r *config.NotifyRouteConfig, msg *notifier.Message) bool {
+	if r.DedupSeconds <= 0 && r.SuppressSeconds <= 0 {
+		return true
+	}
+
+	windowLock.Lock()
+	defer windowLock.Unlock()
+
+	now := msg.Time
+	cleanWindow(now)
+
+	dedupKey := msg.Key
+	if dedupKey == "" {
+		dedupKey = msg.Content
+	}
+	dedupKey = msg.Event + "|" + dedupKey
+
+	if r.SuppressSeconds > 0 && now.Before(suppressWindow[msg.Event]) {
+		return false
+	}
+
+	if r.DedupSeconds > 0 && now.Before(dedupWindow[dedupKey]) {
+		return false
+	}
+
+	if r.SuppressSeconds > 0 {
+		suppressWindow[msg.Event] = now.Add(time.Duration(r.SuppressSeconds) * time.Second)
+	}
+
+	if r.DedupSeconds > 0 {
+		dedupWindow[dedupKey] = now.Add(time.Duration(r.DedupSeconds) * time.Second)
+	}
+
+	return true
+}
+
+func cleanWindow(now time.Time) {
+	if len(dedupWindow) < windowCleanThreshold {
+		return
+	}
+
+	for k, v := range dedupWindow {
+		if !now.Before(v) {
+			delete(dedupWindow, k)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package notify
+
+import (
+	"fmt"
+	"github.com/SongZihuan/huan-springboard/src/logger"
+	"github.com/SongZihuan/huan-springboard/src/notifier"
+	"sync"
+)
+
+func asyncSend(msg *notifier.Message) {
+	prepareMessage(msg)
+
+	for _, n := range route(msg) {
+		go getChannelState(n).deliver(msg)
+	}
+}
+
+func syncSend(msg *notifier.Message) {
+	prepareMessage(msg)
+
+	var wg sync.WaitGroup
+
+	for _, n := range route(msg) {
+		wg.Add(1)
+		go func(n notifier.Notifier) {
+			defer wg.Done()
+			getChannelState(n).deliver(msg)
+		}(n)
+	}
+
+	wg.Wait()
+}
+
+// sendTo 发送消息，队列可用时写入持久化队列，否则直接发送
+func sendTo(n notifier.Notifier, msg *notifier.Message) {
+	q := getRunningQueue()
+	if q != nil {
+		err := q.enqueue(n, msg)
+		if err == nil {
+			return
+		}
+		logger.Errorf("Notify %s enqueue error, send directly: %s", n.Name(), err.Error())
+	}
+
+	err := trySend(n, msg)
+	if err != nil {
+		logger.Errorf("Notify %s (%s) Send Error: %s", n.Name(), n.Type(), err.Error())
+	}
+}
+
+func trySend(n notifier.Notifier, msg *notifier.Message) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(error); ok {
+				logger.Panicf("notify %s send panic error: %s", n.Name(), e.Error())
+				err = e
+			} else {
+				logger.Panicf("notify %s send panic: %v", n.Name(), r)
+				err = fmt.Errorf("panic: %v", r)
+			}
+		}
+	}()
+
+	return n.Send(msg)
+}
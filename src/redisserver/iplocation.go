@@ -12,20 +12,7 @@ import (
 func QueryIpLocation(ip string) (*apiip.QueryIpLocationData, error) {
 	key := fmt.Sprintf("ip:location:%s", ip)
 
-	cacheRes := func() *apiip.QueryIpLocationData {
-		res, err := rdb.Get(context.Background(), key).Result()
-		if err != nil {
-			return nil
-		}
-
-		var loc apiip.QueryIpLocationData
-		err = json.Unmarshal([]byte(res), &loc)
-		if err != nil {
-			return nil
-		}
-
-		return &loc
-	}()
+	cacheRes := CachedIpLocation(ip)
 	if cacheRes != nil {
 		return cacheRes, nil
 	}
@@ -47,3 +34,23 @@ func QueryIpLocation(ip string) (*apiip.QueryIpLocationData, error) {
 
 	return res, nil
 }
+
+// CachedIpLocation 只从缓存中查询IP定位，不调用API，未命中时返回 nil
+func CachedIpLocation(ip string) *apiip.QueryIpLocationData {
+	if rdb == nil {
+		return nil
+	}
+
+	res, err := rdb.Get(context.Background(), fmt.Sprintf("ip:location:%s", ip)).Result()
+	if err != nil {
+		return nil
+	}
+
+	var loc apiip.QueryIpLocationData
+	err = json.Unmarshal([]byte(res), &loc)
+	if err != nil {
+		return nil
+	}
+
+	return &loc
+}
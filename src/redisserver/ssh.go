@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/SongZihuan/huan-springboard/src/logger"
+	"strings"
 	"time"
 )
 
@@ -46,3 +47,62 @@ func QuerySSHIpBanned(ip string) bool { // 返回 true 表示放行
 		return false
 	}
 }
+
+// SSHIpBan Redis 中的一条 ssh 封禁
+type SSHIpBan struct {
+	IP  string
+	TTL time.Duration // 小于 0 表示永久
+}
+
+// BanSSHIp 设置 ssh 封禁（覆盖原有的封禁时长），ttl 小于等于 0 表示永久
+func BanSSHIp(ip string, ttl time.Duration) error {
+	key := fmt.Sprintf("ssh:ip:banned:%s", ip)
+
+	if ttl <= 0 {
+		ttl = 0 // 不设置过期时间
+	}
+
+	return rdb.Set(context.Background(), key, BannedData, ttl).Err()
+}
+
+// UnbanSSHIp 解除 ssh 封禁，返回封禁是否存在
+func UnbanSSHIp(ip string) (bool, error) {
+	key := fmt.Sprintf("ssh:ip:banned:%s", ip)
+
+	res, err := rdb.Del(context.Background(), key).Result()
+	if err != nil {
+		return false, err
+	}
+
+	return res > 0, nil
+}
+
+// ListSSHIpBanned 列出 Redis 中全部的 ssh 封禁
+func ListSSHIpBanned() ([]*SSHIpBan, error) {
+	const prefix = "ssh:ip:banned:"
+
+	res := make([]*SSHIpBan, 0, 10)
+	iter := rdb.Scan(context.Background(), 0, prefix+"*", 100).Iterator()
+	for iter.Next(context.Background()) {
+		key := iter.Val()
+
+		ttl, err := rdb.TTL(context.Background(), key).Result()
+		if err != nil {
+			return nil, err
+		} else if ttl == -2 { // 扫描后过期
+			continue
+		}
+
+		res = append(res, &SSHIpBan{
+			IP:  strings.TrimPrefix(key, prefix),
+			TTL: ttl,
+		})
+	}
+
+	err := iter.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
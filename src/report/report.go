@@ -0,0 +1,245 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/SongZihuan/huan-springboard/src/config"
+	"github.com/SongZihuan/huan-springboard/src/database"
+	"github.com/SongZihuan/huan-springboard/src/utils"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	FormatTable = "table"
+	FormatCSV   = "csv"
+	FormatJSON  = "json"
+)
+
+const (
+	DefaultLimit = 100
+	DefaultTop   = 10
+)
+
+const histogramWidth = 40
+
+// Options 连接记录的查询选项，命令行选项和管理接口的查询参数同名
+type Options struct {
+	IP     string // 来访 IP
+	To     string // 转发目标，例如 127.0.0.1:22
+	Since  string // 时间，或者时长（表示距今多久以前，例如 24h、7d）
+	Until  string // 同 Since
+	Accept string // true/false，为空表示不限制
+	Limit  int    // 列出记录的最大数量，0 表示不限制
+	Top    int    // 统计时排行的数量
+	Format string // table/csv/json
+}
+
+func (o *Options) Check() error {
+	switch o.Format {
+	case FormatTable, FormatCSV, FormatJSON:
+	default:
+		return fmt.Errorf("bad format '%s', must be table/csv/json", o.Format)
+	}
+
+	if o.Limit < 0 {
+		return fmt.Errorf("bad limit %d", o.Limit)
+	}
+
+	if o.Top <= 0 {
+		return fmt.Errorf("bad top %d, must be greater than 0", o.Top)
+	}
+
+	return nil
+}
+
+// Filter 转换为数据库的查询条件，时间使用配置文件中的时区
+func (o *Options) Filter() (*database.ConnectRecordFilter, error) {
+	var res database.ConnectRecordFilter
+
+	if o.IP != "" {
+		ip := net.ParseIP(o.IP)
+		if ip == nil {
+			return nil, fmt.Errorf("bad ip '%s'", o.IP)
+		}
+		res.IP = ip.String()
+	}
+
+	res.To = strings.TrimSpace(o.To)
+
+	now := time.Now()
+
+	since, err := parseTime(o.Since, now)
+	if err != nil {
+		return nil, err
+	}
+	res.Since = since
+
+	until, err := parseTime(o.Until, now)
+	if err != nil {
+		return nil, err
+	}
+	res.Until = until
+
+	if res.Since != nil && res.Until != nil && !res.Until.After(*res.Since) {
+		return nil, fmt.Errorf("until must be after since")
+	}
+
+	if o.Accept != "" {
+		accept, err := strconv.ParseBool(o.Accept)
+		if err != nil {
+			return nil, fmt.Errorf("bad accept '%s', must be true/false", o.Accept)
+		}
+		res.Accept = &accept
+	}
+
+	return &res, nil
+}
+
+// parseTime 解析时间或者时长（表示距今多久以前），为空时返回 nil
+func parseTime(str string, now time.Time) (*time.Time, error) {
+	if str == "" {
+		return nil, nil
+	}
+
+	t, err := utils.ParseTime(str, config.TimeZone())
+	if err == nil {
+		return &t, nil
+	}
+
+	d, durErr := utils.ParseDuration(str)
+	if durErr != nil {
+		return nil, fmt.Errorf("bad time '%s', must be a time like '%s' (or RFC3339) or a duration like 24h or 7d", str, utils.TimeLayout)
+	}
+
+	t = now.Add(-d)
+	return &t, nil
+}
+
+func formatTime(t time.Time) string {
+	return t.In(config.TimeZone()).Format(utils.TimeLayout)
+}
+
+func formatRatio(accepted int64, total int64) string {
+	if total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f%%", float64(accepted)*100/float64(total))
+}
+
+func writeJSON(writer io.Writer, v any) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// WriteRecords 输出连接记录
+func WriteRecords(writer io.Writer, format string, records []*database.ConnectRecord) error {
+	switch format {
+	case FormatJSON:
+		return writeJSON(writer, records)
+	case FormatCSV:
+		w := csv.NewWriter(writer)
+		_ = w.Write([]string{"id", "time", "from", "to", "accept", "time_consuming_ms", "mark"})
+		for _, r := range records {
+			timeConsuming := ""
+			if r.TimeConsuming != nil {
+				timeConsuming = strconv.FormatInt(*r.TimeConsuming, 10)
+			}
+
+			_ = w.Write([]string{strconv.FormatUint(uint64(r.ID), 10), r.Time.In(config.TimeZone()).Format(time.RFC3339), r.From, r.To, strconv.FormatBool(r.Accept), timeConsuming, r.Mark})
+		}
+		w.Flush()
+		return w.Error()
+	default:
+		tw := tabwriter.NewWriter(writer, 0, 4, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, "ID\tTIME\tFROM\tTO\tACCEPT\tDURATION\tMARK")
+		for _, r := range records {
+			timeConsuming := "-"
+			if r.TimeConsuming != nil {
+				timeConsuming = (time.Duration(*r.TimeConsuming) * time.Millisecond).String()
+			}
+
+			_, _ = fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%t\t%s\t%s\n", r.ID, formatTime(r.Time), r.From, r.To, r.Accept, timeConsuming, r.Mark)
+		}
+		return tw.Flush()
+	}
+}
+
+// WriteReport 输出连接记录的统计
+func WriteReport(writer io.Writer, format string, report *database.ConnectReport) error {
+	switch format {
+	case FormatJSON:
+		return writeJSON(writer, report)
+	case FormatCSV:
+		// 所有统计放在同一张表中，section 区分统计项
+		w := csv.NewWriter(writer)
+		_ = w.Write([]string{"section", "value", "total", "accepted", "rejected", "accept_ratio"})
+
+		row := func(section string, value string, total int64, accepted int64, rejected int64) {
+			ratio := ""
+			if total != 0 {
+				ratio = strconv.FormatFloat(float64(accepted)/float64(total), 'f', 4, 64)
+			}
+			_ = w.Write([]string{section, value, strconv.FormatInt(total, 10), strconv.FormatInt(accepted, 10), strconv.FormatInt(rejected, 10), ratio})
+		}
+
+		row("summary", "", report.Total, report.Accepted, report.Rejected)
+		for _, c := range report.TopRejectedIPs {
+			row("top-rejected-ip", c.Value, c.Total, c.Accepted, c.Rejected)
+		}
+		for _, c := range report.TopDestinations {
+			row("top-destination", c.Value, c.Total, c.Accepted, c.Rejected)
+		}
+		for _, h := range report.Hourly {
+			row("hourly", h.Hour.Format(time.RFC3339), h.Total, h.Accepted, h.Rejected)
+		}
+
+		w.Flush()
+		return w.Error()
+	default:
+		return writeReportTable(writer, report)
+	}
+}
+
+func writeReportTable(writer io.Writer, report *database.ConnectReport) error {
+	_, err := fmt.Fprintf(writer, "total: %d, accepted: %d, rejected: %d, accept ratio: %s\n", report.Total, report.Accepted, report.Rejected, formatRatio(report.Accepted, report.Total))
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(writer, 0, 4, 2, ' ', 0)
+
+	_, _ = fmt.Fprintln(tw, "\nTOP REJECTED IP\tTOTAL\tACCEPTED\tREJECTED\tACCEPT RATIO")
+	for _, c := range report.TopRejectedIPs {
+		_, _ = fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%s\n", c.Value, c.Total, c.Accepted, c.Rejected, formatRatio(c.Accepted, c.Total))
+	}
+
+	_, _ = fmt.Fprintln(tw, "\nTOP DESTINATION\tTOTAL\tACCEPTED\tREJECTED\tACCEPT RATIO")
+	for _, c := range report.TopDestinations {
+		_, _ = fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%s\n", c.Value, c.Total, c.Accepted, c.Rejected, formatRatio(c.Accepted, c.Total))
+	}
+
+	var peak int64 = 0
+	for _, h := range report.Hourly {
+		peak = max(peak, h.Total)
+	}
+
+	// 直方图：+ 表示接受的连接，- 表示拒绝的连接，按最多连接的小时缩放
+	_, _ = fmt.Fprintln(tw, "\nHOUR\tTOTAL\tACCEPTED\tREJECTED\tHISTOGRAM")
+	for _, h := range report.Hourly {
+		bar := ""
+		if peak > 0 {
+			bar = strings.Repeat("+", int(h.Accepted*histogramWidth/peak)) + strings.Repeat("-", int(h.Rejected*histogramWidth/peak))
+		}
+
+		_, _ = fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%s\n", h.Hour.Format("2006-01-02 15:00"), h.Total, h.Accepted, h.Rejected, bar)
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,103 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+)
+
+// attemptDelay 上一个连接未完成时，发起下一个连接前等待的时间（RFC 8305 建议 250ms）
+const attemptDelay = 250 * time.Millisecond
+
+// Interleave 交替排列主协议族和备用协议族的地址（RFC 8305），主协议族的地址在前
+func Interleave(primary []*net.TCPAddr, fallback []*net.TCPAddr) []*net.TCPAddr {
+	res := make([]*net.TCPAddr, 0, len(primary)+len(fallback))
+	for i := 0; i < len(primary) || i < len(fallback); i++ {
+		if i < len(primary) {
+			res = append(res, primary[i])
+		}
+		if i < len(fallback) {
+			res = append(res, fallback[i])
+		}
+	}
+	return res
+}
+
+// DialHappyEyeballs 按顺序发起到候选地址的连接：每隔 attemptDelay（或者上一个连接失败时立即）发起下一个连接，
+// 使用第一个成功的连接并关闭其余的连接，全部失败时返回最后一个错误；dialer 返回连接该协议族（tcp4 或 tcp6）时使用的 net.Dialer
+func DialHappyEyeballs(ctx context.Context, dialer func(network string) *net.Dialer, addrs []*net.TCPAddr) (net.Conn, error) {
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no address")
+	} else if len(addrs) == 1 {
+		return dialer(tcpNetwork(addrs[0])).DialContext(ctx, tcpNetwork(addrs[0]), addrs[0].String())
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+
+	results := make(chan result, len(addrs))
+	next := 0
+	pending := 0
+
+	var delay <-chan time.Time
+	start := func() {
+		addr := addrs[next]
+		next++
+		pending++
+
+		go func() {
+			conn, err := dialer(tcpNetwork(addr)).DialContext(ctx, tcpNetwork(addr), addr.String())
+			results <- result{conn: conn, err: err}
+		}()
+
+		if next < len(addrs) {
+			delay = time.After(attemptDelay)
+		} else {
+			delay = nil
+		}
+	}
+
+	start()
+
+	var lastErr error
+	for pending > 0 {
+		select {
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				cancel()
+				go func(n int) {
+					// 关闭同时建立成功的其余连接
+					for i := 0; i < n; i++ {
+						if r := <-results; r.conn != nil {
+							_ = r.conn.Close()
+						}
+					}
+				}(pending)
+				return r.conn, nil
+			}
+
+			lastErr = r.err
+			if next < len(addrs) {
+				start()
+			}
+		case <-delay:
+			start()
+		}
+	}
+
+	return nil, lastErr
+}
+
+func tcpNetwork(addr *net.TCPAddr) string {
+	if addr.IP.To4() != nil {
+		return "tcp4"
+	}
+	return "tcp6"
+}
@@ -0,0 +1,161 @@
+package resolver
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func tcpAddr(s string) *net.TCPAddr {
+	return net.TCPAddrFromAddrPort(netip.MustParseAddrPort(s))
+}
+
+func addrStrings(addrs []*net.TCPAddr) []string {
+	res := make([]string, 0, len(addrs))
+	for _, a := range addrs {
+		res = append(res, a.String())
+	}
+	return res
+}
+
+func TestInterleave(t *testing.T) {
+	a4, b4, c4 := tcpAddr("192.0.2.1:80"), tcpAddr("192.0.2.2:80"), tcpAddr("192.0.2.3:80")
+	a6, b6 := tcpAddr("[2001:db8::1]:80"), tcpAddr("[2001:db8::2]:80")
+
+	tests := []struct {
+		primary  []*net.TCPAddr
+		fallback []*net.TCPAddr
+		want     []*net.TCPAddr
+	}{
+		{nil, nil, []*net.TCPAddr{}},
+		{[]*net.TCPAddr{a4}, nil, []*net.TCPAddr{a4}},
+		{nil, []*net.TCPAddr{a6}, []*net.TCPAddr{a6}},
+		{[]*net.TCPAddr{a4, b4}, []*net.TCPAddr{a6, b6}, []*net.TCPAddr{a4, a6, b4, b6}},
+		{[]*net.TCPAddr{a4, b4, c4}, []*net.TCPAddr{a6}, []*net.TCPAddr{a4, a6, b4, c4}},
+		{[]*net.TCPAddr{a6}, []*net.TCPAddr{a4, b4, c4}, []*net.TCPAddr{a6, a4, b4, c4}},
+	}
+
+	for _, tt := range tests {
+		got := addrStrings(Interleave(tt.primary, tt.fallback))
+		want := addrStrings(tt.want)
+		if len(got) != len(want) {
+			t.Errorf("Interleave(%v, %v) = %v, want %v", addrStrings(tt.primary), addrStrings(tt.fallback), got, want)
+			continue
+		}
+
+		for i := range got {
+			if got[i] != want[i] {
+				t.Errorf("Interleave(%v, %v) = %v, want %v", addrStrings(tt.primary), addrStrings(tt.fallback), got, want)
+				break
+			}
+		}
+	}
+}
+
+// listen 本地监听，返回监听地址
+func listen(t *testing.T) (net.Listener, *net.TCPAddr) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = ln.Close()
+	})
+	return ln, ln.Addr().(*net.TCPAddr)
+}
+
+// deadAddr 没有监听的本地地址，连接会被立即拒绝
+func deadAddr(t *testing.T) *net.TCPAddr {
+	ln, addr := listen(t)
+	_ = ln.Close()
+	return addr
+}
+
+// slowDialer 连接 slow 时先等待 delay，模拟没有响应的地址
+func slowDialer(slow *net.TCPAddr, delay time.Duration) func(network string) *net.Dialer {
+	return func(network string) *net.Dialer {
+		return &net.Dialer{
+			Control: func(network, address string, c syscall.RawConn) error {
+				if address == slow.String() {
+					time.Sleep(delay)
+				}
+				return nil
+			},
+		}
+	}
+}
+
+func defaultDialer(network string) *net.Dialer {
+	return &net.Dialer{}
+}
+
+func TestDialHappyEyeballs(t *testing.T) {
+	_, live := listen(t)
+	dead := deadAddr(t)
+
+	if _, err := DialHappyEyeballs(context.Background(), defaultDialer, nil); err == nil {
+		t.Error("expected error for no address")
+	}
+
+	conn, err := DialHappyEyeballs(context.Background(), defaultDialer, []*net.TCPAddr{live})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = conn.Close()
+
+	// 失败的地址立即尝试下一个，不等待 attemptDelay
+	start := time.Now()
+	conn, err = DialHappyEyeballs(context.Background(), defaultDialer, []*net.TCPAddr{dead, deadAddr(t), live})
+	if err != nil {
+		t.Fatal(err)
+	} else if conn.RemoteAddr().String() != live.String() {
+		t.Errorf("connected to %s, want %s", conn.RemoteAddr().String(), live.String())
+	} else if elapsed := time.Since(start); elapsed >= attemptDelay {
+		t.Errorf("fallback after failure took %s, want less than %s", elapsed, attemptDelay)
+	}
+	_ = conn.Close()
+
+	_, err = DialHappyEyeballs(context.Background(), defaultDialer, []*net.TCPAddr{dead, deadAddr(t)})
+	if err == nil {
+		t.Error("expected error when all addresses are dead")
+	}
+}
+
+func TestDialHappyEyeballsSlow(t *testing.T) {
+	_, slow := listen(t)
+	_, live := listen(t)
+
+	const delay = time.Second
+
+	// 第一个地址没有响应时，attemptDelay 后连接下一个地址
+	start := time.Now()
+	conn, err := DialHappyEyeballs(context.Background(), slowDialer(slow, delay), []*net.TCPAddr{slow, live})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	elapsed := time.Since(start)
+	if conn.RemoteAddr().String() != live.String() {
+		t.Errorf("connected to %s, want %s", conn.RemoteAddr().String(), live.String())
+	} else if elapsed < attemptDelay || elapsed >= delay {
+		t.Errorf("connected after %s, want between %s and %s", elapsed, attemptDelay, delay)
+	}
+}
+
+func TestDialHappyEyeballsCancel(t *testing.T) {
+	_, slow := listen(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err := DialHappyEyeballs(ctx, slowDialer(slow, 300*time.Millisecond), []*net.TCPAddr{slow, deadAddr(t)})
+	if err == nil {
+		t.Error("expected error when context is done")
+	}
+}
@@ -0,0 +1,207 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// lookupTimeout 一次解析的超时时间
+const lookupTimeout = 10 * time.Second
+
+// Target 目标主机的一个协议族的地址：主机名按固定的间隔 refresh 定期重新解析（不使用 DNS 记录的 TTL），解析失败时保留上一次成功解析的地址
+type Target struct {
+	network string        // tcp4 或 tcp6
+	host    string        // 配置中的主机名或 IP 地址
+	port    int           // 配置中的端口（端口范围时为第一个端口）
+	zone    string        // ipv6 地址的 zone
+	refresh time.Duration // 重新解析的固定间隔，0 表示不重新解析（IP 地址或关闭了重新解析）
+
+	mu         sync.RWMutex
+	ips        []net.IP
+	expire     time.Time
+	refreshing atomic.Bool
+}
+
+// RefreshResult 一次重新解析的结果
+type RefreshResult struct {
+	Old []net.IP
+	New []net.IP // 解析失败时与 Old 相同
+	Err error
+}
+
+func (r *RefreshResult) Changed() bool {
+	return !slices.EqualFunc(r.Old, r.New, func(a net.IP, b net.IP) bool {
+		return a.Equal(b)
+	})
+}
+
+// NewTarget 解析 host 在 network（tcp4 或 tcp6）协议族的全部地址，没有地址时返回错误
+func NewTarget(network string, host string, port int, refresh time.Duration) (*Target, error) {
+	if network != "tcp4" && network != "tcp6" {
+		return nil, fmt.Errorf("unknown network %s", network)
+	}
+
+	res := &Target{
+		network: network,
+		host:    host,
+		port:    port,
+		refresh: refresh,
+	}
+
+	ipStr, zone, _ := strings.Cut(host, "%")
+	if ip := net.ParseIP(ipStr); ip != nil {
+		if (ip.To4() != nil) != (network == "tcp4") {
+			return nil, fmt.Errorf("%s is not an %s address", host, res.family())
+		}
+
+		res.ips = []net.IP{ip}
+		res.zone = zone
+		res.refresh = 0
+		return res, nil
+	}
+
+	ips, err := res.lookup()
+	if err != nil {
+		return nil, err
+	}
+
+	res.ips = ips
+	res.expire = time.Now().Add(refresh)
+	return res, nil
+}
+
+func (t *Target) family() string {
+	if t.network == "tcp4" {
+		return "ipv4"
+	}
+	return "ipv6"
+}
+
+func (t *Target) lookup() ([]net.IP, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), lookupTimeout)
+	defer cancel()
+
+	network := "ip4"
+	if t.network == "tcp6" {
+		network = "ip6"
+	}
+
+	ips, err := net.DefaultResolver.LookupIP(ctx, network, t.host)
+	if err != nil {
+		return nil, err
+	} else if len(ips) == 0 {
+		return nil, fmt.Errorf("%s has no %s address", t.host, t.family())
+	}
+
+	return ips, nil
+}
+
+func (t *Target) Network() string {
+	return t.network
+}
+
+func (t *Target) Host() string {
+	return t.host
+}
+
+// Port 配置中的端口（端口范围时为第一个端口）
+func (t *Target) Port() int {
+	return t.port
+}
+
+// String 配置中的主机名和端口
+func (t *Target) String() string {
+	return net.JoinHostPort(t.host, strconv.Itoa(t.port))
+}
+
+// Equal 配置（协议族、主机名、端口和重新解析间隔）是否相同，不比较解析结果和过期时间
+func (t *Target) Equal(other *Target) bool {
+	if t == nil || other == nil {
+		return t == other
+	}
+
+	return t.network == other.network && t.host == other.host && t.port == other.port && t.zone == other.zone && t.refresh == other.refresh
+}
+
+// IPs 当前的全部地址
+func (t *Target) IPs() []net.IP {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return slices.Clone(t.ips)
+}
+
+// Addr 目标的一个端口
+func (t *Target) Addr(port int) *Addr {
+	return &Addr{
+		Target: t,
+		Port:   port,
+	}
+}
+
+// RefreshIfExpired 距上一次解析超过 refresh 间隔时重新解析，未过期、不需要重新解析或者正在解析时返回 nil
+func (t *Target) RefreshIfExpired() *RefreshResult {
+	if t.refresh <= 0 {
+		return nil
+	}
+
+	t.mu.RLock()
+	expire := t.expire
+	t.mu.RUnlock()
+
+	if time.Now().Before(expire) || !t.refreshing.CompareAndSwap(false, true) {
+		return nil
+	}
+	defer t.refreshing.Store(false)
+
+	ips, err := t.lookup()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	res := &RefreshResult{
+		Old: t.ips,
+		New: t.ips,
+		Err: err,
+	}
+
+	t.expire = time.Now().Add(t.refresh)
+	if err == nil {
+		t.ips = ips
+		res.New = ips
+	}
+
+	return res
+}
+
+// Addr 目标的一个端口，实现 net.Addr，String 为配置中的主机名和端口
+type Addr struct {
+	Target *Target
+	Port   int
+}
+
+func (a *Addr) Network() string {
+	return a.Target.network
+}
+
+func (a *Addr) String() string {
+	return net.JoinHostPort(a.Target.host, strconv.Itoa(a.Port))
+}
+
+// TCPAddrs 当前解析到的全部地址
+func (a *Addr) TCPAddrs() []*net.TCPAddr {
+	ips := a.Target.IPs()
+
+	res := make([]*net.TCPAddr, 0, len(ips))
+	for _, ip := range ips {
+		res = append(res, &net.TCPAddr{IP: ip, Port: a.Port, Zone: a.Target.zone})
+	}
+
+	return res
+}
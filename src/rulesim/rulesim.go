@@ -0,0 +1,224 @@
+package rulesim
+
+import (
+	"fmt"
+	"github.com/SongZihuan/huan-springboard/src/api/apiip"
+	"github.com/SongZihuan/huan-springboard/src/config"
+	"io"
+	"net"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	ComponentTCP = "tcp"
+	ComponentSSH = "ssh"
+)
+
+// 每一步检查的结果
+const (
+	ResultAllow = "allow" // 命中并放行
+	ResultDeny  = "deny"  // 命中并拒绝
+	ResultPass  = "pass"  // 检查通过，继续下一步
+	ResultSkip  = "skip"  // 不适用，跳过
+)
+
+// Location 指定来访 IP 的定位，不为 nil 时不查询 IP 定位
+type Location struct {
+	Nation   string `json:"nation"`
+	Province string `json:"province"`
+	City     string `json:"city"`
+	Isp      string `json:"isp"`
+}
+
+// Data 转换为 IP 定位查询结果，l 为 nil 时返回 nil
+func (l *Location) Data(ip string) *apiip.QueryIpLocationData {
+	if l == nil {
+		return nil
+	}
+
+	return &apiip.QueryIpLocationData{
+		Nation:   l.Nation,
+		Province: l.Province,
+		City:     l.City,
+		Ip:       ip,
+		Isp:      l.Isp,
+	}
+}
+
+// LocationOf 由 IP 定位查询结果生成，d 为 nil 时返回 nil
+func LocationOf(d *apiip.QueryIpLocationData) *Location {
+	if d == nil {
+		return nil
+	}
+
+	return &Location{
+		Nation:   d.Nation,
+		Province: d.Province,
+		City:     d.City,
+		Isp:      d.Isp,
+	}
+}
+
+func (l *Location) String() string {
+	res := make([]string, 0, 4)
+	for _, s := range []string{l.Nation, l.Province, l.City, l.Isp} {
+		if s != "" {
+			res = append(res, s)
+		}
+	}
+	return strings.Join(res, " ")
+}
+
+// Request 模拟的来访连接
+type Request struct {
+	Component string    `json:"component"` // tcp 或 ssh
+	IP        string    `json:"ip"`
+	Port      int64     `json:"port"`     // 转发的监听端口，0 表示不指定
+	Location  *Location `json:"location"` // 为空表示查询 IP 定位
+}
+
+func (r *Request) Check() (net.IP, error) {
+	if r.Component != ComponentTCP && r.Component != ComponentSSH {
+		return nil, fmt.Errorf("bad component '%s', must be tcp/ssh", r.Component)
+	}
+
+	ip := net.ParseIP(r.IP)
+	if ip == nil {
+		return nil, fmt.Errorf("bad ip '%s'", r.IP)
+	}
+
+	if r.Port < 0 || r.Port > 65535 {
+		return nil, fmt.Errorf("bad port %d", r.Port)
+	}
+
+	return ip, nil
+}
+
+// Step 一步检查，Rule 与访问日志中的规则名称相同
+type Step struct {
+	Rule   string `json:"rule"`
+	Result string `json:"result"`
+	Reason string `json:"reason"`
+}
+
+// Result 模拟结果，Steps 按检查顺序记录每一步（包括被跳过的规则及原因）
+type Result struct {
+	Component string    `json:"component"`
+	IP        string    `json:"ip"`
+	Port      int64     `json:"port,omitempty"`
+	Allow     bool      `json:"allow"`
+	Rule      string    `json:"rule"`
+	Reason    string    `json:"reason,omitempty"`
+	Location  *Location `json:"location,omitempty"`
+	Steps     []*Step   `json:"steps"`
+}
+
+func NewResult(req *Request) *Result {
+	return &Result{
+		Component: req.Component,
+		IP:        req.IP,
+		Port:      req.Port,
+		Location:  req.Location,
+		Steps:     make([]*Step, 0, 10),
+	}
+}
+
+// LocationStep 记录检查时使用的 IP 定位，res 为 nil（非模拟）时不记录
+func (res *Result) LocationStep(d *apiip.QueryIpLocationData) {
+	if res == nil {
+		return
+	}
+
+	res.Location = LocationOf(d)
+	res.Step("ip-location", ResultPass, "%s", res.Location.String())
+}
+
+// Step 记录一步检查，res 为 nil（非模拟）时不记录
+func (res *Result) Step(rule string, result string, format string, args ...any) {
+	if res == nil {
+		return
+	}
+
+	res.Steps = append(res.Steps, &Step{
+		Rule:   rule,
+		Result: result,
+		Reason: fmt.Sprintf(format, args...),
+	})
+}
+
+// RuleStep 记录一条规则的检查结果，原因后附加规则的匹配条件，res 为 nil（非模拟）时不记录
+func (res *Result) RuleStep(rule string, result string, reason string, r *config.RuleConfig) {
+	if res == nil {
+		return
+	}
+
+	res.Step(rule, result, "%s (%s)", reason, DescribeRule(r))
+}
+
+func (res *Result) Fprint(writer io.Writer) error {
+	verdict := ResultDeny
+	if res.Allow {
+		verdict = ResultAllow
+	}
+
+	title := fmt.Sprintf("%s %s", res.Component, res.IP)
+	if res.Port != 0 {
+		title = fmt.Sprintf("%s (port %d)", title, res.Port)
+	}
+
+	_, err := fmt.Fprintf(writer, "%s: %s by %s\n", title, verdict, res.Rule)
+	if err != nil {
+		return err
+	}
+
+	if res.Reason != "" {
+		_, _ = fmt.Fprintf(writer, "reason: %s\n", res.Reason)
+	}
+
+	if res.Location != nil {
+		_, _ = fmt.Fprintf(writer, "location: %s\n", res.Location.String())
+	}
+
+	_, _ = fmt.Fprintln(writer)
+
+	tw := tabwriter.NewWriter(writer, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "RULE\tRESULT\tREASON")
+	for _, s := range res.Steps {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\n", s.Rule, s.Result, s.Reason)
+	}
+
+	return tw.Flush()
+}
+
+// DescribeRule 规则的匹配条件，例如：ipv4cidr=10.0.0.0/8 nation=中国 banned
+func DescribeRule(r *config.RuleConfig) string {
+	var res []string
+
+	add := func(name string, value string) {
+		if value != "" {
+			res = append(res, fmt.Sprintf("%s=%s", name, value))
+		}
+	}
+
+	add("ipv4", r.IPv4)
+	add("ipv6", r.IPv6)
+	add("ipv4cidr", r.IPv4Cidr)
+	add("ipv6cidr", r.IPv6Cidr)
+	add("nation", r.Nation)
+	add("nation-vague", r.NationVague)
+	add("province", r.Province)
+	add("province-vague", r.ProvinceVague)
+	add("city", r.City)
+	add("city-vague", r.CityVague)
+	add("isp", r.ISP)
+	add("isp-vague", r.ISPVague)
+
+	if r.Banned.ToBool(true) {
+		res = append(res, "banned")
+	} else {
+		res = append(res, "allowed")
+	}
+
+	return strings.Join(res, " ")
+}
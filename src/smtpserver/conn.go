@@ -0,0 +1,181 @@
+package smtpserver
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/SongZihuan/huan-springboard/src/config"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+type smtpConn struct {
+	conn   net.Conn
+	client *smtp.Client
+	timer  *time.Timer // 空闲超时关闭
+}
+
+func (c *smtpConn) setDeadline() {
+	_ = c.conn.SetDeadline(time.Now().Add(smtpTimeout))
+}
+
+func (c *smtpConn) close() {
+	c.setDeadline()
+	_ = c.client.Quit()
+	_ = c.conn.Close()
+}
+
+func splitAddress(address string) (host string, port string, err error) {
+	const missingPort = "missing port in address"
+	host, port, err = net.SplitHostPort(address)
+	var addrErr *net.AddrError
+	if errors.As(err, &addrErr) {
+		if addrErr.Err == missingPort {
+			host = address
+			port = "25"
+			if smtpTLS == config.SMTPTLSImplicit {
+				port = "465"
+			}
+		} else {
+			return "", "", err
+		}
+	} else if err != nil {
+		return "", "", err
+	}
+
+	return host, port, nil
+}
+
+// dial 建立连接并完成 TLS 和认证，TLS 模式不满足时直接失败，不会降级为明文
+func dial() (*smtpConn, error) {
+	host, port, err := splitAddress(smtpAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsconfig := &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: false,
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   smtpTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+
+	var conn net.Conn
+	if smtpTLS == config.SMTPTLSImplicit {
+		conn, err = tls.DialWithDialer(dialer, "tcp", net.JoinHostPort(host, port), tlsconfig)
+		if err != nil {
+			return nil, fmt.Errorf("tls dial: %v", err)
+		}
+	} else {
+		conn, err = dialer.Dial("tcp", net.JoinHostPort(host, port))
+		if err != nil {
+			return nil, err
+		}
+	}
+	_ = conn.SetDeadline(time.Now().Add(smtpTimeout))
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("new smtp client: %v", err)
+	}
+
+	res := &smtpConn{
+		conn:   conn,
+		client: client,
+	}
+
+	err = prepare(res.client, host, tlsconfig)
+	if err != nil {
+		res.close()
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func prepare(client *smtp.Client, host string, tlsconfig *tls.Config) error {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+
+	if err = client.Hello(hostname); err != nil {
+		return fmt.Errorf("hello: %v", err)
+	}
+
+	if smtpTLS == config.SMTPTLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("start tls: server does not support STARTTLS")
+		}
+
+		if err = client.StartTLS(tlsconfig); err != nil {
+			return fmt.Errorf("start tls: %v", err)
+		}
+	}
+
+	return authenticate(client, host)
+}
+
+func authenticate(client *smtp.Client, host string) error {
+	if smtpAuth == config.SMTPAuthNone {
+		return nil
+	}
+
+	canAuth, options := client.Extension("AUTH")
+	if !canAuth {
+		if smtpAuth == config.SMTPAuthAuto {
+			return nil
+		}
+		return fmt.Errorf("auth: server does not support AUTH")
+	}
+
+	mode := smtpAuth
+	if mode == config.SMTPAuthAuto {
+		if smtpOAuth2.IsSet() {
+			mode = config.SMTPAuthXOAuth2
+		} else if strings.Contains(options, "CRAM-MD5") {
+			mode = config.SMTPAuthCramMD5
+		} else if strings.Contains(options, "PLAIN") {
+			mode = config.SMTPAuthPlain
+		} else if strings.Contains(options, "LOGIN") {
+			mode = config.SMTPAuthLogin
+		} else {
+			return nil
+		}
+	}
+
+	if !strings.Contains(options, strings.ToUpper(mode)) {
+		return fmt.Errorf("auth: server does not support %s (supported: %s)", strings.ToUpper(mode), options)
+	}
+
+	var auth smtp.Auth
+	switch mode {
+	case config.SMTPAuthXOAuth2:
+		token, err := oauth2Token(&smtpOAuth2, smtpTimeout)
+		if err != nil {
+			return fmt.Errorf("auth: %s", err.Error())
+		}
+		auth = XOAuth2Auth(smtpUser, token)
+	case config.SMTPAuthCramMD5:
+		auth = smtp.CRAMMD5Auth(smtpUser, smtpPassword)
+	case config.SMTPAuthPlain:
+		auth = smtp.PlainAuth("", smtpUser, smtpPassword, host)
+	case config.SMTPAuthLogin:
+		auth = LoginAuth(smtpUser, smtpPassword)
+	default:
+		return fmt.Errorf("auth: unknown auth %s", mode)
+	}
+
+	if err := client.Auth(auth); err != nil {
+		return fmt.Errorf("auth: %s", err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package smtpserver
+
+import (
+	"fmt"
+	"strings"
+)
+
+type RecipientError struct {
+	Address string
+	Err     error
+}
+
+// DeliveryError 记录每个收件人的投递失败原因，Delivered 为成功投递的收件人数
+type DeliveryError struct {
+	Failed    []*RecipientError
+	Delivered int
+}
+
+func (e *DeliveryError) add(address string, err error) {
+	e.Failed = append(e.Failed, &RecipientError{
+		Address: address,
+		Err:     err,
+	})
+}
+
+// Partial 是否至少投递给了一个收件人
+func (e *DeliveryError) Partial() bool {
+	return e.Delivered > 0
+}
+
+func (e *DeliveryError) Error() string {
+	res := make([]string, 0, len(e.Failed))
+	for _, f := range e.Failed {
+		res = append(res, fmt.Sprintf("%s: %s", f.Address, f.Err.Error()))
+	}
+
+	return fmt.Sprintf("%d recipient(s) failed, %d delivered: %s", len(e.Failed), e.Delivered, strings.Join(res, "; "))
+}
@@ -0,0 +1,107 @@
+package smtpserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/SongZihuan/huan-springboard/src/config"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type oauth2TokenResp struct {
+	AccessToken      string `json:"access_token"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+var tokenLock sync.Mutex
+var cachedToken string
+var cachedTokenExpiry time.Time
+
+// oauth2Token 返回 XOAUTH2 使用的访问令牌：优先使用 refresh-token 刷新，其次读取令牌文件，最后使用固定令牌
+func oauth2Token(cfg *config.SMTPOAuth2Config, timeout time.Duration) (string, error) {
+	if cfg.TokenURL != "" {
+		return refreshOAuth2Token(cfg, timeout)
+	}
+
+	if cfg.TokenFile != "" {
+		data, err := os.ReadFile(cfg.TokenFile)
+		if err != nil {
+			return "", fmt.Errorf("read oauth2 token file error: %s", err.Error())
+		}
+
+		token := strings.TrimSpace(string(data))
+		if token == "" {
+			return "", fmt.Errorf("oauth2 token file is empty")
+		}
+
+		return token, nil
+	}
+
+	if cfg.AccessToken != "" {
+		return cfg.AccessToken, nil
+	}
+
+	return "", fmt.Errorf("oauth2 is not set")
+}
+
+func refreshOAuth2Token(cfg *config.SMTPOAuth2Config, timeout time.Duration) (string, error) {
+	tokenLock.Lock()
+	defer tokenLock.Unlock()
+
+	// 提前一分钟刷新
+	if cachedToken != "" && time.Now().Add(time.Minute).Before(cachedTokenExpiry) {
+		return cachedToken, nil
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", cfg.RefreshToken)
+	form.Set("client_id", cfg.ClientID)
+	if cfg.ClientSecret != "" {
+		form.Set("client_secret", cfg.ClientSecret)
+	}
+	if len(cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(cfg.Scopes, " "))
+	}
+
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.PostForm(cfg.TokenURL, form)
+	if err != nil {
+		return "", fmt.Errorf("oauth2 refresh token error: %s", err.Error())
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	respData, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("read oauth2 response error: %s", err.Error())
+	}
+
+	var tokenResp oauth2TokenResp
+	err = json.Unmarshal(respData, &tokenResp)
+	if err != nil {
+		return "", fmt.Errorf("json unmarshal oauth2 response error: %s", err.Error())
+	}
+
+	if resp.StatusCode != http.StatusOK || tokenResp.AccessToken == "" {
+		return "", fmt.Errorf("oauth2 refresh token error [status: %d]: %s %s", resp.StatusCode, tokenResp.Error, tokenResp.ErrorDescription)
+	}
+
+	expiresIn := tokenResp.ExpiresIn
+	if expiresIn <= 0 {
+		expiresIn = 3600
+	}
+
+	cachedToken = tokenResp.AccessToken
+	cachedTokenExpiry = time.Now().Add(time.Duration(expiresIn) * time.Second)
+
+	return cachedToken, nil
+}
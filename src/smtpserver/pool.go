@@ -0,0 +1,91 @@
+package smtpserver
+
+import (
+	"sync"
+	"time"
+)
+
+var poolLock sync.Mutex
+var poolIdle []*smtpConn
+var poolClosed = false
+
+// getConn 取出一个可用的空闲连接（使用 NOOP 检查），没有时新建连接
+func getConn() (*smtpConn, error) {
+	for {
+		poolLock.Lock()
+		if len(poolIdle) == 0 {
+			poolLock.Unlock()
+			break
+		}
+
+		c := poolIdle[len(poolIdle)-1]
+		poolIdle = poolIdle[:len(poolIdle)-1]
+		poolLock.Unlock()
+
+		if c.timer != nil {
+			c.timer.Stop()
+			c.timer = nil
+		}
+
+		c.setDeadline()
+		if c.client.Noop() == nil {
+			return c, nil
+		}
+
+		_ = c.conn.Close()
+	}
+
+	return dial()
+}
+
+// putConn 归还连接，连接异常、不复用或空闲连接已满时直接关闭
+func putConn(c *smtpConn, healthy bool) {
+	if c == nil {
+		return
+	}
+
+	poolLock.Lock()
+	if !healthy || poolClosed || smtpKeepAlive <= 0 || len(poolIdle) >= smtpMaxIdle {
+		poolLock.Unlock()
+		c.close()
+		return
+	}
+
+	c.timer = time.AfterFunc(smtpKeepAlive, func() {
+		if removeIdle(c) {
+			c.close()
+		}
+	})
+	poolIdle = append(poolIdle, c)
+	poolLock.Unlock()
+}
+
+func removeIdle(c *smtpConn) bool {
+	poolLock.Lock()
+	defer poolLock.Unlock()
+
+	for i, ic := range poolIdle {
+		if ic == c {
+			poolIdle = append(poolIdle[:i], poolIdle[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// CloseSmtp 关闭全部空闲连接，之后的邮件不再复用连接
+func CloseSmtp() {
+	poolLock.Lock()
+	idle := poolIdle
+	poolIdle = nil
+	poolClosed = true
+	poolLock.Unlock()
+
+	for _, c := range idle {
+		if c.timer != nil {
+			c.timer.Stop()
+		}
+		c.close()
+	}
+}
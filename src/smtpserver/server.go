@@ -1,16 +1,11 @@
 package smtpserver
 
 import (
-	"crypto/tls"
-	"errors"
 	"fmt"
 	"github.com/SongZihuan/huan-springboard/src/config"
 	"github.com/SongZihuan/huan-springboard/src/utils"
 	"gopkg.in/gomail.v2"
-	"net"
 	"net/mail"
-	"net/smtp"
-	"os"
 	"strings"
 	"sync"
 	"time"
@@ -20,6 +15,12 @@ var smtpAddress string = ""
 var smtpUser string = ""
 var smtpPassword string = ""
 var smtpRecipient []*mail.Address
+var smtpTLS string = ""
+var smtpAuth string = ""
+var smtpOAuth2 config.SMTPOAuth2Config
+var smtpTimeout time.Duration
+var smtpKeepAlive time.Duration
+var smtpMaxIdle int
 
 var once sync.Once
 
@@ -31,6 +32,12 @@ func InitSmtp() (err error) {
 		smtpUser = config.GetConfig().SMTP.User
 		smtpPassword = config.GetConfig().SMTP.Password
 		smtpRecipient = make([]*mail.Address, 0, len(recipientList))
+		smtpTLS = config.GetConfig().SMTP.TLS
+		smtpAuth = config.GetConfig().SMTP.Auth
+		smtpOAuth2 = config.GetConfig().SMTP.OAuth2
+		smtpTimeout = time.Duration(config.GetConfig().SMTP.TimeoutSeconds) * time.Second
+		smtpKeepAlive = time.Duration(config.GetConfig().SMTP.KeepAliveSeconds) * time.Second
+		smtpMaxIdle = int(config.GetConfig().SMTP.MaxIdleConnections)
 
 		if !config.IsReady() {
 			panic("config is not ready")
@@ -72,9 +79,20 @@ func Send(subject string, msg string) error {
 	}
 
 	subject = fmt.Sprintf("【%s 消息提醒】 %s", config.GetConfig().SystemName, subject)
+	return SendMail(subject, msg, "")
+}
+
+// SendMail 发送邮件（不添加主题前缀），html 不为空时发送 multipart/alternative 邮件
+func SendMail(subject string, text string, html string) error {
+	if !config.IsReady() {
+		panic("config is not ready")
+	} else if smtpAddress == "" || smtpUser == "" {
+		return nil
+	}
+
 	now := time.Now()
 
-	err := _sendTo(subject, msg, nil, nil, smtpRecipient, "", now)
+	err := _sendTo(subject, text, html, nil, nil, smtpRecipient, "", now)
 	if err != nil {
 		return err
 	}
@@ -82,7 +100,7 @@ func Send(subject string, msg string) error {
 	return nil
 }
 
-func _sendTo(subject string, msg string, fromAddr *mail.Address, replyToAddr *mail.Address, toAddr []*mail.Address, messageID string, t time.Time) (err error) {
+func _sendTo(subject string, msg string, html string, fromAddr *mail.Address, replyToAddr *mail.Address, toAddr []*mail.Address, messageID string, t time.Time) (err error) {
 	if smtpAddress == "" || smtpUser == "" {
 		return nil
 	}
@@ -114,84 +132,18 @@ func _sendTo(subject string, msg string, fromAddr *mail.Address, replyToAddr *ma
 		}
 	}
 
-	const missingPort = "missing port in address"
-	host, port, err := net.SplitHostPort(smtpAddress)
-	var addrErr *net.AddrError
-	if errors.As(err, &addrErr) {
-		if addrErr.Err == missingPort {
-			host = smtpAddress
-			port = "25"
-		} else {
-			return err
-		}
-	} else if err != nil {
-		return err
-	}
-
-	tlsconfig := &tls.Config{
-		ServerName:         host,
-		InsecureSkipVerify: false,
-	}
-
-	conn, err := net.Dial("tcp", net.JoinHostPort(host, port))
+	c, err := getConn()
 	if err != nil {
 		return err
 	}
-	defer func() {
-		_ = conn.Close()
-	}()
 
-	isSecureConn := false
-	_conn := tls.Client(conn, tlsconfig)
-	err = _conn.Handshake()
-	if err == nil {
-		conn = _conn
-		isSecureConn = true
-	}
-
-	smtpClient, err := smtp.NewClient(conn, host)
-	if err != nil {
-		return fmt.Errorf("new smtp client: %v", err)
-	}
+	healthy := false
 	defer func() {
-		_ = smtpClient.Quit()
-		smtpClient = nil
+		putConn(c, healthy)
 	}()
 
-	hostname, err := os.Hostname()
-	if err != nil {
-		return err
-	}
-
-	if err = smtpClient.Hello(hostname); err != nil {
-		return fmt.Errorf("hello: %v", err)
-	}
-
-	// If not using SMTPS, always use STARTTLS if available
-	hasStartTLS, _ := smtpClient.Extension("STARTTLS")
-	if !isSecureConn && hasStartTLS {
-		if err = smtpClient.StartTLS(tlsconfig); err != nil {
-			return fmt.Errorf("start tls: %v", err)
-		}
-	}
-
-	canAuth, options := smtpClient.Extension("AUTH")
-	if canAuth {
-		var auth smtp.Auth
-		if strings.Contains(options, "CRAM-MD5") {
-			auth = smtp.CRAMMD5Auth(sender, smtpPassword)
-		} else if strings.Contains(options, "PLAIN") {
-			auth = smtp.PlainAuth("", sender, smtpPassword, host)
-		} else if strings.Contains(options, "LOGIN") {
-			auth = LoginAuth(sender, smtpPassword)
-		}
-
-		if auth != nil {
-			if err = smtpClient.Auth(auth); err != nil {
-				return fmt.Errorf("auth: %s", err.Error())
-			}
-		}
-	}
+	c.setDeadline()
+	smtpClient := c.client
 
 	err = smtpClient.Mail(sender)
 	if err != nil {
@@ -199,16 +151,17 @@ func _sendTo(subject string, msg string, fromAddr *mail.Address, replyToAddr *ma
 	}
 
 	recList := make([]string, 0, len(toAddr))
+	deliveryErr := &DeliveryError{}
 
 	for _, addr := range toAddr {
 		if addr.Address == "" || !utils.IsValidEmail(addr.Address) {
-			fmt.Printf("%s is not a valid email, ignore\n", addr.Address)
+			deliveryErr.add(addr.Address, fmt.Errorf("not a valid email"))
 			continue
 		}
 
 		err = smtpClient.Rcpt(addr.Address)
 		if err != nil {
-			fmt.Printf("%s set rcpt error: %s, ignore\n", addr.String(), err.Error())
+			deliveryErr.add(addr.Address, err)
 			continue
 		}
 
@@ -216,7 +169,8 @@ func _sendTo(subject string, msg string, fromAddr *mail.Address, replyToAddr *ma
 	}
 
 	if len(recList) == 0 {
-		return fmt.Errorf("no any valid recipient")
+		healthy = smtpClient.Reset() == nil
+		return deliveryErr
 	}
 
 	if fromAddr.Address == "" {
@@ -234,6 +188,9 @@ func _sendTo(subject string, msg string, fromAddr *mail.Address, replyToAddr *ma
 		gomsg.SetHeader("References", messageID)
 	}
 	gomsg.SetBody("text/plain", msg)
+	if html != "" {
+		gomsg.AddAlternative("text/html", html)
+	}
 
 	w, err := smtpClient.Data()
 	if err != nil {
@@ -249,33 +206,15 @@ func _sendTo(subject string, msg string, fromAddr *mail.Address, replyToAddr *ma
 		return fmt.Errorf("close: %v", err)
 	}
 
-	return nil
-}
-
-type loginAuth struct {
-	username, password string
-}
+	// 重置会话以便复用连接
+	healthy = smtpClient.Reset() == nil
 
-func (*loginAuth) Start(_ *smtp.ServerInfo) (string, []byte, error) {
-	return "LOGIN", []byte{}, nil
-}
-
-func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
-	if more {
-		switch string(fromServer) {
-		case "Username:":
-			return []byte(a.username), nil
-		case "Password:":
-			return []byte(a.password), nil
-		default:
-			return nil, fmt.Errorf("unknwon fromServer: %s", string(fromServer))
-		}
+	if len(deliveryErr.Failed) > 0 {
+		deliveryErr.Delivered = len(recList)
+		return deliveryErr
 	}
-	return nil, nil
-}
 
-func LoginAuth(username, password string) smtp.Auth {
-	return &loginAuth{username, password}
+	return nil
 }
 
 type Message struct {
@@ -0,0 +1,35 @@
+package sockopt
+
+import (
+	"syscall"
+)
+
+// Options 连接目标前设置的 socket 选项
+type Options struct {
+	Device         string // 绑定网卡（SO_BINDTODEVICE），留空表示不绑定
+	Mark           uint32 // SO_MARK，0 表示不设置
+	KeepAliveCount int    // TCP_KEEPCNT，0 表示使用系统默认值
+	Transparent    bool   // IP_TRANSPARENT（ipv6 为 IPV6_TRANSPARENT），允许绑定非本机地址
+}
+
+func (o *Options) IsZero() bool {
+	return o.Device == "" && o.Mark == 0 && o.KeepAliveCount == 0 && !o.Transparent
+}
+
+// Control 返回用于 net.Dialer.Control 的函数，没有需要设置的选项时返回 nil
+func (o *Options) Control() func(network string, address string, c syscall.RawConn) error {
+	if o.IsZero() {
+		return nil
+	}
+
+	return func(network string, address string, c syscall.RawConn) error {
+		var err error
+		ctrlErr := c.Control(func(fd uintptr) {
+			err = o.set(network, fd)
+		})
+		if ctrlErr != nil {
+			return ctrlErr
+		}
+		return err
+	}
+}
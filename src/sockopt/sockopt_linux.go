@@ -0,0 +1,48 @@
+//go:build linux
+
+package sockopt
+
+import (
+	"fmt"
+	"golang.org/x/sys/unix"
+)
+
+// Supported 当前系统是否支持 SO_BINDTODEVICE、SO_MARK、TCP_KEEPCNT 和 IP_TRANSPARENT
+const Supported = true
+
+func (o *Options) set(network string, fd uintptr) error {
+	if o.Device != "" {
+		err := unix.SetsockoptString(int(fd), unix.SOL_SOCKET, unix.SO_BINDTODEVICE, o.Device)
+		if err != nil {
+			return fmt.Errorf("set SO_BINDTODEVICE %s: %s", o.Device, err.Error())
+		}
+	}
+
+	if o.Mark != 0 {
+		err := unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_MARK, int(o.Mark))
+		if err != nil {
+			return fmt.Errorf("set SO_MARK %d: %s", o.Mark, err.Error())
+		}
+	}
+
+	if o.KeepAliveCount != 0 {
+		err := unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_KEEPCNT, o.KeepAliveCount)
+		if err != nil {
+			return fmt.Errorf("set TCP_KEEPCNT %d: %s", o.KeepAliveCount, err.Error())
+		}
+	}
+
+	if o.Transparent {
+		var err error
+		if network == "tcp6" {
+			err = unix.SetsockoptInt(int(fd), unix.SOL_IPV6, unix.IPV6_TRANSPARENT, 1)
+		} else {
+			err = unix.SetsockoptInt(int(fd), unix.SOL_IP, unix.IP_TRANSPARENT, 1)
+		}
+		if err != nil {
+			return fmt.Errorf("set IP_TRANSPARENT: %s (CAP_NET_ADMIN is required)", err.Error())
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package sockopt
+
+import (
+	"fmt"
+)
+
+// Supported 当前系统是否支持 SO_BINDTODEVICE、SO_MARK、TCP_KEEPCNT 和 IP_TRANSPARENT
+const Supported = false
+
+func (o *Options) set(network string, fd uintptr) error {
+	return fmt.Errorf("device, mark, keepalive-count and transparent are only supported on linux")
+}
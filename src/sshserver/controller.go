@@ -6,5 +6,5 @@ import (
 )
 
 type SshController interface {
-	RemoteAddrCheck(remoteAddr *net.TCPAddr, to *net.TCPAddr, countRules []*config.SshCountRuleConfig) error
+	RemoteAddrCheck(remoteAddr *net.TCPAddr, to net.Addr, countRules []*config.SshCountRuleConfig) (rule string, err error) // rule 为命中的规则名称，用于访问日志
 }
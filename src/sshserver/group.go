@@ -7,6 +7,7 @@ import (
 	"github.com/SongZihuan/huan-springboard/src/database"
 	"github.com/SongZihuan/huan-springboard/src/logger"
 	"github.com/SongZihuan/huan-springboard/src/redisserver"
+	"github.com/SongZihuan/huan-springboard/src/rulesim"
 	"net"
 	"sync"
 	"sync/atomic"
@@ -17,8 +18,9 @@ var sshServerGroupOnce sync.Once
 var sshServerGroup *SshServerGroup
 
 type SshServerGroup struct {
-	status  atomic.Int32
-	servers sync.Map
+	status      atomic.Int32
+	servers     sync.Map
+	serversLock sync.Mutex // 启动、停止和调整转发时加锁
 }
 
 func NewSshServerGroup() (res *SshServerGroup) { // 单例模式
@@ -43,34 +45,132 @@ func (s *SshServerGroup) Start() error {
 }
 
 func (s *SshServerGroup) StartAllServers() error {
+	s.serversLock.Lock()
+	defer s.serversLock.Unlock()
+
 	if !s.status.CompareAndSwap(StatusWaitStart, StatusRunning) {
 		return nil
 	}
 
 	logger.Infof("SSH ServerGroup All Server Start...")
 	for _, f := range config.GetConfig().SSH.Forward {
-		server, err := NewSshServer(&SshServerOpt{
-			Config:     f,
-			Controller: s,
-		})
-		if err != nil {
-			logger.Errorf("New SSH Server Error: %s\n", err)
+		for _, b := range f.Binds {
+			s.startServer(f, b)
 		}
+	}
+	logger.Infof("SSH ServerGroup All Server Start Finished")
+
+	return nil
+}
+
+// startServer 启动转发的一个监听地址，服务以监听地址和端口（ListenBind.Key）为键保存；返回是否保存（创建失败或冲突时不保存）
+func (s *SshServerGroup) startServer(f *config.SshForwardConfig, bind *config.ListenBind) bool {
+	server, err := NewSshServer(&SshServerOpt{
+		Config:     f,
+		Bind:       bind,
+		Controller: s,
+	})
+	if err != nil {
+		logger.Errorf("New SSH Server Error: %s\n", err)
+		return false
+	}
 
-		_, loaded := s.servers.LoadOrStore(f.SrcPort, server)
-		if loaded {
-			logger.Errorf("SSH Port Conflict: %d\n", f.SrcPort)
+	conflict := s.findConflict(bind)
+	if conflict != "" {
+		logger.Errorf("SSH Listen Conflict: %s overlaps %s\n", bind.Key(), conflict)
+		return false
+	}
+	s.servers.Store(bind.Key(), server)
+
+	err = server.Start()
+	if err != nil {
+		logger.Errorf("Start SSH Server Error: %s\n", err)
+		// 没启动成功，但仍然保留在 Map 中，目的是提前发现可能的端口冲突（配置错误）
+	}
+
+	return true
+}
+
+// findConflict 返回与 bind 冲突（地址和端口相同，或者其中一个为同一协议族的全部地址）的监听地址，没有冲突时返回空字符串
+func (s *SshServerGroup) findConflict(bind *config.ListenBind) (res string) {
+	s.servers.Range(func(key, value any) bool {
+		server, ok := value.(*SshServer)
+		if ok && server.bind.Overlaps(bind) {
+			res = server.bind.Key()
+			return false
 		}
+		return true
+	})
+	return res
+}
 
-		err = server.Start()
-		if err != nil {
-			logger.Errorf("Start SSH Server Error: %s\n", err)
-			// 没启动成功，但仍然保留在 Map 中，目的是提前发现可能的端口冲突（配置错误）
+type forwardBind struct {
+	config *config.SshForwardConfig
+	bind   *config.ListenBind
+}
+
+// Reconcile 重新加载配置后调整转发：停止被删除、被修改或者未运行（例如启动失败）的转发，启动新增的转发，未修改的转发（以及其上的连接）不受影响
+func (s *SshServerGroup) Reconcile() {
+	s.serversLock.Lock()
+	defer s.serversLock.Unlock()
+
+	if s.status.Load() != StatusRunning {
+		return // 未运行（或者已暂停）时不调整，下次启动时使用新的配置
+	}
+
+	forwards := make(map[string]forwardBind, len(config.GetConfig().SSH.Forward))
+	for _, f := range config.GetConfig().SSH.Forward {
+		for _, b := range f.Binds {
+			if _, ok := forwards[b.Key()]; !ok {
+				forwards[b.Key()] = forwardBind{config: f, bind: b}
+			}
 		}
 	}
-	logger.Infof("SSH ServerGroup All Server Start Finished")
 
-	return nil
+	var wg sync.WaitGroup
+	var keep, stopped, started int
+
+	s.servers.Range(func(key, value any) bool {
+		server, ok := value.(*SshServer)
+		if !ok {
+			s.servers.Delete(key)
+			return true
+		}
+
+		fb, ok := forwards[server.bind.Key()]
+		if ok && server.status.Load() == StatusRunning && server.config.Equal(fb.config) {
+			delete(forwards, server.bind.Key()) // 未修改，保留
+			keep++
+			return true
+		}
+
+		s.servers.Delete(key)
+		stopped++
+
+		wg.Add(1)
+		go func(server *SshServer) {
+			defer wg.Done()
+			_ = server.Stop()
+		}(server)
+
+		return true
+	})
+
+	wg.Wait() // 等待端口释放后再启动
+
+	for _, f := range config.GetConfig().SSH.Forward {
+		for _, b := range f.Binds {
+			if forwards[b.Key()].bind != b {
+				continue // 保留的转发或重复的监听地址
+			}
+
+			if s.startServer(f, b) {
+				started++
+			}
+		}
+	}
+
+	logger.Infof("SSH ServerGroup Reconcile: %d kept, %d stopped, %d started", keep, stopped, started)
 }
 
 func (s *SshServerGroup) Stop() error {
@@ -85,6 +185,9 @@ func (s *SshServerGroup) Stop() error {
 }
 
 func (s *SshServerGroup) StopAllServers() error {
+	s.serversLock.Lock()
+	defer s.serversLock.Unlock()
+
 	if !s.status.CompareAndSwap(StatusRunning, StatusWaitStop) {
 		return nil
 	}
@@ -140,10 +243,15 @@ func (s *SshServerGroup) RestartAllServers() error {
 	return nil
 }
 
-func (s *SshServerGroup) RemoteAddrCheck(remoteAddr *net.TCPAddr, to *net.TCPAddr, countRules []*config.SshCountRuleConfig) error {
-	ip := remoteAddr.IP
+func (s *SshServerGroup) RemoteAddrCheck(remoteAddr *net.TCPAddr, to net.Addr, countRules []*config.SshCountRuleConfig) (string, error) {
+	return s.remoteAddrCheck(remoteAddr.IP, to, countRules, nil, nil)
+}
+
+// remoteAddrCheck 来访 IP 检查；sim 不为 nil 时为模拟（不写入 Redis 封禁），记录每一步的检查结果，locOverride 不为 nil 时不查询 IP 定位
+func (s *SshServerGroup) remoteAddrCheck(ip net.IP, to net.Addr, countRules []*config.SshCountRuleConfig, sim *rulesim.Result, locOverride *apiip.QueryIpLocationData) (string, error) {
 	if ip == nil {
-		return fmt.Errorf("无法获取IP")
+		sim.Step("invalid-ip", rulesim.ResultDeny, "can not get ip")
+		return "invalid-ip", fmt.Errorf("无法获取IP")
 	}
 
 	if len(countRules) == 0 {
@@ -154,66 +262,99 @@ func (s *SshServerGroup) RemoteAddrCheck(remoteAddr *net.TCPAddr, to *net.TCPAdd
 	isIntranet := isLoopback || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast()
 
 	if isLoopback && (config.GetConfig().SSH.RuleList.AlwaysAllowIntranet.IsEnable(false) || config.GetConfig().SSH.RuleList.AlwaysAllowLoopback.IsEnable(true)) {
-		return nil
+		sim.Step("always-allow-loopback", rulesim.ResultAllow, "loopback address is always allowed")
+		return "always-allow-loopback", nil
+	} else if isLoopback {
+		sim.Step("always-allow-loopback", rulesim.ResultSkip, "always-allow-loopback and always-allow-intranet are disabled")
+	} else {
+		sim.Step("always-allow-loopback", rulesim.ResultSkip, "not a loopback address")
 	}
 
 	if !database.SshCheckIP(ip.String()) {
-		return fmt.Errorf("IP地址被SQLite中定义的规则（IP）封禁。")
+		sim.Step("sqlite-ip", rulesim.ResultDeny, "ip is banned by sqlite")
+		return "sqlite-ip", fmt.Errorf("IP地址被SQLite中定义的规则（IP）封禁。")
 	}
+	sim.Step("sqlite-ip", rulesim.ResultPass, "ip is not banned by sqlite")
 
 	if ip.IsPrivate() && config.GetConfig().SSH.RuleList.AlwaysAllowIntranet.IsEnable(false) {
-		return nil
+		sim.Step("always-allow-intranet", rulesim.ResultAllow, "private address is always allowed")
+		return "always-allow-intranet", nil
+	} else if ip.IsPrivate() {
+		sim.Step("always-allow-intranet", rulesim.ResultSkip, "always-allow-intranet is disabled")
+	} else {
+		sim.Step("always-allow-intranet", rulesim.ResultSkip, "not a private address")
 	}
 
-	rcErr := s.CountRulesCheck(ip, to, countRules)
+	rcErr := s.countRulesCheck(ip, to, countRules, sim)
 	if rcErr != nil {
-		return rcErr
+		sim.Step("count-rules", rulesim.ResultDeny, "%s", rcErr.Error())
+		return "count-rules", rcErr
 	}
 
 	var loc *apiip.QueryIpLocationData = nil
 
 	if isIntranet {
 		loc = nil
+		sim.Step("ip-location", rulesim.ResultSkip, "intranet address has no location, location rules will be skipped")
+	} else if locOverride != nil {
+		loc = locOverride
 	} else {
 		var err error
 		loc, err = redisserver.QueryIpLocation(ip.String())
 		if err != nil {
 			logger.Errorf("failed to query ip location: %s", err.Error())
-			return fmt.Errorf("查询IP定位失败（%s）。", err.Error())
+			sim.Step("ip-location", rulesim.ResultDeny, "failed to query ip location: %s", err.Error())
+			return "ip-location", fmt.Errorf("查询IP定位失败（%s）。", err.Error())
 		} else if loc == nil {
 			logger.Panicf("failed to query ip location: loc is nil")
-			return fmt.Errorf("查询IP定位失败（loc is nil）。")
+			sim.Step("ip-location", rulesim.ResultDeny, "failed to query ip location")
+			return "ip-location", fmt.Errorf("查询IP定位失败（loc is nil）。")
 		}
+	}
+
+	if loc != nil {
+		sim.LocationStep(loc)
 
-		if !database.TcpCheckLocationNation(loc.Nation) {
-			return fmt.Errorf("IP地址被SQLite中定义的规则（地区-国家）封禁。")
+		if !database.SshCheckLocationNation(loc.Nation) {
+			sim.Step("sqlite-location", rulesim.ResultDeny, "nation is banned by sqlite")
+			return "sqlite-location", fmt.Errorf("IP地址被SQLite中定义的规则（地区-国家）封禁。")
 		}
 
-		if !database.TcpCheckLocationProvince(loc.Province) {
-			return fmt.Errorf("IP地址被SQLite中定义的规则（地区-省份）封禁。")
+		if !database.SshCheckLocationProvince(loc.Province) {
+			sim.Step("sqlite-location", rulesim.ResultDeny, "province is banned by sqlite")
+			return "sqlite-location", fmt.Errorf("IP地址被SQLite中定义的规则（地区-省份）封禁。")
 		}
 
-		if !database.TcpCheckLocationCity(loc.City) {
-			return fmt.Errorf("IP地址被SQLite中定义的规则（地区-城市）封禁。")
+		if !database.SshCheckLocationCity(loc.City) {
+			sim.Step("sqlite-location", rulesim.ResultDeny, "city is banned by sqlite")
+			return "sqlite-location", fmt.Errorf("IP地址被SQLite中定义的规则（地区-城市）封禁。")
 		}
 
-		if !database.TcpCheckLocationISP(loc.Isp) {
-			return fmt.Errorf("IP地址被SQLite中定义的规则（地区-ISP）封禁。")
+		if !database.SshCheckLocationISP(loc.Isp) {
+			sim.Step("sqlite-location", rulesim.ResultDeny, "isp is banned by sqlite")
+			return "sqlite-location", fmt.Errorf("IP地址被SQLite中定义的规则（地区-ISP）封禁。")
 		}
+
+		sim.Step("sqlite-location", rulesim.ResultPass, "location is not banned by sqlite")
 	}
 
 RuleCycle:
-	for _, r := range config.GetConfig().SSH.RuleList.RuleList {
+	for i, r := range config.GetConfig().SSH.RuleList.RuleList {
+		name := fmt.Sprintf("rule-list[%d]", i)
+
 		if loc == nil {
 			if r.HasLocation() {
+				sim.RuleStep(name, rulesim.ResultSkip, "location is unknown", &r.RuleConfig)
 				continue RuleCycle
 			}
 		} else {
 			ok, err := loc.CheckLocation(&r.RuleConfig)
 			if err != nil {
 				logger.Errorf("check location error: %s", err.Error())
-				return fmt.Errorf("在配置文件规则策略中，检测IP地址错误。")
+				sim.Step(name, rulesim.ResultDeny, "check location error: %s", err.Error())
+				return name, fmt.Errorf("在配置文件规则策略中，检测IP地址错误。")
 			} else if !ok {
+				sim.RuleStep(name, rulesim.ResultSkip, "location not matched", &r.RuleConfig)
 				continue RuleCycle
 			}
 		}
@@ -221,32 +362,49 @@ RuleCycle:
 		ok, err := r.CheckIP(ip)
 		if err != nil {
 			logger.Errorf("check ip error: %s", err.Error())
-			return fmt.Errorf("在配置文件规则策略中，检测IP信息错误。")
+			sim.Step(name, rulesim.ResultDeny, "check ip error: %s", err.Error())
+			return name, fmt.Errorf("在配置文件规则策略中，检测IP信息错误。")
 		} else if !ok {
+			sim.RuleStep(name, rulesim.ResultSkip, "ip not matched", &r.RuleConfig)
 			continue RuleCycle
 		}
 
 		if r.Banned.ToBool(true) { // true - 封禁
-			return fmt.Errorf("IP在配置文件规则策略中被封禁。")
+			sim.RuleStep(name, rulesim.ResultDeny, "matched", &r.RuleConfig)
+			return name, fmt.Errorf("IP在配置文件规则策略中被封禁。")
 		}
 
-		return nil
+		sim.RuleStep(name, rulesim.ResultAllow, "matched", &r.RuleConfig)
+		return name, nil
 	}
 
 	if config.GetConfig().SSH.RuleList.DefaultBanned.ToBool(true) { // true - 封禁
-		return fmt.Errorf("IP在配置文件默认兜底规则策略中被封禁。")
+		sim.Step("default", rulesim.ResultDeny, "no rule matched, default-banned is enabled")
+		return "default", fmt.Errorf("IP在配置文件默认兜底规则策略中被封禁。")
 	}
 
-	return nil
+	sim.Step("default", rulesim.ResultAllow, "no rule matched, default-banned is disabled")
+	return "default", nil
+}
+
+func (s *SshServerGroup) CountRulesCheck(ip net.IP, to net.Addr, countRules []*config.SshCountRuleConfig) error {
+	return s.countRulesCheck(ip, to, countRules, nil)
 }
 
-func (s *SshServerGroup) CountRulesCheck(ip net.IP, to *net.TCPAddr, countRules []*config.SshCountRuleConfig) error {
+// countRulesCheck 计数策略检查；sim 不为 nil 时为模拟，命中策略时不写入 Redis 封禁，to 为 nil 时不检查连接记录
+func (s *SshServerGroup) countRulesCheck(ip net.IP, to net.Addr, countRules []*config.SshCountRuleConfig, sim *rulesim.Result) error {
 	now := time.Now()
+	dryRun := sim != nil
 
 	if !redisserver.QuerySSHIpBanned(ip.String()) {
 		return fmt.Errorf("IP在配置文件计数策略中被封禁，IP已被Redis封禁。")
 	}
 
+	if dryRun && to == nil {
+		sim.Step("count-rules", rulesim.ResultSkip, "ip is not banned by redis, forward port is not set so connection records are not checked")
+		return nil
+	}
+
 	if len(countRules) > 0 {
 		limit := int(countRules[0].TryCount + 1) // +1防止TryCount是0
 		after := now.Add(-1 * time.Second * time.Duration(countRules[0].Seconds))
@@ -257,15 +415,18 @@ func (s *SshServerGroup) CountRulesCheck(ip net.IP, to *net.TCPAddr, countRules
 			return fmt.Errorf("从数据库读取SSH记录异常，禁止连接。")
 		}
 
-		for _, r := range countRules {
+		for i, r := range countRules {
 			if s._countRulesCheck(res, r, now) {
 				if r.BannedSeconds <= 0 {
+					sim.Step("count-rules", rulesim.ResultPass, "count-rules[%d] matched, banned-seconds is 0 so the connection is allowed", i)
 					return nil // 返回是否放行，true表示放行
 				}
 
-				err := redisserver.SetSSHIpBanned(ip.String(), time.Duration(r.BannedSeconds)*time.Second)
-				if err != nil {
-					logger.Errorf("count rules check error: %s", err.Error())
+				if !dryRun {
+					err := redisserver.SetSSHIpBanned(ip.String(), time.Duration(r.BannedSeconds)*time.Second)
+					if err != nil {
+						logger.Errorf("count rules check error: %s", err.Error())
+					}
 				}
 				return fmt.Errorf("IP在配置文件计数策略中被封禁, 时长 %d 秒。", r.BannedSeconds)
 			}
@@ -283,14 +444,17 @@ func (s *SshServerGroup) CountRulesCheck(ip net.IP, to *net.TCPAddr, countRules
 
 		if len(res) > 5 {
 			// 命中默认策略
-			err := redisserver.SetSSHIpBanned(ip.String(), 600*time.Second)
-			if err != nil {
-				logger.Errorf("count rules check error: %s", err.Error())
+			if !dryRun {
+				err := redisserver.SetSSHIpBanned(ip.String(), 600*time.Second)
+				if err != nil {
+					logger.Errorf("count rules check error: %s", err.Error())
+				}
 			}
 			return fmt.Errorf("IP在配置文件计数策略中被封禁, 时长 %d 秒。", 600)
 		}
 	}
 
+	sim.Step("count-rules", rulesim.ResultPass, "ip is not banned by redis and no count rule matched")
 	return nil // 没有命中封禁策略
 }
 
@@ -1,35 +1,29 @@
 package sshserver
 
 import (
+	"errors"
 	"fmt"
 	"github.com/SongZihuan/huan-springboard/src/config"
 	"github.com/SongZihuan/huan-springboard/src/database"
-	"github.com/SongZihuan/huan-springboard/src/ipcheck"
+	"github.com/SongZihuan/huan-springboard/src/forward"
 	"github.com/SongZihuan/huan-springboard/src/logger"
 	"github.com/SongZihuan/huan-springboard/src/notify"
-	"github.com/pires/go-proxyproto"
+	"github.com/SongZihuan/huan-springboard/src/redisserver"
 	"io"
 	"net"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 )
 
 type SshServer struct {
-	status atomic.Int32
-	config *config.SshForwardConfig
+	status   atomic.Int32
+	config   *config.SshForwardConfig
+	settings *forward.Settings
+	bind     *config.ListenBind
 
-	ln4              net.Listener
-	ln4Proxy         bool
-	ln4Cross         bool
-	ln4Target        *net.TCPAddr
-	ln4TargetNetwork string
-
-	ln6              net.Listener
-	ln6Proxy         bool
-	ln6Cross         bool
-	ln6Target        *net.TCPAddr
-	ln6TargetNetwork string
+	lns []*forward.Listener // 每个端口、每个协议族一个监听
 
 	swg        sync.WaitGroup
 	allconn    sync.Map
@@ -39,16 +33,23 @@ type SshServer struct {
 
 type SshServerOpt struct {
 	Config     *config.SshForwardConfig
+	Bind       *config.ListenBind // 监听地址，为 Config.Binds 中的一项
 	Controller SshController
 }
 
 func NewSshServer(opt *SshServerOpt) (*SshServer, error) {
-	if opt.Config.ResolveIPv4DestAddress == nil && opt.Config.ResolveIPv6DestAddress == nil {
+	if opt.Config.IPv4DestTarget == nil && opt.Config.IPv6DestTarget == nil && opt.Config.ResolveUnixDestAddress == nil {
 		return nil, fmt.Errorf("no dest address")
 	}
 
+	if opt.Bind == nil {
+		return nil, fmt.Errorf("no listen address")
+	}
+
 	res := &SshServer{
 		config:     opt.Config,
+		settings:   forward.SshSettings(opt.Config),
+		bind:       opt.Bind,
 		controller: opt.Controller,
 	}
 
@@ -57,168 +58,26 @@ func NewSshServer(opt *SshServerOpt) (*SshServer, error) {
 	return res, nil
 }
 
-func (s *SshServer) Start() (err error) {
-	if s.ln4 != nil || s.status.Load() != StatusReady {
+func (s *SshServer) Start() error {
+	if s.lns != nil || s.status.Load() != StatusReady {
 		return nil
 	}
 
-	if ipcheck.SupportIPv4() {
-		if s.config.ResolveIPv4DestAddress != nil {
-			_ln4, err := net.ListenTCP("tcp4", s.config.ResolveIPv4SrcAddress)
-			if err != nil {
-				return fmt.Errorf("listen %d on tcp4 failed: %s", s.config.SrcPort, err.Error())
-			}
-
-			if s.config.IPv4SrcServerProxy.IsEnable(true) {
-				s.ln4 = &proxyproto.Listener{
-					Listener: _ln4,
-				}
-				s.ln4Proxy = true
-				s.ln4Cross = false
-				s.ln4Target = s.config.ResolveIPv4DestAddress
-				s.ln4TargetNetwork = "tcp4"
-			} else {
-				s.ln4 = _ln4
-				s.ln4Proxy = false
-				s.ln4Cross = false
-				s.ln4Target = s.config.ResolveIPv4DestAddress
-				s.ln4TargetNetwork = "tcp4"
-			}
-		} else if s.config.Cross && s.config.ResolveIPv6DestAddress != nil {
-			_ln4, err := net.ListenTCP("tcp4", s.config.ResolveIPv4SrcAddress)
-			if err != nil {
-				return fmt.Errorf("listen %d on tcp4 failed: %s", s.config.SrcPort, err.Error())
-			}
-
-			s.ln4 = _ln4
-			s.ln4Proxy = false
-			s.ln4Cross = true
-			s.ln4Target = s.config.ResolveIPv6DestAddress
-			s.ln4TargetNetwork = "tcp6"
-		}
-	} else {
-		s.ln4 = nil
-		s.ln4Proxy = false
-		s.ln4Cross = false
-		s.ln4Target = nil
-		s.ln4TargetNetwork = ""
-	}
-
-	if ipcheck.SupportIPv6() {
-		if s.config.ResolveIPv6DestAddress != nil {
-			_ln6, err := net.ListenTCP("tcp6", s.config.ResolveIPv6SrcAddress)
-			if err != nil {
-				return fmt.Errorf("listen %d on tcp6 failed: %s", s.config.SrcPort, err.Error())
-			}
-
-			if s.config.IPv6SrcServerProxy.IsEnable(true) {
-				s.ln6 = &proxyproto.Listener{
-					Listener: _ln6,
-				}
-				s.ln6Proxy = true
-				s.ln6Cross = false
-				s.ln6Target = s.config.ResolveIPv6DestAddress
-				s.ln6TargetNetwork = "tcp6"
-			} else {
-				s.ln6 = _ln6
-				s.ln6Proxy = false
-				s.ln6Cross = false
-				s.ln6Target = s.config.ResolveIPv6DestAddress
-				s.ln6TargetNetwork = "tcp6"
-			}
-		} else if s.config.Cross && s.config.ResolveIPv4DestAddress != nil {
-			_ln6, err := net.ListenTCP("tcp6", s.config.ResolveIPv6SrcAddress)
-			if err != nil {
-				return fmt.Errorf("listen %d on tcp6 failed: %s", s.config.SrcPort, err.Error())
-			}
-
-			s.ln6 = _ln6
-			s.ln6Proxy = false
-			s.ln6Cross = true
-			s.ln6Target = s.config.ResolveIPv4DestAddress
-			s.ln6TargetNetwork = "tcp4"
-		}
-	} else {
-		s.ln6 = nil
-		s.ln6Proxy = false
-		s.ln6Cross = false
-		s.ln6Target = nil
-		s.ln6TargetNetwork = ""
-	}
-
-	if s.ln4 == nil && s.ln6 == nil {
-		return fmt.Errorf("no listen address")
-	}
-
-	if s.ln4Target == nil && s.ln6Target == nil {
-		return fmt.Errorf("no target address")
+	lns, err := forward.Listen(s.settings, s.bind)
+	if err != nil {
+		return err
 	}
 
+	s.lns = lns
 	s.stopchan = make(chan bool, 4)
 
-	if s.ln4 != nil {
-		go func() {
-			defer func() {
-				_ = s.ln4.Close()
-				s.ln4 = nil
-			}()
-
-			logger.Infof("listen on %d (ipv4) start", s.config.SrcPort)
-		MainCycle:
-			for {
-				select {
-				case <-s.stopchan:
-					break MainCycle
-				default:
-					// pass
-				}
-
-				status := s.accept(s.ln4,
-					"tcp4",
-					!s.ln4Cross && s.config.IPv4DestRequestProxy.IsEnable(true),
-					s.config.IPv4DestRequestProxyVersion,
-					s.ln4TargetNetwork,
-					s.ln4Target)
-				if status == StatusStop {
-					break MainCycle
-				}
-			}
-
-			logger.Infof("listen on %d (ipv4) stop", s.config.SrcPort)
-		}()
+	for _, l := range lns {
+		go s.serve(l)
 	}
 
-	if s.ln6 != nil {
-		go func() {
-			defer func() {
-				_ = s.ln6.Close()
-				s.ln6 = nil
-			}()
-
-			logger.Infof("listen on %d (ipv6) start", s.config.SrcPort)
-		MainCycle:
-			for {
-				select {
-				case <-s.stopchan:
-					break MainCycle
-				default:
-					// pass
-				}
-
-				status := s.accept(s.ln6,
-					"tcp6",
-					!s.ln6Cross && s.config.IPv6DestRequestProxy.IsEnable(true),
-					s.config.IPv6DestRequestProxyVersion,
-					s.ln6TargetNetwork,
-					s.ln6Target)
-				if status == StatusStop {
-					break MainCycle
-				}
-			}
+	go forward.Refresh(s.stopchan, s.settings.Targets, s.log(0, ""))
 
-			logger.Infof("listen on %d (ipv6) stop", s.config.SrcPort)
-		}()
-	}
+	s.log(0, "").Infof("listen on %s start (%d listeners)", s.bind.Key(), len(lns))
 
 	if !s.status.CompareAndSwap(StatusReady, StatusRunning) {
 		return fmt.Errorf("server run failed: can not set status")
@@ -227,13 +86,39 @@ func (s *SshServer) Start() (err error) {
 	return nil
 }
 
+func (s *SshServer) serve(l *forward.Listener) {
+	defer func() {
+		_ = l.Ln.Close()
+	}()
+
+	for {
+		select {
+		case <-s.stopchan:
+			return
+		default:
+			// pass
+		}
+
+		if s.accept(l) == StatusStop {
+			return
+		}
+	}
+}
+
 func (s *SshServer) Stop() error {
-	if s.ln4 == nil || !s.status.CompareAndSwap(StatusRunning, StatusStopping) {
+	if s.lns == nil || !s.status.CompareAndSwap(StatusRunning, StatusStopping) {
 		return nil
 	}
 
 	close(s.stopchan)
 
+	// 关闭监听，使阻塞中的 Accept 返回并释放端口
+	for _, l := range s.lns {
+		_ = l.Ln.Close()
+	}
+
+	s.log(0, "").Infof("listen on %s stop", s.bind.Key())
+
 	time.Sleep(1 * time.Second)
 
 	go func() {
@@ -256,12 +141,16 @@ func (s *SshServer) Stop() error {
 	return nil
 }
 
-func (s *SshServer) forward(remoteAddr string, conn net.Conn, target net.Conn, record *database.SshConnectRecord) {
+func (s *SshServer) forward(remoteAddr string, conn net.Conn, target net.Conn, record *database.SshConnectRecord, access *logger.AccessRecord) {
 	defer func() {
 		defer func() {
 			_ = recover()
 		}()
 
+		if record == nil {
+			return // 没有写入连接记录（本地进程通过 unix socket 直接连接）
+		}
+
 		err := database.UpdateSshConnectRecord(record, "连接正常断开。")
 		if err != nil {
 			logger.Errorf("update ssh connect record error: %s", record)
@@ -282,12 +171,30 @@ func (s *SshServer) forward(remoteAddr string, conn net.Conn, target net.Conn, r
 	s.swg.Add(1)
 	defer s.swg.Done()
 
-	if _, loaded := s.allconn.LoadOrStore(remoteAddr, conn); loaded {
-		logger.Errorf("%s is already connected", remoteAddr)
+	var bytesIn, bytesOut atomic.Int64
+	var err1, err2 error
+
+	defer func() {
+		// 在两个方向的转发都结束后写入访问日志
+		access.BytesIn = bytesIn.Load()
+		access.BytesOut = bytesOut.Load()
+		logger.WriteAccess(access)
+	}()
+
+	// 本地进程通过 unix socket 直接连接时没有来访地址，使用连接本身区分
+	var key any = remoteAddr
+	if remoteAddr == "" {
+		key = conn
+	}
+
+	if _, loaded := s.allconn.LoadOrStore(key, conn); loaded {
+		s.log(access.Port, remoteAddr).Errorf("%s is already connected", remoteAddr)
+		access.Decision = logger.AccessError
+		access.Reason = "already connected"
 		return
 	}
 	defer func() {
-		s.allconn.Delete(remoteAddr)
+		s.allconn.Delete(key)
 	}()
 
 	var stopchan1 = make(chan bool)
@@ -337,9 +244,11 @@ func (s *SshServer) forward(remoteAddr string, conn net.Conn, target net.Conn, r
 			close(stopchan1)
 		}()
 
-		_, err := io.Copy(target, conn)
+		n, err := io.Copy(target, conn)
+		bytesIn.Store(n)
+		err1 = err
 		if err != nil && conn != nil && target != nil && s.status.Load() == StatusRunning {
-			logger.Errorf("failed to forward from %s to %s: %v", conn.RemoteAddr(), target.RemoteAddr(), err)
+			s.log(access.Port, remoteAddr).Errorf("failed to forward from %s to %s: %v", conn.RemoteAddr(), target.RemoteAddr(), err)
 		}
 	}()
 
@@ -361,38 +270,44 @@ func (s *SshServer) forward(remoteAddr string, conn net.Conn, target net.Conn, r
 			close(stopchan2)
 		}()
 
-		_, err := io.Copy(conn, target)
+		n, err := io.Copy(conn, target)
+		bytesOut.Store(n)
+		err2 = err
 		if err != nil && conn != nil && target != nil && s.status.Load() == StatusRunning {
-			logger.Errorf("failed to forward from %s to %s: %v", target.RemoteAddr(), conn.RemoteAddr(), err)
+			s.log(access.Port, remoteAddr).Errorf("failed to forward from %s to %s: %v", target.RemoteAddr(), conn.RemoteAddr(), err)
 		}
 	}()
 
 	select {
 	case <-stopchan1:
+		access.Reason = logger.CloseReason(logger.CloseClient, err1)
 	case <-stopchan2:
+		access.Reason = logger.CloseReason(logger.CloseTarget, err2)
+	}
+
+	if s.status.Load() != StatusRunning {
+		access.Reason = logger.CloseServer
 	}
 
 	return
 }
 
-func (s *SshServer) accept(ln net.Listener, srcNetwork string, destProxy bool, destProxyVersion int, targetNetwork string, targetAddr *net.TCPAddr) string {
+func (s *SshServer) accept(l *forward.Listener) string {
 	defer func() {
 		if r := recover(); r != nil {
 			if err, ok := r.(error); ok {
-				logger.Panicf("listen on %d panic (error) : %s", s.config.SrcPort, err.Error())
+				logger.Panicf("listen on %s panic (error) : %s", s.bind.Key(), err.Error())
 			} else {
-				logger.Panicf("listen on %d panic : %s", s.config.SrcPort, err.Error())
+				logger.Panicf("listen on %s panic : %s", s.bind.Key(), err.Error())
 			}
 		}
 	}()
 
-	if ln == nil {
-		return StatusStop
-	}
-
-	conn, err := ln.Accept()
-	if err != nil {
-		logger.Errorf("listen on %d accecpt error: %s", s.config.SrcPort, err.Error())
+	conn, err := l.Ln.Accept()
+	if err != nil && errors.Is(err, net.ErrClosed) {
+		return StatusStop // 监听已关闭（Stop）
+	} else if err != nil {
+		s.log(l.Port, "").Errorf("listen on %s accecpt error: %s", s.bind.Key(), err.Error())
 		return StatusContinue
 	}
 	defer func() {
@@ -408,21 +323,48 @@ func (s *SshServer) accept(ln net.Listener, srcNetwork string, destProxy bool, d
 		return StatusContinue
 	}
 
-	remoteSSHAddr, err := net.ResolveTCPAddr(srcNetwork, remoteAddr.String())
+	remoteSSHAddr, err := l.RemoteTCPAddr(remoteAddr)
 	if err != nil {
 		return StatusContinue
 	}
 
-	ckErr := s.controller.RemoteAddrCheck(remoteSSHAddr, targetAddr, s.config.CountRules)
-	if ckErr != nil {
-		_, _ = AddSshConnectRecord("", remoteSSHAddr.IP, targetAddr, false, now, fmt.Sprintf("来访IP检查出现问题。%s", ckErr.Error()))
-		return StatusContinue
+	remote := ""
+	if remoteSSHAddr != nil {
+		remote = remoteSSHAddr.String()
 	}
 
-	target, err := net.DialTCP(targetNetwork, nil, targetAddr)
+	// addRecord 写入 SSH 连接记录；本地进程通过 unix socket 直接连接时没有来访 IP，不写入连接记录
+	addRecord := func(accept bool, mark string) (*database.SshConnectRecord, error) {
+		if remoteSSHAddr == nil {
+			return nil, nil
+		}
+		return AddSshConnectRecord("", remoteSSHAddr.IP, l.Target, accept, now, mark)
+	}
+
+	access := forward.AccessRecord("ssh", conn, l.Port, l.Target, now)
+
+	if remoteSSHAddr != nil {
+		rule, ckErr := s.controller.RemoteAddrCheck(remoteSSHAddr, l.Target, s.config.CountRules)
+		access.Rule = rule
+		if ckErr != nil {
+			s.log(l.Port, remote).With(logger.Fields{logger.FieldReason: ckErr.Error()}).Infof("connection to %s rejected", l.Target.String())
+			_, _ = addRecord(false, fmt.Sprintf("来访IP检查出现问题。%s", ckErr.Error()))
+			access.Decision = logger.AccessReject
+			access.Reason = ckErr.Error()
+			logger.WriteAccess(access)
+			return StatusContinue
+		}
+	} else {
+		access.Rule = "unix-socket" // 本地进程通过 unix socket 连接，没有来访 IP，由 socket 文件的权限控制访问
+	}
+
+	target, err := l.Dial(remoteSSHAddr)
 	if err != nil {
-		logger.Errorf("Failed to connect to target %s: %v", targetAddr.String(), err)
-		_, _ = AddSshConnectRecord("", remoteSSHAddr.IP, targetAddr, false, now, "无法解析来访TCP地址。")
+		s.log(l.Port, remote).Errorf("Failed to connect to target %s: %v", l.Target.String(), err)
+		_, _ = addRecord(false, "无法解析来访TCP地址。")
+		access.Decision = logger.AccessError
+		access.Reason = fmt.Sprintf("dial target: %s", err.Error())
+		logger.WriteAccess(access)
 		return StatusContinue
 	}
 	defer func() {
@@ -431,20 +373,25 @@ func (s *SshServer) accept(ln net.Listener, srcNetwork string, destProxy bool, d
 		}
 	}()
 
-	if destProxy {
-		header := proxyproto.HeaderProxyFromAddrs(byte(destProxyVersion), remoteSSHAddr, targetAddr)
-		_, err = header.WriteTo(target)
-		if err != nil {
-			logger.Errorf("Failed to write proxy header to target %s: %v", targetAddr.String(), err)
-			_, _ = AddSshConnectRecord("", remoteSSHAddr.IP, targetAddr, false, now, "无法写入Proxy协议头部。")
-			return StatusContinue
-		}
+	access.Backend = target.RemoteAddr().String() // 实际连接的目标地址
+
+	err = l.WriteProxyHeader(target, conn)
+	if err != nil {
+		s.log(l.Port, remote).Errorf("Failed to write proxy header to target %s: %v", l.Target.String(), err)
+		_, _ = addRecord(false, "无法写入Proxy协议头部。")
+		access.Decision = logger.AccessError
+		access.Reason = fmt.Sprintf("write proxy header: %s", err.Error())
+		logger.WriteAccess(access)
+		return StatusContinue
 	}
 
-	record, err := AddSshConnectRecord("", remoteSSHAddr.IP, targetAddr, true, now, "允许建立连接。")
+	record, err := addRecord(true, "允许建立连接。")
 	if err != nil {
-		logger.Errorf("Fail to save ssh connect record to database: %s", err.Error())
-		_, _ = AddSshConnectRecord("", remoteSSHAddr.IP, targetAddr, true, now, "无法记录SSH数据，不允许建立连接。")
+		s.log(l.Port, remote).Errorf("Fail to save ssh connect record to database: %s", err.Error())
+		_, _ = addRecord(true, "无法记录SSH数据，不允许建立连接。")
+		access.Decision = logger.AccessError
+		access.Reason = fmt.Sprintf("save connect record: %s", err.Error())
+		logger.WriteAccess(access)
 		return StatusContinue
 	}
 
@@ -452,22 +399,47 @@ func (s *SshServer) accept(ln net.Listener, srcNetwork string, destProxy bool, d
 	_target := target
 	conn = nil
 	target = nil
-	go s.forward(remoteAddr.String(), _conn, _target, record)
+	access.Decision = logger.AccessAccept
+	go s.forward(remote, _conn, _target, record, access)
 
 	return StatusContinue
 }
 
-func AddSshConnectRecord(from string, fromIP net.IP, to *net.TCPAddr, accept bool, now time.Time, mark string) (*database.SshConnectRecord, error) {
+func AddSshConnectRecord(from string, fromIP net.IP, to net.Addr, accept bool, now time.Time, mark string) (*database.SshConnectRecord, error) {
 	record, err := database.AddSshConnectRecord(from, fromIP, to, accept, now, mark)
 	if err != nil {
 		return nil, err
 	}
 
+	location := ipLocation(record.From)
+
 	if accept {
-		notify.SendSshSuccess(record.From, record.To, record.Mark)
+		notify.SendSshSuccess(record.From, location, record.To, record.Mark)
 	} else {
-		notify.SendSshBanned(record.From, record.To, record.Mark)
+		notify.SendSshBanned(record.From, location, record.To, record.Mark)
 	}
 
 	return record, nil
 }
+
+// ipLocation 返回缓存中的IP定位（国家 省份 城市 运营商），未缓存时返回空字符串
+func ipLocation(ip string) string {
+	loc := redisserver.CachedIpLocation(ip)
+	if loc == nil {
+		return ""
+	}
+
+	res := make([]string, 0, 4)
+	for _, s := range []string{loc.Nation, loc.Province, loc.City, loc.Isp} {
+		if s != "" && (len(res) == 0 || res[len(res)-1] != s) {
+			res = append(res, s)
+		}
+	}
+
+	return strings.Join(res, " ")
+}
+
+// log 返回带有组件、端口和来访地址字段的日志，port 为 0 时使用转发的端口（或端口范围）
+func (s *SshServer) log(port int64, remoteAddr string) *logger.Entry {
+	return forward.Log("ssh", s.config.Src, port, remoteAddr)
+}
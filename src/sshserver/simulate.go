@@ -0,0 +1,77 @@
+package sshserver
+
+import (
+	"fmt"
+	"github.com/SongZihuan/huan-springboard/src/config"
+	"github.com/SongZihuan/huan-springboard/src/rulesim"
+	"net"
+)
+
+// Simulate 模拟来访 IP 的检查流程（不建立连接，不写入连接记录和 Redis 封禁），返回检查结果以及每一步的检查过程；
+// 指定端口时使用该转发的计数策略并检查到该转发目标的连接记录
+func Simulate(req *rulesim.Request) (*rulesim.Result, error) {
+	ip, err := req.Check()
+	if err != nil {
+		return nil, err
+	}
+
+	var to net.Addr = nil
+	var countRules []*config.SshCountRuleConfig = nil
+
+	if req.Port != 0 {
+		f := findForward(req.Port)
+		if f == nil {
+			return nil, fmt.Errorf("ssh forward on port %d not found", req.Port)
+		}
+
+		to = simulateTarget(f, req.Port, ip)
+		if to == nil {
+			return nil, fmt.Errorf("ssh forward on port %d has no target for %s", req.Port, ip.String())
+		}
+
+		countRules = f.CountRules
+	}
+
+	res := rulesim.NewResult(req)
+
+	rule, ckErr := NewSshServerGroup().remoteAddrCheck(ip, to, countRules, res, req.Location.Data(req.IP))
+	res.Rule = rule
+	res.Allow = ckErr == nil
+	if ckErr != nil {
+		res.Reason = ckErr.Error()
+	}
+
+	return res, nil
+}
+
+func findForward(port int64) *config.SshForwardConfig {
+	for _, f := range config.GetConfig().SSH.Forward {
+		if f.Src.Contains(port) {
+			return f
+		}
+	}
+	return nil
+}
+
+// simulateTarget 与监听时的选择相同：优先同协议族的目标地址，其次为 unix socket 目标，允许交叉时使用另一协议族的目标地址，目标端口为范围时按端口计算
+func simulateTarget(f *config.SshForwardConfig, port int64, ip net.IP) net.Addr {
+	if ip.To4() != nil {
+		if f.IPv4DestTarget != nil {
+			return f.IPv4Dest(port)
+		} else if f.ResolveUnixDestAddress != nil {
+			return f.ResolveUnixDestAddress
+		} else if f.Cross && f.IPv6DestTarget != nil {
+			return f.IPv6Dest(port)
+		}
+		return nil
+	}
+
+	if f.IPv6DestTarget != nil {
+		return f.IPv6Dest(port)
+	} else if f.ResolveUnixDestAddress != nil {
+		return f.ResolveUnixDestAddress
+	} else if f.Cross && f.IPv4DestTarget != nil {
+		return f.IPv4Dest(port)
+	}
+	return nil
+}
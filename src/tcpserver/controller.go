@@ -4,5 +4,5 @@ import "net"
 
 type TcpController interface {
 	TcpNetworkAccept() bool
-	RemoteAddrCheck(remoteAddr *net.TCPAddr) bool
+	RemoteAddrCheck(remoteAddr *net.TCPAddr) (allow bool, rule string) // rule 为命中的规则名称，用于访问日志
 }
@@ -9,6 +9,7 @@ import (
 	"github.com/SongZihuan/huan-springboard/src/netwatcher"
 	"github.com/SongZihuan/huan-springboard/src/notify"
 	"github.com/SongZihuan/huan-springboard/src/redisserver"
+	"github.com/SongZihuan/huan-springboard/src/rulesim"
 	"math"
 	"net"
 	"strings"
@@ -31,6 +32,7 @@ type TcpServerGroup struct {
 	ifaceNotify         chan *netwatcher.NotifyData
 	ifaceNotifyStopchan chan bool
 	servers             sync.Map
+	serversLock         sync.Mutex // 启动、停止和调整转发时加锁
 	acceptStatus        atomic.Bool
 	stopAcceptTime      *time.Time // 仅限一个协程使用，因此不需要
 }
@@ -64,34 +66,132 @@ func (t *TcpServerGroup) Start() error {
 }
 
 func (t *TcpServerGroup) StartAllServers() error {
+	t.serversLock.Lock()
+	defer t.serversLock.Unlock()
+
 	if !t.status.CompareAndSwap(StatusWaitStart, StatusRunning) {
 		return nil
 	}
 
 	logger.Infof("TCP ServerGroup All Server Start...")
 	for _, f := range config.GetConfig().TCP.Forward {
-		server, err := NewTcpServer(&TcpServerOpt{
-			Config:     f,
-			Controller: t,
-		})
-		if err != nil {
-			logger.Errorf("New TCP Server Error: %s\n", err)
+		for _, b := range f.Binds {
+			t.startServer(f, b)
 		}
+	}
+	logger.Infof("TCP ServerGroup All Server Start Finished")
+
+	return nil
+}
+
+// startServer 启动转发的一个监听地址，服务以监听地址和端口（ListenBind.Key）为键保存；返回是否保存（创建失败或冲突时不保存）
+func (t *TcpServerGroup) startServer(f *config.TcpForwardConfig, bind *config.ListenBind) bool {
+	server, err := NewTcpServer(&TcpServerOpt{
+		Config:     f,
+		Bind:       bind,
+		Controller: t,
+	})
+	if err != nil {
+		logger.Errorf("New TCP Server Error: %s\n", err)
+		return false
+	}
+
+	conflict := t.findConflict(bind)
+	if conflict != "" {
+		logger.Errorf("TCP Listen Conflict: %s overlaps %s\n", bind.Key(), conflict)
+		return false
+	}
+	t.servers.Store(bind.Key(), server)
 
-		_, loaded := t.servers.LoadOrStore(f.SrcPort, server)
-		if loaded {
-			logger.Errorf("TCP Port Conflict: %d\n", f.SrcPort)
+	err = server.Start()
+	if err != nil {
+		logger.Errorf("Start TCP Server Error: %s\n", err)
+		// 没启动成功，但仍然保留在 Map 中，目的是提前发现可能的端口冲突（配置错误）
+	}
+
+	return true
+}
+
+// findConflict 返回与 bind 冲突（地址和端口相同，或者其中一个为同一协议族的全部地址）的监听地址，没有冲突时返回空字符串
+func (t *TcpServerGroup) findConflict(bind *config.ListenBind) (res string) {
+	t.servers.Range(func(key, value any) bool {
+		server, ok := value.(*TcpServer)
+		if ok && server.bind.Overlaps(bind) {
+			res = server.bind.Key()
+			return false
 		}
+		return true
+	})
+	return res
+}
 
-		err = server.Start()
-		if err != nil {
-			logger.Errorf("Start TCP Server Error: %s\n", err)
-			// 没启动成功，但仍然保留在 Map 中，目的是提前发现可能的端口冲突（配置错误）
+type forwardBind struct {
+	config *config.TcpForwardConfig
+	bind   *config.ListenBind
+}
+
+// Reconcile 重新加载配置后调整转发：停止被删除、被修改或者未运行（例如启动失败）的转发，启动新增的转发，未修改的转发（以及其上的连接）不受影响
+func (t *TcpServerGroup) Reconcile() {
+	t.serversLock.Lock()
+	defer t.serversLock.Unlock()
+
+	if t.status.Load() != StatusRunning {
+		return // 未运行（或者已暂停）时不调整，下次启动时使用新的配置
+	}
+
+	forwards := make(map[string]forwardBind, len(config.GetConfig().TCP.Forward))
+	for _, f := range config.GetConfig().TCP.Forward {
+		for _, b := range f.Binds {
+			if _, ok := forwards[b.Key()]; !ok {
+				forwards[b.Key()] = forwardBind{config: f, bind: b}
+			}
 		}
 	}
-	logger.Infof("TCP ServerGroup All Server Start Finished")
 
-	return nil
+	var wg sync.WaitGroup
+	var keep, stopped, started int
+
+	t.servers.Range(func(key, value any) bool {
+		server, ok := value.(*TcpServer)
+		if !ok {
+			t.servers.Delete(key)
+			return true
+		}
+
+		fb, ok := forwards[server.bind.Key()]
+		if ok && server.status.Load() == StatusRunning && server.config.Equal(fb.config) {
+			delete(forwards, server.bind.Key()) // 未修改，保留
+			keep++
+			return true
+		}
+
+		t.servers.Delete(key)
+		stopped++
+
+		wg.Add(1)
+		go func(server *TcpServer) {
+			defer wg.Done()
+			_ = server.Stop()
+		}(server)
+
+		return true
+	})
+
+	wg.Wait() // 等待端口释放后再启动
+
+	for _, f := range config.GetConfig().TCP.Forward {
+		for _, b := range f.Binds {
+			if forwards[b.Key()].bind != b {
+				continue // 保留的转发或重复的监听地址
+			}
+
+			if t.startServer(f, b) {
+				started++
+			}
+		}
+	}
+
+	logger.Infof("TCP ServerGroup Reconcile: %d kept, %d stopped, %d started", keep, stopped, started)
 }
 
 func (t *TcpServerGroup) Stop() error {
@@ -108,6 +208,9 @@ func (t *TcpServerGroup) Stop() error {
 }
 
 func (t *TcpServerGroup) StopAllServers() error {
+	t.serversLock.Lock()
+	defer t.serversLock.Unlock()
+
 	if !t.status.CompareAndSwap(StatusRunning, StatusWaitStop) {
 		return nil
 	}
@@ -252,64 +355,100 @@ func (t *TcpServerGroup) TcpNetworkAccept() bool {
 	return t.acceptStatus.Load()
 }
 
-func (*TcpServerGroup) RemoteAddrCheck(remoteAddr *net.TCPAddr) bool {
-	ip := remoteAddr.IP
+func (*TcpServerGroup) RemoteAddrCheck(remoteAddr *net.TCPAddr) (bool, string) {
+	return remoteAddrCheck(remoteAddr.IP, nil, nil)
+}
+
+// remoteAddrCheck 来访 IP 检查；sim 不为 nil 时为模拟，记录每一步的检查结果，locOverride 不为 nil 时不查询 IP 定位
+func remoteAddrCheck(ip net.IP, sim *rulesim.Result, locOverride *apiip.QueryIpLocationData) (bool, string) {
 	if ip == nil {
-		return false
+		sim.Step("invalid-ip", rulesim.ResultDeny, "can not get ip")
+		return false, "invalid-ip"
 	}
 
 	isLoopback := ip.IsLoopback()
 	isIntranet := isLoopback || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast()
 
 	if isLoopback && (config.GetConfig().TCP.RuleList.AlwaysAllowIntranet.IsEnable(true) || config.GetConfig().TCP.RuleList.AlwaysAllowLoopback.IsEnable(true)) {
-		return true
+		sim.Step("always-allow-loopback", rulesim.ResultAllow, "loopback address is always allowed")
+		return true, "always-allow-loopback"
+	} else if isLoopback {
+		sim.Step("always-allow-loopback", rulesim.ResultSkip, "always-allow-loopback and always-allow-intranet are disabled")
+	} else {
+		sim.Step("always-allow-loopback", rulesim.ResultSkip, "not a loopback address")
 	}
 
 	if !database.TcpCheckIP(ip.String()) {
-		return false
+		sim.Step("sqlite-ip", rulesim.ResultDeny, "ip is banned by sqlite")
+		return false, "sqlite-ip"
 	}
+	sim.Step("sqlite-ip", rulesim.ResultPass, "ip is not banned by sqlite")
 
 	if isIntranet && config.GetConfig().TCP.RuleList.AlwaysAllowIntranet.IsEnable(true) {
-		return true
+		sim.Step("always-allow-intranet", rulesim.ResultAllow, "intranet address is always allowed")
+		return true, "always-allow-intranet"
+	} else if isIntranet {
+		sim.Step("always-allow-intranet", rulesim.ResultSkip, "always-allow-intranet is disabled")
+	} else {
+		sim.Step("always-allow-intranet", rulesim.ResultSkip, "not an intranet address")
 	}
 
 	var loc *apiip.QueryIpLocationData = nil
 	if isIntranet {
 		loc = nil
+		sim.Step("ip-location", rulesim.ResultSkip, "intranet address has no location, location rules will be skipped")
 	} else {
 		var err error
 
-		loc, err = redisserver.QueryIpLocation(ip.String())
+		if locOverride != nil {
+			loc = locOverride
+		} else {
+			loc, err = redisserver.QueryIpLocation(ip.String())
+		}
+
 		if err != nil || loc == nil || strings.Contains(loc.Isp, "专用网络") || strings.Contains(loc.Isp, "本地环回") || strings.Contains(loc.Isp, "本地回环") {
 			if err != nil {
 				logger.Errorf("failed to query ip location: %s", err.Error())
+				sim.Step("ip-location", rulesim.ResultSkip, "failed to query ip location (%s), location rules will be skipped", err.Error())
 			} else if loc == nil {
 				logger.Panicf("failed to query ip location: loc is nil")
+				sim.Step("ip-location", rulesim.ResultSkip, "failed to query ip location, location rules will be skipped")
+			} else {
+				sim.Step("ip-location", rulesim.ResultSkip, "isp is '%s', location rules will be skipped", loc.Isp)
 			}
 
 			loc = nil
 		} else {
+			sim.LocationStep(loc)
+
 			if !database.TcpCheckLocationNation(loc.Nation) ||
 				!database.TcpCheckLocationProvince(loc.Province) ||
 				!database.TcpCheckLocationCity(loc.City) ||
 				!database.TcpCheckLocationISP(loc.Isp) {
-				return false
+				sim.Step("sqlite-location", rulesim.ResultDeny, "location is banned by sqlite")
+				return false, "sqlite-location"
 			}
+			sim.Step("sqlite-location", rulesim.ResultPass, "location is not banned by sqlite")
 		}
 	}
 
 RuleCycle:
-	for _, r := range config.GetConfig().TCP.RuleList.RuleList {
+	for i, r := range config.GetConfig().TCP.RuleList.RuleList {
+		name := fmt.Sprintf("rule-list[%d]", i)
+
 		if loc == nil {
 			if r.HasLocation() {
+				sim.RuleStep(name, rulesim.ResultSkip, "location is unknown", &r.RuleConfig)
 				continue RuleCycle
 			}
 		} else {
 			ok, err := loc.CheckLocation(&r.RuleConfig)
 			if err != nil {
 				logger.Errorf("check location error: %s", err.Error())
-				return false
+				sim.Step(name, rulesim.ResultDeny, "check location error: %s", err.Error())
+				return false, name
 			} else if !ok {
+				sim.RuleStep(name, rulesim.ResultSkip, "location not matched", &r.RuleConfig)
 				continue RuleCycle
 			}
 		}
@@ -317,13 +456,27 @@ RuleCycle:
 		ok, err := r.CheckIP(ip)
 		if err != nil {
 			logger.Errorf("check ip error: %s", err.Error())
-			return false
+			sim.Step(name, rulesim.ResultDeny, "check ip error: %s", err.Error())
+			return false, name
 		} else if !ok {
+			sim.RuleStep(name, rulesim.ResultSkip, "ip not matched", &r.RuleConfig)
 			continue RuleCycle
 		}
 
-		return !r.Banned.ToBool(true) // Banned表示封禁，该函数（IPCheck）返回值表示允许通行，因此取反
+		if r.Banned.ToBool(true) {
+			sim.RuleStep(name, rulesim.ResultDeny, "matched", &r.RuleConfig)
+		} else {
+			sim.RuleStep(name, rulesim.ResultAllow, "matched", &r.RuleConfig)
+		}
+
+		return !r.Banned.ToBool(true), name // Banned表示封禁，该函数（IPCheck）返回值表示允许通行，因此取反
+	}
+
+	if config.GetConfig().TCP.RuleList.DefaultBanned.ToBool(false) {
+		sim.Step("default", rulesim.ResultDeny, "no rule matched, default-banned is enabled")
+	} else {
+		sim.Step("default", rulesim.ResultAllow, "no rule matched, default-banned is disabled")
 	}
 
-	return !config.GetConfig().TCP.RuleList.DefaultBanned.ToBool(false)
+	return !config.GetConfig().TCP.RuleList.DefaultBanned.ToBool(false), "default"
 }
@@ -1,11 +1,11 @@
 package tcpserver
 
 import (
+	"errors"
 	"fmt"
 	"github.com/SongZihuan/huan-springboard/src/config"
-	"github.com/SongZihuan/huan-springboard/src/ipcheck"
+	"github.com/SongZihuan/huan-springboard/src/forward"
 	"github.com/SongZihuan/huan-springboard/src/logger"
-	"github.com/pires/go-proxyproto"
 	"io"
 	"net"
 	"sync"
@@ -14,20 +14,12 @@ import (
 )
 
 type TcpServer struct {
-	status atomic.Int32
-	config *config.TcpForwardConfig
+	status   atomic.Int32
+	config   *config.TcpForwardConfig
+	settings *forward.Settings
+	bind     *config.ListenBind
 
-	ln4              net.Listener
-	ln4Proxy         bool
-	ln4Cross         bool
-	ln4Target        *net.TCPAddr
-	ln4TargetNetwork string
-
-	ln6              net.Listener
-	ln6Proxy         bool
-	ln6Cross         bool
-	ln6Target        *net.TCPAddr
-	ln6TargetNetwork string
+	lns []*forward.Listener // 每个端口、每个协议族一个监听
 
 	swg        sync.WaitGroup
 	allconn    sync.Map
@@ -37,16 +29,23 @@ type TcpServer struct {
 
 type TcpServerOpt struct {
 	Config     *config.TcpForwardConfig
+	Bind       *config.ListenBind // 监听地址，为 Config.Binds 中的一项
 	Controller TcpController
 }
 
 func NewTcpServer(opt *TcpServerOpt) (*TcpServer, error) {
-	if opt.Config.ResolveIPv4DestAddress == nil && opt.Config.ResolveIPv6DestAddress == nil {
+	if opt.Config.IPv4DestTarget == nil && opt.Config.IPv6DestTarget == nil && opt.Config.ResolveUnixDestAddress == nil {
 		return nil, fmt.Errorf("no dest address")
 	}
 
+	if opt.Bind == nil {
+		return nil, fmt.Errorf("no listen address")
+	}
+
 	res := &TcpServer{
 		config:     opt.Config,
+		settings:   forward.TcpSettings(opt.Config),
+		bind:       opt.Bind,
 		controller: opt.Controller,
 	}
 
@@ -55,168 +54,26 @@ func NewTcpServer(opt *TcpServerOpt) (*TcpServer, error) {
 	return res, nil
 }
 
-func (t *TcpServer) Start() (err error) {
-	if t.ln4 != nil || t.status.Load() != StatusReady {
+func (t *TcpServer) Start() error {
+	if t.lns != nil || t.status.Load() != StatusReady {
 		return nil
 	}
 
-	if ipcheck.SupportIPv4() {
-		if t.config.ResolveIPv4DestAddress != nil {
-			_ln4, err := net.ListenTCP("tcp4", t.config.ResolveIPv4SrcAddress)
-			if err != nil {
-				return fmt.Errorf("listen %d on tcp4 failed: %s", t.config.SrcPort, err.Error())
-			}
-
-			if t.config.IPv4SrcServerProxy.IsEnable(true) {
-				t.ln4 = &proxyproto.Listener{
-					Listener: _ln4,
-				}
-				t.ln4Proxy = true
-				t.ln4Cross = false
-				t.ln4Target = t.config.ResolveIPv4DestAddress
-				t.ln4TargetNetwork = "tcp4"
-			} else {
-				t.ln4 = _ln4
-				t.ln4Proxy = false
-				t.ln4Cross = false
-				t.ln4Target = t.config.ResolveIPv4DestAddress
-				t.ln4TargetNetwork = "tcp4"
-			}
-		} else if t.config.Cross && t.config.ResolveIPv6DestAddress != nil {
-			_ln4, err := net.ListenTCP("tcp4", t.config.ResolveIPv4SrcAddress)
-			if err != nil {
-				return fmt.Errorf("listen %d on tcp4 failed: %s", t.config.SrcPort, err.Error())
-			}
-
-			t.ln4 = _ln4
-			t.ln4Proxy = false
-			t.ln4Cross = true
-			t.ln4Target = t.config.ResolveIPv6DestAddress
-			t.ln4TargetNetwork = "tcp6"
-		}
-	} else {
-		t.ln4 = nil
-		t.ln4Proxy = false
-		t.ln4Cross = false
-		t.ln4Target = nil
-		t.ln4TargetNetwork = ""
-	}
-
-	if ipcheck.SupportIPv6() {
-		if t.config.ResolveIPv6DestAddress != nil {
-			_ln6, err := net.ListenTCP("tcp6", t.config.ResolveIPv6SrcAddress)
-			if err != nil {
-				return fmt.Errorf("listen %d on tcp6 failed: %s", t.config.SrcPort, err.Error())
-			}
-
-			if t.config.IPv6SrcServerProxy.IsEnable(true) {
-				t.ln6 = &proxyproto.Listener{
-					Listener: _ln6,
-				}
-				t.ln6Proxy = true
-				t.ln6Cross = false
-				t.ln6Target = t.config.ResolveIPv6DestAddress
-				t.ln6TargetNetwork = "tcp6"
-			} else {
-				t.ln6 = _ln6
-				t.ln6Proxy = false
-				t.ln6Cross = false
-				t.ln6Target = t.config.ResolveIPv6DestAddress
-				t.ln6TargetNetwork = "tcp6"
-			}
-		} else if t.config.Cross && t.config.ResolveIPv4DestAddress != nil {
-			_ln6, err := net.ListenTCP("tcp6", t.config.ResolveIPv6SrcAddress)
-			if err != nil {
-				return fmt.Errorf("listen %d on tcp6 failed: %s", t.config.SrcPort, err.Error())
-			}
-
-			t.ln6 = _ln6
-			t.ln6Proxy = false
-			t.ln6Cross = true
-			t.ln6Target = t.config.ResolveIPv4DestAddress
-			t.ln6TargetNetwork = "tcp4"
-		}
-	} else {
-		t.ln6 = nil
-		t.ln6Proxy = false
-		t.ln6Cross = false
-		t.ln6Target = nil
-		t.ln6TargetNetwork = ""
-	}
-
-	if t.ln4 == nil && t.ln6 == nil {
-		return fmt.Errorf("no listen address")
-	}
-
-	if t.ln4Target == nil && t.ln6Target == nil {
-		return fmt.Errorf("no target address")
+	lns, err := forward.Listen(t.settings, t.bind)
+	if err != nil {
+		return err
 	}
 
+	t.lns = lns
 	t.stopchan = make(chan bool, 4)
 
-	if t.ln4 != nil {
-		go func() {
-			defer func() {
-				_ = t.ln4.Close()
-				t.ln4 = nil
-			}()
-
-			logger.Infof("listen on %d (ipv4) start", t.config.SrcPort)
-		MainCycle:
-			for {
-				select {
-				case <-t.stopchan:
-					break MainCycle
-				default:
-					// pass
-				}
-
-				status := t.accept(t.ln4,
-					"tcp4",
-					!t.ln4Cross && t.config.IPv4DestRequestProxy.IsEnable(true),
-					t.config.IPv4DestRequestProxyVersion,
-					t.ln4TargetNetwork,
-					t.ln4Target)
-				if status == StatusStop {
-					break MainCycle
-				}
-			}
-
-			logger.Infof("listen on %d (ipv4) stop", t.config.SrcPort)
-		}()
+	for _, l := range lns {
+		go t.serve(l)
 	}
 
-	if t.ln6 != nil {
-		go func() {
-			defer func() {
-				_ = t.ln6.Close()
-				t.ln6 = nil
-			}()
-
-			logger.Infof("listen on %d (ipv6) start", t.config.SrcPort)
-		MainCycle:
-			for {
-				select {
-				case <-t.stopchan:
-					break MainCycle
-				default:
-					// pass
-				}
-
-				status := t.accept(t.ln6,
-					"tcp6",
-					!t.ln6Cross && t.config.IPv6DestRequestProxy.IsEnable(true),
-					t.config.IPv6DestRequestProxyVersion,
-					t.ln6TargetNetwork,
-					t.ln6Target)
-				if status == StatusStop {
-					break MainCycle
-				}
-			}
+	go forward.Refresh(t.stopchan, t.settings.Targets, t.log(0, ""))
 
-			logger.Infof("listen on %d (ipv6) stop", t.config.SrcPort)
-		}()
-	}
+	t.log(0, "").Infof("listen on %s start (%d listeners)", t.bind.Key(), len(lns))
 
 	if !t.status.CompareAndSwap(StatusReady, StatusRunning) {
 		return fmt.Errorf("server run failed: can not set status")
@@ -225,13 +82,39 @@ func (t *TcpServer) Start() (err error) {
 	return nil
 }
 
+func (t *TcpServer) serve(l *forward.Listener) {
+	defer func() {
+		_ = l.Ln.Close()
+	}()
+
+	for {
+		select {
+		case <-t.stopchan:
+			return
+		default:
+			// pass
+		}
+
+		if t.accept(l) == StatusStop {
+			return
+		}
+	}
+}
+
 func (t *TcpServer) Stop() error {
-	if t.ln4 == nil || !t.status.CompareAndSwap(StatusRunning, StatusStopping) {
+	if t.lns == nil || !t.status.CompareAndSwap(StatusRunning, StatusStopping) {
 		return nil
 	}
 
 	close(t.stopchan)
 
+	// 关闭监听，使阻塞中的 Accept 返回并释放端口
+	for _, l := range t.lns {
+		_ = l.Ln.Close()
+	}
+
+	t.log(0, "").Infof("listen on %s stop", t.bind.Key())
+
 	time.Sleep(1 * time.Second)
 
 	go func() {
@@ -254,7 +137,7 @@ func (t *TcpServer) Stop() error {
 	return nil
 }
 
-func (t *TcpServer) forward(remoteAddr string, conn net.Conn, target net.Conn) {
+func (t *TcpServer) forward(remoteAddr string, conn net.Conn, target net.Conn, record *logger.AccessRecord) {
 	defer func() {
 		r := recover()
 		if r != nil {
@@ -269,12 +152,30 @@ func (t *TcpServer) forward(remoteAddr string, conn net.Conn, target net.Conn) {
 	t.swg.Add(1)
 	defer t.swg.Done()
 
-	if _, loaded := t.allconn.LoadOrStore(remoteAddr, conn); loaded {
-		logger.Errorf("%s is already connected", remoteAddr)
+	var bytesIn, bytesOut atomic.Int64
+	var err1, err2 error
+
+	defer func() {
+		// 在两个方向的转发都结束后写入访问日志
+		record.BytesIn = bytesIn.Load()
+		record.BytesOut = bytesOut.Load()
+		logger.WriteAccess(record)
+	}()
+
+	// 本地进程通过 unix socket 直接连接时没有来访地址，使用连接本身区分
+	var key any = remoteAddr
+	if remoteAddr == "" {
+		key = conn
+	}
+
+	if _, loaded := t.allconn.LoadOrStore(key, conn); loaded {
+		t.log(record.Port, remoteAddr).Errorf("%s is already connected", remoteAddr)
+		record.Decision = logger.AccessError
+		record.Reason = "already connected"
 		return
 	}
 	defer func() {
-		t.allconn.Delete(remoteAddr)
+		t.allconn.Delete(key)
 	}()
 
 	var stopchan1 = make(chan bool)
@@ -321,9 +222,11 @@ func (t *TcpServer) forward(remoteAddr string, conn net.Conn, target net.Conn) {
 			close(stopchan1)
 		}()
 
-		_, err := io.Copy(target, conn)
+		n, err := io.Copy(target, conn)
+		bytesIn.Store(n)
+		err1 = err
 		if err != nil && conn != nil && target != nil && t.status.Load() == StatusRunning {
-			logger.Errorf("failed to forward from %s to %s: %v", conn.RemoteAddr(), target.RemoteAddr(), err)
+			t.log(record.Port, remoteAddr).Errorf("failed to forward from %s to %s: %v", conn.RemoteAddr(), target.RemoteAddr(), err)
 		}
 	}()
 
@@ -343,38 +246,44 @@ func (t *TcpServer) forward(remoteAddr string, conn net.Conn, target net.Conn) {
 			close(stopchan2)
 		}()
 
-		_, err := io.Copy(conn, target)
+		n, err := io.Copy(conn, target)
+		bytesOut.Store(n)
+		err2 = err
 		if err != nil && conn != nil && target != nil && t.status.Load() == StatusRunning {
-			logger.Errorf("failed to forward from %s to %s: %v", target.RemoteAddr(), conn.RemoteAddr(), err)
+			t.log(record.Port, remoteAddr).Errorf("failed to forward from %s to %s: %v", target.RemoteAddr(), conn.RemoteAddr(), err)
 		}
 	}()
 
 	select {
 	case <-stopchan1:
+		record.Reason = logger.CloseReason(logger.CloseClient, err1)
 	case <-stopchan2:
+		record.Reason = logger.CloseReason(logger.CloseTarget, err2)
+	}
+
+	if t.status.Load() != StatusRunning {
+		record.Reason = logger.CloseServer
 	}
 
 	return
 }
 
-func (t *TcpServer) accept(ln net.Listener, srcNetwork string, destProxy bool, destProxyVersion int, targetNetwork string, targetAddr *net.TCPAddr) string {
+func (t *TcpServer) accept(l *forward.Listener) string {
 	defer func() {
 		if r := recover(); r != nil {
 			if err, ok := r.(error); ok {
-				logger.Panicf("listen on %d panic (error) : %s", t.config.SrcPort, err.Error())
+				logger.Panicf("listen on %s panic (error) : %s", t.bind.Key(), err.Error())
 			} else {
-				logger.Panicf("listen on %d panic : %s", t.config.SrcPort, err.Error())
+				logger.Panicf("listen on %s panic : %s", t.bind.Key(), err.Error())
 			}
 		}
 	}()
 
-	if ln == nil {
-		return StatusStop
-	}
-
-	conn, err := ln.Accept()
-	if err != nil {
-		logger.Errorf("listen on %d accecpt error: %s", t.config.SrcPort, err.Error())
+	conn, err := l.Ln.Accept()
+	if err != nil && errors.Is(err, net.ErrClosed) {
+		return StatusStop // 监听已关闭（Stop）
+	} else if err != nil {
+		t.log(l.Port, "").Errorf("listen on %s accecpt error: %s", t.bind.Key(), err.Error())
 		return StatusContinue
 	}
 	defer func() {
@@ -383,27 +292,50 @@ func (t *TcpServer) accept(ln net.Listener, srcNetwork string, destProxy bool, d
 		}
 	}()
 
-	if !t.controller.TcpNetworkAccept() {
-		return StatusContinue
-	}
+	now := time.Now()
 
 	remoteAddr := conn.RemoteAddr()
 	if remoteAddr == nil {
 		return StatusContinue
 	}
 
-	remoteTCPAddr, err := net.ResolveTCPAddr(srcNetwork, remoteAddr.String())
-	if err != nil {
+	record := forward.AccessRecord("tcp", conn, l.Port, l.Target, now)
+
+	if !t.controller.TcpNetworkAccept() {
+		record.Decision = logger.AccessReject
+		record.Rule = "net-watcher"
+		record.Reason = "tcp network accept paused"
+		logger.WriteAccess(record)
 		return StatusContinue
 	}
 
-	if !t.controller.RemoteAddrCheck(remoteTCPAddr) {
+	remoteTCPAddr, err := l.RemoteTCPAddr(remoteAddr)
+	if err != nil {
 		return StatusContinue
 	}
 
-	target, err := net.DialTCP(targetNetwork, nil, targetAddr)
+	remote := ""
+	if remoteTCPAddr != nil {
+		remote = remoteTCPAddr.String()
+
+		allow, rule := t.controller.RemoteAddrCheck(remoteTCPAddr)
+		record.Rule = rule
+		if !allow {
+			record.Decision = logger.AccessReject
+			record.Reason = "banned"
+			logger.WriteAccess(record)
+			return StatusContinue
+		}
+	} else {
+		record.Rule = "unix-socket" // 本地进程通过 unix socket 连接，没有来访 IP，由 socket 文件的权限控制访问
+	}
+
+	target, err := l.Dial(remoteTCPAddr)
 	if err != nil {
-		logger.Errorf("Failed to connect to target %s: %v", targetAddr.String(), err)
+		t.log(l.Port, remote).Errorf("Failed to connect to target %s: %v", l.Target.String(), err)
+		record.Decision = logger.AccessError
+		record.Reason = fmt.Sprintf("dial target: %s", err.Error())
+		logger.WriteAccess(record)
 		return StatusContinue
 	}
 	defer func() {
@@ -412,20 +344,28 @@ func (t *TcpServer) accept(ln net.Listener, srcNetwork string, destProxy bool, d
 		}
 	}()
 
-	if destProxy {
-		header := proxyproto.HeaderProxyFromAddrs(byte(destProxyVersion), remoteTCPAddr, targetAddr)
-		_, err = header.WriteTo(target)
-		if err != nil {
-			logger.Errorf("Failed to write proxy header to target %s: %v", targetAddr.String(), err)
-			return StatusContinue
-		}
+	record.Backend = target.RemoteAddr().String() // 实际连接的目标地址
+
+	err = l.WriteProxyHeader(target, conn)
+	if err != nil {
+		t.log(l.Port, remote).Errorf("Failed to write proxy header to target %s: %v", l.Target.String(), err)
+		record.Decision = logger.AccessError
+		record.Reason = fmt.Sprintf("write proxy header: %s", err.Error())
+		logger.WriteAccess(record)
+		return StatusContinue
 	}
 
 	_conn := conn
 	_target := target
 	conn = nil
 	target = nil
-	go t.forward(remoteAddr.String(), _conn, _target)
+	record.Decision = logger.AccessAccept
+	go t.forward(remote, _conn, _target, record)
 
 	return StatusContinue
 }
+
+// log 返回带有组件、端口和来访地址字段的日志，port 为 0 时使用转发的端口（或端口范围）
+func (t *TcpServer) log(port int64, remoteAddr string) *logger.Entry {
+	return forward.Log("tcp", t.config.Src, port, remoteAddr)
+}
@@ -0,0 +1,32 @@
+package tcpserver
+
+import (
+	"fmt"
+	"github.com/SongZihuan/huan-springboard/src/config"
+	"github.com/SongZihuan/huan-springboard/src/rulesim"
+)
+
+// Simulate 模拟来访 IP 的检查流程（不建立连接，不修改任何状态），返回检查结果以及每一步的检查过程
+func Simulate(req *rulesim.Request) (*rulesim.Result, error) {
+	ip, err := req.Check()
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Port != 0 && findForward(req.Port) == nil {
+		return nil, fmt.Errorf("tcp forward on port %d not found", req.Port)
+	}
+
+	res := rulesim.NewResult(req)
+	res.Allow, res.Rule = remoteAddrCheck(ip, res, req.Location.Data(req.IP))
+	return res, nil
+}
+
+func findForward(port int64) *config.TcpForwardConfig {
+	for _, f := range config.GetConfig().TCP.Forward {
+		if f.Src.Contains(port) {
+			return f
+		}
+	}
+	return nil
+}
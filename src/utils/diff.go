@@ -0,0 +1,161 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+const diffContext = 3
+
+type diffOp struct {
+	kind byte // ' ' 相同、'-' 删除、'+' 新增
+	line string
+}
+
+// UnifiedDiff 按行比较 a 和 b，输出 unified 格式（上下文 3 行）；内容相同时返回空字符串
+func UnifiedDiff(aName string, bName string, a string, b string) string {
+	ops := diffLines(splitLines(a), splitLines(b))
+
+	// aPos[i]、bPos[i]：第 i 个操作之前 a、b 的行数
+	aPos := make([]int, len(ops)+1)
+	bPos := make([]int, len(ops)+1)
+	for i, op := range ops {
+		aPos[i+1], bPos[i+1] = aPos[i], bPos[i]
+		if op.kind != '+' {
+			aPos[i+1]++
+		}
+		if op.kind != '-' {
+			bPos[i+1]++
+		}
+	}
+
+	var res strings.Builder
+
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+
+		// 相邻修改之间的相同行不超过上下文的两倍时合并为一段
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+
+			next := end
+			for next < len(ops) && ops[next].kind == ' ' && next-end < 2*diffContext {
+				next++
+			}
+
+			if next < len(ops) && ops[next].kind != ' ' {
+				end = next
+				continue
+			}
+
+			break
+		}
+
+		start := max(i-diffContext, 0)
+		stop := min(end+diffContext, len(ops))
+
+		if res.Len() == 0 {
+			res.WriteString(fmt.Sprintf("--- %s\n+++ %s\n", aName, bName))
+		}
+
+		res.WriteString(fmt.Sprintf("@@ -%s +%s @@\n", hunkRange(aPos[start], aPos[stop]), hunkRange(bPos[start], bPos[stop])))
+		for _, op := range ops[start:stop] {
+			res.WriteByte(op.kind)
+			res.WriteString(op.line)
+			res.WriteByte('\n')
+		}
+
+		i = stop
+	}
+
+	return res.String()
+}
+
+func hunkRange(from int, to int) string {
+	if to-from == 0 {
+		return fmt.Sprintf("%d,0", from)
+	} else if to-from == 1 {
+		return fmt.Sprintf("%d", from+1)
+	}
+	return fmt.Sprintf("%d,%d", from+1, to-from)
+}
+
+func splitLines(str string) []string {
+	if str == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(str, "\n"), "\n")
+}
+
+// diffLines 去除相同的开头和结尾后，按最长公共子序列计算中间部分的差异
+func diffLines(a []string, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for _, l := range a[:prefix] {
+		ops = append(ops, diffOp{kind: ' ', line: l})
+	}
+
+	am, bm := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	n, m := len(am), len(bm)
+
+	// lcs[i][j]：am[i:] 与 bm[j:] 的最长公共子序列长度
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if am[i] == bm[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < n && j < m {
+		if am[i] == bm[j] {
+			ops = append(ops, diffOp{kind: ' ', line: am[i]})
+			i++
+			j++
+		} else if lcs[i+1][j] >= lcs[i][j+1] {
+			ops = append(ops, diffOp{kind: '-', line: am[i]})
+			i++
+		} else {
+			ops = append(ops, diffOp{kind: '+', line: bm[j]})
+			j++
+		}
+	}
+
+	for ; i < n; i++ {
+		ops = append(ops, diffOp{kind: '-', line: am[i]})
+	}
+
+	for ; j < m; j++ {
+		ops = append(ops, diffOp{kind: '+', line: bm[j]})
+	}
+
+	for _, l := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{kind: ' ', line: l})
+	}
+
+	return ops
+}
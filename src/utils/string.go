@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 	"unicode"
+	"unicode/utf8"
 )
 
 const BASE_CHAR = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
@@ -365,3 +366,36 @@ func ReadBytes(str string) uint64 {
 	num, _ := strconv.ParseUint(str, 10, 64)
 	return num
 }
+
+// TruncateUTF8 按字节截断字符串，保证不截断在 UTF-8 字符中间
+func TruncateUTF8(str string, maxBytes int) string {
+	if maxBytes <= 0 {
+		return ""
+	}
+
+	if len(str) <= maxBytes {
+		return str
+	}
+
+	i := maxBytes
+	for i > 0 && !utf8.RuneStart(str[i]) {
+		i--
+	}
+
+	return str[:i]
+}
+
+// TruncateUTF8Ellipsis 同 TruncateUTF8，截断时在末尾添加省略号（计入长度）
+func TruncateUTF8Ellipsis(str string, maxBytes int) string {
+	const ellipsis = "..."
+
+	if len(str) <= maxBytes {
+		return str
+	}
+
+	if maxBytes <= len(ellipsis) {
+		return TruncateUTF8(str, maxBytes)
+	}
+
+	return TruncateUTF8(str, maxBytes-len(ellipsis)) + ellipsis
+}
@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const TimeLayout = "2006-01-02 15:04:05"
+
+// ParseTime 解析时间，支持 RFC3339 以及 2006-01-02 15:04:05、2006-01-02 15:04、2006-01-02（使用 loc 时区）
+func ParseTime(str string, loc *time.Location) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, str)
+	if err == nil {
+		return t, nil
+	}
+
+	for _, layout := range []string{TimeLayout, "2006-01-02 15:04", "2006-01-02"} {
+		t, err = time.ParseInLocation(layout, str, loc)
+		if err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("bad time '%s', must be like '%s' or RFC3339", str, TimeLayout)
+}
+
+// maxDurationDays time.Duration 能表示的最大天数（约 292 年）
+const maxDurationDays = uint64(math.MaxInt64 / int64(24*time.Hour))
+
+// ParseDuration 解析时长，支持 Go 的时长格式（例如 30m、12h）以及天（例如 7d），时长必须大于 0
+func ParseDuration(str string) (time.Duration, error) {
+	var res time.Duration
+	var err error
+
+	if days, ok := strings.CutSuffix(str, "d"); ok {
+		var n uint64
+		n, err = strconv.ParseUint(days, 10, 32)
+		if err == nil && n > maxDurationDays {
+			return 0, fmt.Errorf("bad duration '%s', must not be longer than %dd", str, maxDurationDays)
+		}
+		res = time.Duration(n) * 24 * time.Hour
+	} else {
+		res, err = time.ParseDuration(str)
+	}
+
+	if err != nil || res <= 0 {
+		return 0, fmt.Errorf("bad duration '%s', must be like 30m, 12h or 7d", str)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		str     string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"30m", 30 * time.Minute, false},
+		{"12h", 12 * time.Hour, false},
+		{"1h30m", 90 * time.Minute, false},
+		{"7d", 7 * 24 * time.Hour, false},
+		{"106751d", 106751 * 24 * time.Hour, false},
+		{"106752d", 0, true},
+		{"200000d", 0, true},
+		{"99999999999d", 0, true},
+		{"0d", 0, true},
+		{"0s", 0, true},
+		{"-1h", 0, true},
+		{"-1d", 0, true},
+		{"1.5d", 0, true},
+		{"d", 0, true},
+		{"7", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseDuration(tt.str)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseDuration(%q) error = %v, wantErr %v", tt.str, err, tt.wantErr)
+		} else if got != tt.want {
+			t.Errorf("ParseDuration(%q) = %v, want %v", tt.str, got, tt.want)
+		}
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*3600)
+
+	tests := []struct {
+		str     string
+		want    time.Time
+		wantErr bool
+	}{
+		{"2025-02-16T08:00:00Z", time.Date(2025, 2, 16, 8, 0, 0, 0, time.UTC), false},
+		{"2025-02-16 08:00:00", time.Date(2025, 2, 16, 8, 0, 0, 0, loc), false},
+		{"2025-02-16 08:00", time.Date(2025, 2, 16, 8, 0, 0, 0, loc), false},
+		{"2025-02-16", time.Date(2025, 2, 16, 0, 0, 0, 0, loc), false},
+		{"2025/02/16", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseTime(tt.str, loc)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseTime(%q) error = %v, wantErr %v", tt.str, err, tt.wantErr)
+		} else if !got.Equal(tt.want) {
+			t.Errorf("ParseTime(%q) = %v, want %v", tt.str, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package wxrobot
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	errCodeSystemBusy = -1    // 系统繁忙
+	errCodeFreqLimit  = 45009 // 接口调用超过频率限制（机器人每分钟最多发送 20 条消息）
+)
+
+const (
+	maxRetry       = 3
+	retryBaseDelay = 3 * time.Second
+	retryMaxDelay  = 60 * time.Second
+)
+
+type webhookLimit struct {
+	until time.Time
+	delay time.Duration
+}
+
+var limitLock sync.Mutex
+var limits = make(map[string]*webhookLimit)
+
+func isFreqLimitCode(code int) bool {
+	return code == errCodeFreqLimit || code == errCodeSystemBusy
+}
+
+// waitLimit 若该 Webhook 处于退避期则等待至退避结束
+func waitLimit(webhook string) {
+	limitLock.Lock()
+	l, ok := limits[webhook]
+	var wait time.Duration
+	if ok {
+		wait = time.Until(l.until)
+	}
+	limitLock.Unlock()
+
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+}
+
+// markLimited 记录一次频率限制，退避时间按指数增长
+func markLimited(webhook string) {
+	limitLock.Lock()
+	defer limitLock.Unlock()
+
+	l, ok := limits[webhook]
+	if !ok {
+		l = &webhookLimit{}
+		limits[webhook] = l
+	}
+
+	if l.delay <= 0 {
+		l.delay = retryBaseDelay
+	} else {
+		l.delay = min(l.delay*2, retryMaxDelay)
+	}
+
+	l.until = time.Now().Add(l.delay)
+}
+
+func clearLimited(webhook string) {
+	limitLock.Lock()
+	defer limitLock.Unlock()
+
+	delete(limits, webhook)
+}
@@ -0,0 +1,72 @@
+package wxrobot
+
+import (
+	"fmt"
+	"github.com/SongZihuan/huan-springboard/src/utils"
+	"strings"
+)
+
+const (
+	maxTextBytes     = 2048 // 文本消息内容的最大字节数
+	maxMarkdownBytes = 4096 // Markdown 消息内容的最大字节数
+	maxParts         = 5    // 单条消息最多拆分的条数，超出部分省略
+	partHeaderBytes  = 16   // 为分段序号 (i/n) 预留的字节数
+)
+
+const omitted = "\n……（内容过长，已省略）"
+
+// splitMessage 按行拆分超长消息，每段不超过 limit 字节并带有分段序号，单行过长时按 UTF-8 边界截断拆分
+func splitMessage(msg string, limit int) []string {
+	if len(msg) <= limit {
+		return []string{msg}
+	}
+
+	budget := limit - partHeaderBytes
+
+	res := make([]string, 0, maxParts)
+	var cur strings.Builder
+
+	flush := func() {
+		if cur.Len() > 0 {
+			res = append(res, cur.String())
+			cur.Reset()
+		}
+	}
+
+	for _, line := range strings.Split(msg, "\n") {
+		for len(line) > budget {
+			flush()
+			part := utils.TruncateUTF8(line, budget)
+			if part == "" {
+				break
+			}
+			res = append(res, part)
+			line = line[len(part):]
+		}
+
+		if cur.Len() > 0 && cur.Len()+1+len(line) > budget {
+			flush()
+		}
+
+		if cur.Len() > 0 {
+			cur.WriteString("\n")
+		}
+		cur.WriteString(line)
+	}
+	flush()
+
+	if len(res) > maxParts {
+		res = res[:maxParts]
+		res[maxParts-1] = utils.TruncateUTF8(res[maxParts-1], budget-len(omitted)) + omitted
+	}
+
+	if len(res) == 1 {
+		return res
+	}
+
+	for i := range res {
+		res[i] = fmt.Sprintf("(%d/%d)\n%s", i+1, len(res), res[i])
+	}
+
+	return res
+}
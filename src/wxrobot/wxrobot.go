@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/SongZihuan/huan-springboard/src/config"
+	"github.com/SongZihuan/huan-springboard/src/logger"
 	"io"
 	"net/http"
 )
@@ -37,46 +38,117 @@ type RespWebhookMsg struct {
 }
 
 func Send(msg string, atAll bool) error {
+	return SendTo(config.GetConfig().API.Webhook, msg, atAll)
+}
+
+func SendTo(webhook string, msg string, atAll bool) error {
 	if msg == "" {
 		return nil
 	}
 
-	return send(fmt.Sprintf("【%s 消息提醒】 %s", config.GetConfig().SystemName, msg), atAll)
+	return SendText(webhook, fmt.Sprintf("【%s 消息提醒】 %s", config.GetConfig().SystemName, msg), atAll)
 }
 
-func send(msg string, atAll bool) error {
-	if !config.IsReady() {
-		panic("config is not ready")
+// SendText 发送文本消息（不添加前缀），超长时按行拆分为多条发送，仅第一条提醒成员
+func SendText(webhook string, msg string, atAll bool) error {
+	if webhook == "" || msg == "" {
+		return nil
 	}
 
-	webhook := config.GetConfig().API.Webhook
+	var parts []*ReqWebhookMsg
+	for i, part := range splitMessage(msg, maxTextBytes) {
+		data := &ReqWebhookMsg{
+			MsgType: msgtypetext,
+			Text: &WebhookText{
+				Content: part,
+			},
+		}
+		if atAll && i == 0 {
+			data.Text.MentionedMobileList = []string{atall}
+		}
+
+		parts = append(parts, data)
+	}
 
-	if webhook == "" || msg == "" {
+	return sendParts(webhook, parts)
+}
+
+// SendMarkdown 发送 Markdown 消息（不支持提醒成员），超长时按行拆分为多条发送
+func SendMarkdown(webhook string, content string) error {
+	if webhook == "" || content == "" {
 		return nil
 	}
 
-	if len([]byte(msg)) >= 2048 {
-		return fmt.Errorf("msg too long")
+	var parts []*ReqWebhookMsg
+	for _, part := range splitMessage(content, maxMarkdownBytes) {
+		parts = append(parts, &ReqWebhookMsg{
+			MsgType: msgtypemarkdown,
+			Markdown: &WebhookMarkdown{
+				Content: part,
+			},
+		})
+	}
+
+	return sendParts(webhook, parts)
+}
+
+// sendParts 依次发送拆分后的消息：第一条失败时返回错误（由调用方重试）；
+// 已有部分送达后的失败只记录日志并返回成功，避免重试时重复发送已送达的部分
+func sendParts(webhook string, parts []*ReqWebhookMsg) error {
+	for i, data := range parts {
+		err := send(webhook, data)
+		if err != nil && i == 0 {
+			return err
+		} else if err != nil {
+			logger.Errorf("wxrobot send part %d/%d failed, the remaining parts are dropped: %s", i+1, len(parts), err.Error())
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// send 发送单条消息，遇到频率限制时退避重试
+func send(webhook string, data *ReqWebhookMsg) error {
+	if !config.IsReady() {
+		panic("config is not ready")
 	}
 
-	data := ReqWebhookMsg{
-		MsgType: msgtypetext,
-		Text: &WebhookText{
-			Content: msg,
-		},
+	var resp *RespWebhookMsg
+	var err error
+
+	for i := 0; i <= maxRetry; i++ {
+		waitLimit(webhook)
+
+		resp, err = post(webhook, data)
+		if err != nil {
+			return err
+		}
+
+		if !isFreqLimitCode(resp.ErrCode) {
+			break
+		}
+
+		markLimited(webhook)
 	}
-	if atAll {
-		data.Text.MentionedMobileList = []string{atall}
+
+	if resp.ErrCode != 0 {
+		return fmt.Errorf("send message error [code: %d]: %s", resp.ErrCode, resp.ErrMsg)
 	}
 
+	clearLimited(webhook)
+	return nil
+}
+
+func post(webhook string, data *ReqWebhookMsg) (*RespWebhookMsg, error) {
 	webhookData, err := json.Marshal(data)
 	if err != nil {
-		return fmt.Errorf("json marshal error: %s", err.Error())
+		return nil, fmt.Errorf("json marshal error: %s", err.Error())
 	}
 
 	resp, err := http.Post(webhook, "application/json", bytes.NewBuffer(webhookData))
 	if err != nil {
-		return fmt.Errorf("http post error: %s", err.Error())
+		return nil, fmt.Errorf("http post error: %s", err.Error())
 	}
 	defer func() {
 		_ = resp.Body.Close()
@@ -84,18 +156,14 @@ func send(msg string, atAll bool) error {
 
 	respData, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("read response body error: %s", err.Error())
+		return nil, fmt.Errorf("read response body error: %s", err.Error())
 	}
 
 	var respWebhook RespWebhookMsg
 	err = json.Unmarshal(respData, &respWebhook)
 	if err != nil {
-		return fmt.Errorf("json unmarshal response body error: %s", err.Error())
-	}
-
-	if respWebhook.ErrCode != 0 {
-		return fmt.Errorf("send message error [code: %d]: %s", respWebhook.ErrCode, respWebhook.ErrMsg)
+		return nil, fmt.Errorf("json unmarshal response body error: %s", err.Error())
 	}
 
-	return nil
+	return &respWebhook, nil
 }